@@ -1,11 +1,17 @@
 package dialog
 
 import (
+	"unicode"
+
 	"github.com/gdamore/tcell/v2"
+	"github.com/uditrawat03/bitcode/internal/theme"
 )
 
 type Dialog struct {
+	// Width and Height are the size on screen, at most the requested one
 	X, Y, Width, Height int
+	wantWidth           int
+	wantHeight          int
 	title               string
 	description         string
 	input               []rune
@@ -21,6 +27,19 @@ type Dialog struct {
 	restoreFocus func()
 
 	HasInput bool
+
+	// Choice buttons, e.g. Save / Discard / Cancel
+	choices  []string
+	choice   int
+	onChoice func(int)
+
+	// Filtered list, see NewListDialog
+	filter     func(query string) []Item
+	items      []Item
+	selected   int
+	listScroll int
+	onPick     func(int)
+	preview    func(int) *Preview
 }
 
 // NewDialog creates a dialog
@@ -28,6 +47,8 @@ func NewDialog(title string, description string, w, h int, onSubmit, onCancel fu
 	return &Dialog{
 		Width:        w,
 		Height:       h,
+		wantWidth:    w,
+		wantHeight:   h,
 		title:        title,
 		description:  description,
 		HasInput:     true,
@@ -37,6 +58,37 @@ func NewDialog(title string, description string, w, h int, onSubmit, onCancel fu
 	}
 }
 
+// NewChoiceDialog creates a dialog with a row of buttons instead of an input.
+// onChoice gets the index of the picked button, Esc picks the last one.
+func NewChoiceDialog(title string, description string, choices []string, onChoice func(int), restoreFocus func()) *Dialog {
+	width := len(title) + 6
+	if w := len(description) + 4; w > width {
+		width = w
+	}
+	buttons := 0
+	for _, c := range choices {
+		buttons += len(c) + 5
+	}
+	if buttons+2 > width {
+		width = buttons + 2
+	}
+	if width < 40 {
+		width = 40
+	}
+
+	return &Dialog{
+		Width:        width,
+		Height:       6,
+		wantWidth:    width,
+		wantHeight:   6,
+		title:        title,
+		description:  description,
+		choices:      choices,
+		onChoice:     onChoice,
+		restoreFocus: restoreFocus,
+	}
+}
+
 func (d *Dialog) SetFocus(f bool) {
 	d.focused = f
 	if !f && d.restoreFocus != nil {
@@ -48,11 +100,20 @@ func (d *Dialog) IsFocused() bool {
 	return d.focused
 }
 
-// Center the dialog dynamically on screen
+// SetInput fills the input, e.g. with the name being renamed
+func (d *Dialog) SetInput(text string) {
+	d.input = []rune(text)
+	d.cursor = len(d.input)
+}
+
+// Center the dialog dynamically on screen, shrinking it to fit; it grows
+// back to the requested size when the screen does
 func (d *Dialog) Center(screen tcell.Screen) {
 	sw, sh := screen.Size()
-	d.X = (sw - d.Width) / 2
-	d.Y = (sh - d.Height) / 2
+	d.Width = min(d.wantWidth, sw)
+	d.Height = min(d.wantHeight, sh)
+	d.X = max(0, (sw-d.Width)/2)
+	d.Y = max(0, (sh-d.Height)/2)
 }
 
 // Handle keyboard input
@@ -61,15 +122,30 @@ func (d *Dialog) HandleKey(ev *tcell.EventKey) {
 		return
 	}
 
+	if len(d.choices) > 0 {
+		d.handleChoiceKey(ev)
+		return
+	}
+
+	if d.filter != nil {
+		d.handleListKey(ev)
+		return
+	}
+
 	switch ev.Key() {
 	case tcell.KeyEnter:
-		if d.onSubmit != nil {
-			d.onSubmit(string(d.input))
-		}
+		d.Submit()
 	case tcell.KeyEsc:
-		if d.onCancel != nil {
-			d.onCancel("")
-		}
+		d.Cancel()
+	default:
+		d.editInput(ev)
+	}
+}
+
+// editInput handles typing and moving in the input, reporting whether the
+// text changed
+func (d *Dialog) editInput(ev *tcell.EventKey) bool {
+	switch ev.Key() {
 	case tcell.KeyBackspace, tcell.KeyBackspace2:
 		if d.cursor > 0 {
 			d.input = append(d.input[:d.cursor-1], d.input[d.cursor:]...)
@@ -77,6 +153,7 @@ func (d *Dialog) HandleKey(ev *tcell.EventKey) {
 			if d.scrollX > 0 && d.cursor < d.scrollX {
 				d.scrollX--
 			}
+			return true
 		}
 	case tcell.KeyLeft:
 		if d.cursor > 0 {
@@ -92,23 +169,24 @@ func (d *Dialog) HandleKey(ev *tcell.EventKey) {
 				d.scrollX++
 			}
 		}
-	default:
-		if ev.Rune() != 0 {
-			d.input = append(d.input[:d.cursor], append([]rune{ev.Rune()}, d.input[d.cursor:]...)...)
-			d.cursor++
-			if d.cursor-d.scrollX >= d.Width-2 {
-				d.scrollX++
-			}
+	case tcell.KeyRune:
+		d.input = append(d.input[:d.cursor], append([]rune{ev.Rune()}, d.input[d.cursor:]...)...)
+		d.cursor++
+		if d.cursor-d.scrollX >= d.Width-2 {
+			d.scrollX++
 		}
+		return true
 	}
+	return false
 }
 
 // Draw dialog with nice border, background, title, and scrolling input
 func (d *Dialog) Draw(s tcell.Screen) {
-	bgStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.NewRGBColor(30, 30, 30))
-	borderStyle := tcell.StyleDefault.Foreground(tcell.NewRGBColor(200, 200, 200)).Background(tcell.NewRGBColor(30, 30, 30))
-	titleStyle := tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.NewRGBColor(30, 30, 30))
-	descStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.NewRGBColor(30, 30, 30))
+	th := theme.Current()
+	bgStyle := th.Style(theme.DialogText)
+	borderStyle := th.Style(theme.DialogBorder)
+	titleStyle := th.Style(theme.DialogTitle)
+	descStyle := th.Style(theme.DialogText)
 
 	// Draw border with corners
 	for row := 0; row < d.Height; row++ {
@@ -140,6 +218,13 @@ func (d *Dialog) Draw(s tcell.Screen) {
 		s.SetContent(d.X+2+i, d.Y, r, nil, titleStyle)
 	}
 
+	if d.filter != nil {
+		d.drawInput(s, d.Y+1, bgStyle)
+		d.drawList(s)
+		d.drawPreview(s)
+		return
+	}
+
 	// Draw description
 	for i, r := range d.description {
 		if i >= d.Width-4 {
@@ -148,19 +233,30 @@ func (d *Dialog) Draw(s tcell.Screen) {
 		s.SetContent(d.X+2+i, d.Y+2, r, nil, descStyle)
 	}
 
+	if len(d.choices) > 0 {
+		d.drawChoices(s, bgStyle)
+		s.HideCursor()
+		return
+	}
+
 	// Draw input area
 	if d.HasInput {
-		for i := 0; i < d.Width-2 && d.scrollX+i < len(d.input); i++ {
-			s.SetContent(d.X+1+i, d.Y+4, d.input[d.scrollX+i], nil, bgStyle)
-		}
+		d.drawInput(s, d.Y+4, bgStyle)
+	} else {
+		s.HideCursor()
+	}
+}
 
-		if d.focused {
-			cursorPos := d.cursor - d.scrollX
-			if cursorPos >= 0 && cursorPos < d.Width-2 {
-				s.ShowCursor(d.X+1+cursorPos, d.Y+4)
-			} else {
-				s.HideCursor()
-			}
+// drawInput draws the scrolled input text on row y
+func (d *Dialog) drawInput(s tcell.Screen, y int, style tcell.Style) {
+	for i := 0; i < d.Width-2 && d.scrollX+i < len(d.input); i++ {
+		s.SetContent(d.X+1+i, y, d.input[d.scrollX+i], nil, style)
+	}
+
+	if d.focused {
+		cursorPos := d.cursor - d.scrollX
+		if cursorPos >= 0 && cursorPos < d.Width-2 {
+			s.ShowCursor(d.X+1+cursorPos, y)
 		} else {
 			s.HideCursor()
 		}
@@ -170,5 +266,91 @@ func (d *Dialog) Draw(s tcell.Screen) {
 }
 
 func (d *Dialog) HandleMouse(ev *tcell.EventMouse) {
-	// Ignore mouse events for now
+	if len(d.choices) == 0 || ev.Buttons()&tcell.Button1 == 0 {
+		return
+	}
+	x, y := ev.Position()
+	if y != d.Y+d.Height-2 {
+		return
+	}
+	col := d.X + 2
+	for i, c := range d.choices {
+		w := len(c) + 4
+		if x >= col && x < col+w {
+			d.choice = i
+			d.pick()
+			return
+		}
+		col += w + 1
+	}
+}
+
+// handleChoiceKey moves between buttons and picks one
+func (d *Dialog) handleChoiceKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyLeft, tcell.KeyBacktab:
+		d.choice = (d.choice + len(d.choices) - 1) % len(d.choices)
+	case tcell.KeyRight, tcell.KeyTab:
+		d.choice = (d.choice + 1) % len(d.choices)
+	case tcell.KeyEnter:
+		d.Submit()
+	case tcell.KeyEsc:
+		d.Cancel()
+	case tcell.KeyRune:
+		// first letter of a button picks it
+		for i, c := range d.choices {
+			if c != "" && unicode.ToLower(rune(c[0])) == unicode.ToLower(ev.Rune()) {
+				d.choice = i
+				d.pick()
+				return
+			}
+		}
+	}
+}
+
+// Submit accepts the input, or picks the highlighted button or list item
+func (d *Dialog) Submit() {
+	if len(d.choices) > 0 {
+		d.pick()
+	} else if d.filter != nil {
+		d.pickItem()
+	} else if d.onSubmit != nil {
+		d.onSubmit(string(d.input))
+	}
+}
+
+// Cancel dismisses the dialog; with buttons the last one means cancel
+func (d *Dialog) Cancel() {
+	if len(d.choices) > 0 {
+		d.choice = len(d.choices) - 1
+		d.pick()
+	} else if d.onCancel != nil {
+		d.onCancel("")
+	}
+}
+
+func (d *Dialog) pick() {
+	if d.onChoice != nil {
+		d.onChoice(d.choice)
+	}
+}
+
+// drawChoices renders the buttons on the row above the bottom border
+func (d *Dialog) drawChoices(s tcell.Screen, style tcell.Style) {
+	selectedStyle := theme.Current().Style(theme.DialogSelected)
+	col := d.X + 2
+	row := d.Y + d.Height - 2
+	for i, c := range d.choices {
+		st := style
+		if i == d.choice {
+			st = selectedStyle
+		}
+		for j, r := range "[ " + c + " ]" {
+			if col+j >= d.X+d.Width-1 {
+				return
+			}
+			s.SetContent(col+j, row, r, nil, st)
+		}
+		col += len(c) + 5
+	}
 }
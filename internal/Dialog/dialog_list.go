@@ -0,0 +1,242 @@
+package dialog
+
+import (
+	"slices"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/uditrawat03/bitcode/internal/syntax"
+	"github.com/uditrawat03/bitcode/internal/theme"
+)
+
+// Item is a row of a list dialog
+type Item struct {
+	Label string
+	// Detail is shown dimmed on the right, e.g. a key binding
+	Detail string
+	// Matches are rune indexes of Label to highlight
+	Matches []int
+}
+
+// Preview is shown next to the list for the selected item
+type Preview struct {
+	Lines []string
+	// Lexer highlights the lines, nil for plain text
+	Lexer syntax.Lexer
+}
+
+// NewListDialog creates a dialog with an input filtering a list below it.
+// filter is called with the input whenever it changes; onPick gets the
+// index of the chosen item in the last list filter returned.
+func NewListDialog(title string, w, h int, filter func(query string) []Item, onPick func(int), onCancel func(string), restoreFocus func()) *Dialog {
+	d := &Dialog{
+		Width:        w,
+		Height:       max(h, 4),
+		wantWidth:    w,
+		wantHeight:   max(h, 4),
+		title:        title,
+		HasInput:     true,
+		onCancel:     onCancel,
+		restoreFocus: restoreFocus,
+		filter:       filter,
+		onPick:       onPick,
+	}
+	d.refilter()
+	return d
+}
+
+// SetPreview shows what preview returns for the selected item beside the
+// list, when the dialog is wide enough
+func (d *Dialog) SetPreview(preview func(i int) *Preview) {
+	d.preview = preview
+}
+
+// Query returns the filter text
+func (d *Dialog) Query() string {
+	return string(d.input)
+}
+
+// Selected returns the index of the highlighted item, -1 when the list is
+// empty
+func (d *Dialog) Selected() int {
+	if len(d.items) == 0 {
+		return -1
+	}
+	return d.selected
+}
+
+// Refresh runs the filter again, e.g. when the items it searches changed
+func (d *Dialog) Refresh() {
+	d.items = d.filter(string(d.input))
+	d.selected = min(d.selected, max(0, len(d.items)-1))
+	d.scrollToSelected()
+}
+
+func (d *Dialog) refilter() {
+	d.items = d.filter(string(d.input))
+	d.selected = 0
+	d.listScroll = 0
+}
+
+func (d *Dialog) listHeight() int {
+	return d.Height - 3
+}
+
+func (d *Dialog) handleListKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		d.Submit()
+	case tcell.KeyEsc:
+		d.Cancel()
+	case tcell.KeyUp, tcell.KeyBacktab:
+		d.moveSelection(-1)
+	case tcell.KeyDown, tcell.KeyTab:
+		d.moveSelection(1)
+	case tcell.KeyPgUp:
+		d.moveSelection(-d.listHeight())
+	case tcell.KeyPgDn:
+		d.moveSelection(d.listHeight())
+	default:
+		if d.editInput(ev) {
+			d.refilter()
+		}
+	}
+}
+
+func (d *Dialog) moveSelection(delta int) {
+	if len(d.items) == 0 {
+		return
+	}
+	d.selected = max(0, min(d.selected+delta, len(d.items)-1))
+	d.scrollToSelected()
+}
+
+func (d *Dialog) scrollToSelected() {
+	if d.selected < d.listScroll {
+		d.listScroll = d.selected
+	}
+	if d.selected >= d.listScroll+d.listHeight() {
+		d.listScroll = d.selected - d.listHeight() + 1
+	}
+}
+
+func (d *Dialog) pickItem() {
+	if len(d.items) > 0 && d.onPick != nil {
+		d.onPick(d.selected)
+	}
+}
+
+// drawList renders the items below the input, the selected one highlighted
+func (d *Dialog) drawList(s tcell.Screen) {
+	th := theme.Current()
+	style := th.Style(theme.DialogText)
+	selectedStyle := th.Style(theme.DialogSelected)
+	dimFg := th.Foreground(theme.PanelDim)
+	matchFg := th.Foreground(theme.DialogTitle)
+	width := d.listWidth()
+
+	for row := 0; row < d.listHeight(); row++ {
+		y := d.Y + 2 + row
+		idx := d.listScroll + row
+		if idx >= len(d.items) {
+			if idx == 0 {
+				put(s, d.X+2, y, width-1, "No matches", style.Foreground(dimFg))
+			}
+			break
+		}
+		item := d.items[idx]
+		st := style
+		if idx == d.selected {
+			st = selectedStyle
+			for col := 0; col < width; col++ {
+				s.SetContent(d.X+1+col, y, ' ', nil, st)
+			}
+		}
+
+		detail := []rune(item.Detail)
+		labelWidth := width - 2
+		if len(detail) > 0 {
+			labelWidth -= len(detail) + 2
+			put(s, d.X+1+width-1-len(detail), y, len(detail), item.Detail, st.Foreground(dimFg))
+		}
+		for i, r := range []rune(item.Label) {
+			if i >= labelWidth {
+				break
+			}
+			cell := st
+			if slices.Contains(item.Matches, i) {
+				cell = st.Bold(true)
+				if idx != d.selected {
+					cell = cell.Foreground(matchFg)
+				}
+			}
+			s.SetContent(d.X+2+i, y, r, nil, cell)
+		}
+	}
+}
+
+// listWidth is the room for the list, the preview takes the rest
+func (d *Dialog) listWidth() int {
+	if d.preview == nil || d.Width < 60 {
+		return d.Width - 2
+	}
+	return d.Width * 2 / 5
+}
+
+// drawPreview renders the selected item's preview right of the list
+func (d *Dialog) drawPreview(s tcell.Screen) {
+	if d.preview == nil || d.Width < 60 {
+		return
+	}
+	th := theme.Current()
+	style := th.Style(theme.DialogText)
+	border := th.Style(theme.DialogBorder)
+	x := d.X + 1 + d.listWidth()
+	width := d.X + d.Width - 1 - (x + 1)
+	for row := 0; row < d.listHeight(); row++ {
+		s.SetContent(x, d.Y+2+row, '│', nil, border)
+	}
+
+	i := d.Selected()
+	if i < 0 {
+		return
+	}
+	p := d.preview(i)
+	if p == nil {
+		return
+	}
+	styles := th.Syntax()
+	var state syntax.State
+	for row, line := range p.Lines {
+		if row >= d.listHeight() {
+			break
+		}
+		runes := []rune(line)
+		var tokens []syntax.Token
+		if p.Lexer != nil {
+			tokens, state = p.Lexer.Lex(runes, state)
+		}
+		for col, r := range runes {
+			if col >= width {
+				break
+			}
+			for len(tokens) > 0 && tokens[0].End <= col {
+				tokens = tokens[1:]
+			}
+			cell := style
+			if len(tokens) > 0 && tokens[0].Start <= col {
+				cell = styles.Apply(style, tokens[0].Class)
+			}
+			s.SetContent(x+2+col, d.Y+2+row, r, nil, cell)
+		}
+	}
+}
+
+// put draws text clipped to width
+func put(s tcell.Screen, x, y, width int, text string, style tcell.Style) {
+	for i, r := range []rune(text) {
+		if i >= width {
+			return
+		}
+		s.SetContent(x+i, y, r, nil, style)
+	}
+}
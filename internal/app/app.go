@@ -4,6 +4,7 @@ import (
 	"log"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/uditrawat03/bitcode/internal/theme"
 	"github.com/uditrawat03/bitcode/internal/ui"
 )
 
@@ -29,19 +30,26 @@ func (app *App) Initialize() error {
 		return err
 	}
 
-	// Enable mouse events
-	screen.EnableMouse()
-	screen.EnablePaste()
 	// screen.ShowCursor(0, 0)
 
+	// Fit the theme to terminals without true color
+	theme.SetColors(screen.Colors())
+
 	app.screen = screen
 
 	app.ui = ui.CreateScreenManager()
+	app.ui.SetScreen(screen)
 
 	// Initialize all UI components with screen dimensions
 	screenWidth, screenHeight := screen.Size()
 	app.ui.InitComponents(screenWidth, screenHeight)
 
+	// Settings, including whether mouse and paste are enabled
+	app.ui.LoadConfig()
+
+	// Offer leftovers from a crash and keep snapshots of unsaved work
+	app.ui.StartRecovery()
+
 	return nil
 }
 
@@ -56,13 +64,18 @@ func (app *App) Run() {
 
 		switch ev := event.(type) {
 		case *tcell.EventKey:
-			if ev.Key() == tcell.KeyEscape && !app.ui.IsDialogOpen() {
-				app.running = false
+			if ev.Key() == tcell.KeyEscape && !app.ui.CapturesEscape() {
+				app.ui.RequestQuit(func() { app.running = false })
 			} else {
 				app.ui.HandleKey(ev)
 			}
 		case *tcell.EventMouse:
 			app.ui.HandleMouse(ev)
+		case *tcell.EventInterrupt:
+			app.ui.HandleInterrupt(ev)
+		case *tcell.EventResize:
+			app.screen.Sync()
+			app.ui.Resize()
 		}
 
 		app.draw()
@@ -78,6 +91,9 @@ func (app *App) draw() {
 }
 
 func (app *App) Shutdown() {
+	if app.ui != nil {
+		app.ui.Close()
+	}
 	if app.screen != nil {
 		app.screen.Fini()
 		app.screen = nil
@@ -0,0 +1,78 @@
+package buffer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic writes path through a temp file in the same directory.
+// The original mode and owner are kept when the file already exists, a new
+// file gets perm. A symlink is written through, the file it points at gets
+// the new content.
+func writeFileAtomic(path string, perm os.FileMode, write func(io.Writer) error) (err error) {
+	path = resolveSymlink(path)
+	mode := perm
+	uid, gid := -1, -1
+	if info, statErr := os.Stat(path); statErr == nil {
+		mode = info.Mode().Perm()
+		uid, gid = fileOwner(info)
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", path, err)
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return fmt.Errorf("chmod %s: %w", path, err)
+	}
+	if uid >= 0 {
+		// only root can give the file away, ignore failures for everyone else
+		_ = tmp.Chown(uid, gid)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", path, err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename %s: %w", path, err)
+	}
+
+	// persist the rename itself
+	if d, dirErr := os.Open(dir); dirErr == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// resolveSymlink is the file path ends up at after following symlinks, so
+// the rename replaces the target instead of the link. A link to a file that
+// doesn't exist yet resolves to where it points.
+func resolveSymlink(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return path
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	return target
+}
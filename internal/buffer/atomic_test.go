@@ -0,0 +1,134 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// writeString is a writeFileAtomic callback writing s
+func writeString(s string) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+// checkFile fails unless path holds want and dir holds nothing but names
+func checkFile(t *testing.T, path, want string, names ...string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Fatalf("%s holds %q, want %q", path, data, want)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	if !slices.Equal(got, names) {
+		t.Fatalf("dir holds %q, want %q", got, names)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+
+	if err := writeFileAtomic(path, 0600, writeString("new")); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, path, "new", "file.txt")
+
+	// a failed write leaves the old content and no temp file behind
+	failed := errors.New("disk full")
+	err := writeFileAtomic(path, 0600, func(w io.Writer) error {
+		io.WriteString(w, "half")
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("writeFileAtomic error = %v, want %v", err, failed)
+	}
+	checkFile(t, path, "new", "file.txt")
+
+	if err := writeFileAtomic(path, 0600, writeString("replaced")); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, path, "replaced", "file.txt")
+}
+
+func TestWriteFileAtomicMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no permission bits on windows")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+
+	// a new file gets perm, an existing one keeps its mode
+	if err := writeFileAtomic(path, 0600, writeString("a")); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Fatalf("new file mode %v, want 0600", info.Mode().Perm())
+	}
+	if err := os.Chmod(path, 0751); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, 0600, writeString("b")); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0751 {
+		t.Fatalf("rewritten file mode %v, want 0751", info.Mode().Perm())
+	}
+}
+
+func TestWriteFileAtomicSymlink(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string // relative to the link's dir
+		exists bool
+		// what the target's dir holds afterwards
+		files []string
+	}{
+		{"relative", "real.txt", true, []string{"link.txt", "real.txt"}},
+		{"into a subdir", "sub/real.txt", true, []string{"real.txt"}},
+		{"dangling", "real.txt", false, []string{"link.txt", "real.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			target := filepath.Join(dir, filepath.FromSlash(tt.target))
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if tt.exists {
+				if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			link := filepath.Join(dir, "link.txt")
+			if err := os.Symlink(filepath.FromSlash(tt.target), link); err != nil {
+				t.Skipf("can't create symlinks: %v", err)
+			}
+
+			if err := writeFileAtomic(link, 0644, writeString("new")); err != nil {
+				t.Fatal(err)
+			}
+			// the link stays a link and the file it points at changes
+			if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+				t.Fatalf("link replaced by a regular file: %v", err)
+			}
+			checkFile(t, target, "new", tt.files...)
+		})
+	}
+}
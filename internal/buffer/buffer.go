@@ -2,199 +2,492 @@ package buffer
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+
+	"github.com/uditrawat03/bitcode/internal/watcher"
+	"golang.org/x/text/transform"
+)
+
+const (
+	LF   = "\n"
+	CRLF = "\r\n"
 )
 
+const utf8BOM = "\ufeff"
+
 type Buffer struct {
-	Content [][]rune
+	text    *rope
 	File    string
+	Name    string // display name for buffers without a file
 	CursorX int
 	CursorY int
 	mu      sync.RWMutex
+
+	// On disk format, detected on Load and written back by Save
+	Encoding     string
+	LineEnding   string
+	FinalNewline bool
+	BOM          bool
+
+	history history
+	// set by changes that are not part of the undo history, like a new line ending
+	formatChanged bool
+	// bumped on every change to the content or format
+	revision int
+	// recent edits by line, see changes.go
+	changes changeLog
+
+	// what the file looked like on disk when it was last loaded or saved
+	disk diskState
+
+	// unique in this process, names the recovery snapshot
+	id uint64
 }
 
+// lastID numbers buffers as they are created
+var lastID atomic.Uint64
+
 func NewBuffer(path string) *Buffer {
-	buf := &Buffer{File: path}
+	buf := newEmptyBuffer(path)
 	if path != "" {
-		buf.Load()
-	} else {
-		buf.Content = [][]rune{{}}
+		if err := buf.Load(); err != nil {
+			log.Printf("Unable to load file: %v", err)
+		}
 	}
 	return buf
 }
 
-func (b *Buffer) Load() {
-	file, err := os.Open(b.File)
+// Load reads the file from disk, detecting its encoding. A missing file is
+// not an error, the buffer just starts empty so it can be saved later.
+func (b *Buffer) Load() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.load("")
+}
+
+// ReloadWithEncoding reads the file again decoding it as the named encoding
+func (b *Buffer) ReloadWithEncoding(name string) error {
+	canonical, _, err := LookupEncoding(name)
 	if err != nil {
-		log.Printf("Unable to open file: %v", err)
-		b.Content = [][]rune{{}}
-		return
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.load(canonical)
+}
+
+// load reads the file using enc, or a detected encoding when enc is empty
+func (b *Buffer) load(enc string) error {
+	var src io.Reader = strings.NewReader("")
+	f, err := os.Open(b.File)
+	switch {
+	case err == nil:
+		defer f.Close()
+		src = f
+	case !os.IsNotExist(err):
+		return fmt.Errorf("open %s: %w", b.File, err)
 	}
-	defer file.Close()
+	missing := err != nil
 
-	scanner := bufio.NewScanner(file)
-	b.Content = [][]rune{}
-	for scanner.Scan() {
-		b.Content = append(b.Content, []rune(scanner.Text()))
+	// the file is hashed as it streams past, for DiskChanged
+	hash := sha256.New()
+	raw := bufio.NewReaderSize(io.TeeReader(src, hash), detectSampleBytes)
+	if enc == "" {
+		enc = detectEncoding(detectSample(raw))
 	}
-	if len(b.Content) == 0 {
-		b.Content = [][]rune{{}}
+	var r io.Reader = raw
+	if e := encoderFor(enc); e != nil {
+		r = transform.NewReader(raw, e.NewDecoder())
+	}
+	loaded, err := readText(r)
+	if err != nil {
+		return fmt.Errorf("decode as %s: %w", enc, err)
 	}
+
+	b.disk = newDiskState(b.File, hash.Sum(nil))
+	b.Encoding = enc
+	b.BOM = loaded.bom
+	b.LineEnding = loaded.lineEnding
+	b.FinalNewline = loaded.finalNewline || missing // new files get a trailing newline like most editors write
+	b.text = loaded.text
+	b.history = history{}
+	b.formatChanged = false
+	b.revision++
+	b.resetChanges()
 	b.CursorX, b.CursorY = 0, 0
+	return nil
+}
+
+// Save writes the buffer atomically: the content goes to a temp file next to
+// the target which is synced and then renamed over it, so a crash never
+// leaves a half written file behind. The text is written back in the
+// encoding, line ending and BOM style it was loaded with.
+func (b *Buffer) Save() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.save()
 }
 
-func (b *Buffer) Save() {
+// SaveWithEncoding converts the buffer to the named encoding and saves it.
+// The old encoding is kept when the text can't be represented in the new one.
+func (b *Buffer) SaveWithEncoding(name string) error {
+	canonical, _, err := LookupEncoding(name)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous := b.Encoding
+	b.Encoding = canonical
+	if err := b.save(); err != nil {
+		b.Encoding = previous
+		return err
+	}
+	return nil
+}
+
+// save writes the file; callers hold b.mu
+func (b *Buffer) save() error {
 	if b.File == "" {
-		log.Println("No file path specified.")
-		return
+		return errors.New("no file path specified")
 	}
+	hash := sha256.New()
+	err := writeFileAtomic(b.File, 0644, func(w io.Writer) error {
+		writer := bufio.NewWriter(io.MultiWriter(w, hash))
+
+		var dst io.Writer = writer
+		var encoder io.WriteCloser
+		if enc := encoderFor(b.Encoding); enc != nil {
+			encoder = transform.NewWriter(writer, enc.NewEncoder())
+			dst = encoder
+		}
 
-	file, err := os.Create(b.File)
+		if b.BOM {
+			if _, err := io.WriteString(dst, utf8BOM); err != nil {
+				return fmt.Errorf("encode as %s: %w", b.Encoding, err)
+			}
+		}
+		out := newLineEndingWriter(dst, b.LineEnding)
+		if _, err := b.text.WriteTo(out); err != nil {
+			return fmt.Errorf("encode as %s: %w", b.Encoding, err)
+		}
+		if b.FinalNewline {
+			if _, err := out.Write([]byte(LF)); err != nil {
+				return err
+			}
+		}
+		if encoder != nil {
+			if err := encoder.Close(); err != nil {
+				return fmt.Errorf("encode as %s: %w", b.Encoding, err)
+			}
+		}
+		return writer.Flush()
+	})
 	if err != nil {
-		log.Printf("Error saving file: %v", err)
-		return
+		return err
 	}
-	defer file.Close()
+	b.history.markSaved()
+	b.formatChanged = false
+	b.disk.update(b.File, hash.Sum(nil))
+	return nil
+}
 
-	writer := bufio.NewWriter(file)
-	for _, line := range b.Content {
-		_, _ = writer.WriteString(string(line) + "\n")
-	}
-	writer.Flush()
+// Revision changes on every change to the content or format; callers use it
+// to know when cached results such as search matches are stale
+func (b *Buffer) Revision() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.revision
 }
 
-// --- Editing helpers ---
+// Modified reports whether the buffer differs from what was last loaded or saved
+func (b *Buffer) Modified() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.formatChanged || b.history.top() != b.history.savedID
+}
 
-func (b *Buffer) InsertRune(ch rune) {
+// SetLineEnding changes the line ending used on the next Save
+func (b *Buffer) SetLineEnding(le string) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-
-	if b.CursorY >= len(b.Content) {
-		b.Content = append(b.Content, []rune{})
+	if le != b.LineEnding {
+		b.LineEnding = le
+		b.formatChanged = true
+		b.revision++
 	}
-	line := b.Content[b.CursorY]
+}
+
+// NewScratchBuffer creates an unsaved buffer holding text, e.g. a diff
+func NewScratchBuffer(name, text string) *Buffer {
+	buf := newEmptyBuffer("")
+	buf.Name = name
+	buf.text = newRope(text)
+	return buf
+}
 
-	if b.CursorX > len(line) {
-		b.CursorX = len(line)
+// DisplayName is the file's base name, or the scratch name
+func (b *Buffer) DisplayName() string {
+	switch {
+	case b.File != "":
+		return filepath.Base(b.File)
+	case b.Name != "":
+		return b.Name
 	}
+	return "[No Name]"
+}
 
-	// insert at cursor
-	newLine := append(line[:b.CursorX], append([]rune{ch}, line[b.CursorX:]...)...)
-	b.Content[b.CursorY] = newLine
-	b.CursorX++
+func newEmptyBuffer(path string) *Buffer {
+	return &Buffer{
+		File:         path,
+		text:         newRope(""),
+		Encoding:     UTF8,
+		LineEnding:   LF,
+		FinalNewline: true,
+		id:           lastID.Add(1),
+	}
 }
 
-func (b *Buffer) DeleteRune() {
+// --- Editing helpers ---
+
+func (b *Buffer) InsertRune(ch rune) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	b.openStep()
+	defer b.closeStep()
 
-	if b.CursorY >= len(b.Content) {
-		return
+	b.clampCursor()
+	if ch == '\r' {
+		ch = '\n'
 	}
-	line := b.Content[b.CursorY]
+	b.CursorX, b.CursorY = b.insertText(b.CursorX, b.CursorY, []rune{ch}, true)
+}
+
+func (b *Buffer) DeleteRune() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.openStep()
+	defer b.closeStep()
 
+	b.clampCursor()
 	if b.CursorX == 0 {
 		// join with previous line
 		if b.CursorY > 0 {
-			prev := b.Content[b.CursorY-1]
-			b.Content[b.CursorY-1] = append(prev, line...)
-			b.Content = append(b.Content[:b.CursorY], b.Content[b.CursorY+1:]...)
+			prevLen := b.lineLen(b.CursorY - 1)
+			b.deleteText(prevLen, b.CursorY-1, 0, b.CursorY)
 			b.CursorY--
-			b.CursorX = len(prev)
+			b.CursorX = prevLen
 		}
 		return
 	}
 
 	// delete before cursor
-	newLine := append(line[:b.CursorX-1], line[b.CursorX:]...)
-	b.Content[b.CursorY] = newLine
+	b.deleteText(b.CursorX-1, b.CursorY, b.CursorX, b.CursorY)
 	b.CursorX--
 }
 
 func (b *Buffer) InsertLine() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	b.openStep()
+	defer b.closeStep()
 
-	if b.CursorY >= len(b.Content) {
-		b.Content = append(b.Content, []rune{})
-		b.CursorY = len(b.Content) - 1
-		b.CursorX = 0
-		return
-	}
-
-	line := b.Content[b.CursorY]
-	left := line[:b.CursorX]
-	right := line[b.CursorX:]
-
-	// replace current line with left, insert right below
-	b.Content[b.CursorY] = left
-	b.Content = append(b.Content[:b.CursorY+1], append([][]rune{right}, b.Content[b.CursorY+1:]...)...)
-
-	b.CursorY++
-	b.CursorX = 0
+	b.clampCursor()
+	b.CursorX, b.CursorY = b.insertText(b.CursorX, b.CursorY, []rune{'\n'}, false)
 }
 
 func (b *Buffer) SetLine(y int, text string) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if y < 0 || y >= len(b.Content) {
+	if y < 0 || y >= b.text.Lines() {
 		return
 	}
-	b.Content[y] = []rune(text)
+	b.openStep()
+	defer b.closeStep()
+
+	b.deleteText(0, y, b.lineLen(y), y)
+	b.insertText(0, y, []rune(text), false)
 }
 
 func (b *Buffer) Line(y int) string {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	if y < 0 || y >= len(b.Content) {
+	if y < 0 || y >= b.text.Lines() {
 		return ""
 	}
-	return string(b.Content[y])
+	return b.line(y)
 }
 
 func (b *Buffer) Lines() []string {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	lines := make([]string, len(b.Content))
-	for i, l := range b.Content {
-		lines[i] = string(l)
+	return strings.Split(b.text.String(), "\n")
+}
+
+// LineCount returns the number of lines, always at least one
+func (b *Buffer) LineCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.text.Lines()
+}
+
+// LineLen returns the length of line y in runes
+func (b *Buffer) LineLen(y int) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if y < 0 || y >= b.text.Lines() {
+		return 0
 	}
-	return lines
+	return b.lineLen(y)
+}
+
+// String returns the whole content, lines joined by '\n'
+func (b *Buffer) String() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.text.String()
 }
 
 // ---------------- BufferManager -----------------
 
 type BufferManager struct {
-	buffers map[string]*Buffer
+	buffers map[string]*Buffer // by canonical path
 	active  *Buffer
-	mu      sync.RWMutex
+	// most recently used first
+	recent []*Buffer
+	mu     sync.RWMutex
+
+	watcher          *watcher.Watcher
+	onExternalChange func(*Buffer)
+	onClosed         func(*Buffer)
+	onRenamed        func(buf *Buffer, oldPath string)
+
+	// crash recovery, see recovery.go
+	recoveryDir  string
+	snapshots    map[*Buffer]int // buffer -> version last written
+	stopRecovery chan struct{}
 }
 
 func NewBufferManager() *BufferManager {
-	return &BufferManager{
+	bm := &BufferManager{
 		buffers: make(map[string]*Buffer),
 	}
+	w, err := watcher.New()
+	if err != nil {
+		log.Printf("File watching disabled: %v", err)
+		return bm
+	}
+	bm.watcher = w
+	go bm.watch()
+	return bm
 }
 
-func (bm *BufferManager) Open(path string) *Buffer {
+// SetOnExternalChange sets the callback run when an open file changes on
+// disk. It is called from a background goroutine.
+func (bm *BufferManager) SetOnExternalChange(cb func(*Buffer)) {
 	bm.mu.Lock()
 	defer bm.mu.Unlock()
+	bm.onExternalChange = cb
+}
+
+// Shutdown stops watching files and, since every buffer was saved or
+// deliberately discarded by now, drops this session's recovery snapshots
+func (bm *BufferManager) Shutdown() {
+	if bm.watcher != nil {
+		bm.watcher.Close()
+	}
+	bm.mu.Lock()
+	stop := bm.stopRecovery
+	bm.stopRecovery = nil
+	bm.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		bm.clearSnapshots()
+	}
+}
+
+func (bm *BufferManager) watch() {
+	for path := range bm.watcher.Events() {
+		bm.mu.RLock()
+		cb := bm.onExternalChange
+		var changed *Buffer
+		for _, buf := range bm.buffers {
+			if absPath(buf.File) == path {
+				changed = buf
+				break
+			}
+		}
+		bm.mu.RUnlock()
 
-	if buf, ok := bm.buffers[path]; ok {
-		bm.active = buf
-		return buf
+		if changed != nil && cb != nil {
+			cb(changed)
+		}
 	}
+}
 
-	buf := NewBuffer(path)
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// Open returns the buffer for path, loading it the first time. Paths are
+// canonicalized, so "./a.go" and "a.go" share one buffer.
+func (bm *BufferManager) Open(path string) (*Buffer, error) {
+	path = CanonicalPath(path)
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	buf, ok := bm.buffers[path]
+	if !ok {
+		var err error
+		if buf, err = bm.load(path); err != nil {
+			return nil, err
+		}
+	}
+	bm.activate(buf)
+	return buf, nil
+}
+
+// load reads path into a new buffer and starts watching it; path must be
+// canonical and bm.mu held
+func (bm *BufferManager) load(path string) (*Buffer, error) {
+	buf := newEmptyBuffer(path)
+	if err := buf.Load(); err != nil {
+		return nil, err
+	}
 	bm.buffers[path] = buf
-	bm.active = buf
-	return buf
+	if bm.watcher != nil {
+		if err := bm.watcher.Add(path); err != nil {
+			log.Printf("Unable to watch %s: %v", path, err)
+		}
+	}
+	return buf, nil
+}
+
+// Lookup returns the open buffer for path, if any
+func (bm *BufferManager) Lookup(path string) (*Buffer, bool) {
+	path = CanonicalPath(path)
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+	buf, ok := bm.buffers[path]
+	return buf, ok
 }
 
 func (bm *BufferManager) Active() *Buffer {
@@ -203,58 +496,75 @@ func (bm *BufferManager) Active() *Buffer {
 	return bm.active
 }
 
-func (bm *BufferManager) SaveActive() {
+// Modified returns the open buffers with unsaved changes, ordered by path
+func (bm *BufferManager) Modified() []*Buffer {
 	bm.mu.RLock()
 	defer bm.mu.RUnlock()
-	if bm.active != nil {
-		bm.active.Save()
+
+	var modified []*Buffer
+	for _, buf := range bm.buffers {
+		if buf.Modified() {
+			modified = append(modified, buf)
+		}
+	}
+	sort.Slice(modified, func(i, j int) bool { return modified[i].File < modified[j].File })
+	return modified
+}
+
+func (bm *BufferManager) SaveActive() error {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+	if bm.active == nil {
+		return nil
 	}
+	return bm.active.Save()
 }
 
 func (buf *Buffer) DeleteAtCursor(cursorX, cursorY int, selStartY, selEndY int, selecting bool) {
+	buf.mu.Lock()
+	defer buf.mu.Unlock()
+	buf.openStep()
+	defer buf.closeStep()
+
 	if selecting {
 		// Delete all lines between selStartY and selEndY
 		start, end := selStartY, selEndY
 		if start > end {
 			start, end = end, start
 		}
-		buf.Content = append(buf.Content[:start], buf.Content[end+1:]...)
-		buf.CursorY = start
+		last := buf.text.Lines() - 1
+		end = min(end, last)
+		switch {
+		case end < last:
+			buf.deleteText(0, start, 0, end+1)
+		case start > 0:
+			buf.deleteText(buf.lineLen(start-1), start-1, buf.lineLen(end), end)
+		default:
+			// every line selected, keep a single empty one
+			buf.deleteText(0, 0, buf.lineLen(end), end)
+		}
+		buf.CursorY = min(start, buf.text.Lines()-1)
 		buf.CursorX = 0
 	} else {
 		// Delete single character at cursor
-		if cursorY < len(buf.Content) && cursorX < len(buf.Content[cursorY]) {
-			line := buf.Content[cursorY]
-			buf.Content[cursorY] = append(line[:cursorX], line[cursorX+1:]...)
-		} else if cursorY < len(buf.Content)-1 {
+		if cursorY < buf.text.Lines() && cursorX < buf.lineLen(cursorY) {
+			buf.deleteText(cursorX, cursorY, cursorX+1, cursorY)
+		} else if cursorY < buf.text.Lines()-1 {
 			// Join next line if at end
-			buf.Content[cursorY] = append(buf.Content[cursorY], buf.Content[cursorY+1]...)
-			buf.Content = append(buf.Content[:cursorY+1], buf.Content[cursorY+2:]...)
+			buf.deleteText(buf.lineLen(cursorY), cursorY, 0, cursorY+1)
 		}
 	}
 }
 
 // DeleteSelection removes text from selStart -> selEnd (multi-line capable)
 func (buf *Buffer) DeleteSelection(selStartX, selStartY, selEndX, selEndY int) {
-	// Ensure selection coordinates are ordered
-	if selStartY > selEndY || (selStartY == selEndY && selStartX > selEndX) {
-		selStartX, selEndX = selEndX, selStartX
-		selStartY, selEndY = selEndY, selStartY
-	}
+	buf.mu.Lock()
+	defer buf.mu.Unlock()
+	buf.openStep()
+	defer buf.closeStep()
 
-	if selStartY == selEndY {
-		// Single-line selection
-		line := buf.Content[selStartY]
-		buf.Content[selStartY] = append(line[:selStartX], line[selEndX:]...)
-	} else {
-		// Multi-line selection
-		startLine := buf.Content[selStartY][:selStartX]
-		endLine := buf.Content[selEndY][selEndX:]
-		buf.Content[selStartY] = append(startLine, endLine...)
-
-		// Remove intermediate lines
-		buf.Content = append(buf.Content[:selStartY+1], buf.Content[selEndY+1:]...)
-	}
+	selStartX, selStartY, selEndX, selEndY = buf.orderRange(selStartX, selStartY, selEndX, selEndY)
+	buf.deleteText(selStartX, selStartY, selEndX, selEndY)
 
 	// Move cursor to start of selection
 	buf.CursorY = selStartY
@@ -264,99 +574,117 @@ func (buf *Buffer) DeleteSelection(selStartX, selStartY, selEndX, selEndY int) {
 // Buffer methods
 // CopySelection copies the selected text to clipboard (multi-line)
 func (buf *Buffer) CopySelection(selStartX, selStartY, selEndX, selEndY int) []rune {
-	startY, endY := selStartY, selEndY
-	startX, endX := selStartX, selEndX
-	if startY > endY || (startY == endY && startX > endX) {
-		startY, endY = endY, startY
-		startX, endX = endX, startX
-	}
-
-	var copied []rune
-	for y := startY; y <= endY; y++ {
-		line := buf.Content[y]
-		if y == startY && y == endY {
-			copied = append(copied, line[startX:endX]...)
-		} else if y == startY {
-			copied = append(copied, line[startX:]...)
-			copied = append(copied, '\n')
-		} else if y == endY {
-			copied = append(copied, line[:endX]...)
-		} else {
-			copied = append(copied, line...)
-			copied = append(copied, '\n')
-		}
-	}
-	return copied
+	buf.mu.RLock()
+	defer buf.mu.RUnlock()
+
+	startX, startY, endX, endY := buf.orderRange(selStartX, selStartY, selEndX, selEndY)
+	return buf.textRange(startX, startY, endX, endY)
 }
 
 // CutSelection removes the selection and returns the text
 func (buf *Buffer) CutSelection(selStartX, selStartY, selEndX, selEndY int) []rune {
-	text := buf.CopySelection(selStartX, selStartY, selEndX, selEndY)
-
-	startY, endY := selStartY, selEndY
-	startX, endX := selStartX, selEndX
-	if startY > endY || (startY == endY && startX > endX) {
-		startY, endY = endY, startY
-		startX, endX = endX, startX
-	}
+	buf.mu.Lock()
+	defer buf.mu.Unlock()
+	buf.openStep()
+	defer buf.closeStep()
 
-	if startY == endY {
-		buf.Content[startY] = append(buf.Content[startY][:startX], buf.Content[startY][endX:]...)
-	} else {
-		// first line
-		buf.Content[startY] = buf.Content[startY][:startX]
-		// last line
-		buf.Content[endY] = buf.Content[endY][endX:]
-		// merge first and last line
-		buf.Content[startY] = append(buf.Content[startY], buf.Content[endY]...)
-		// remove intermediate lines
-		buf.Content = append(buf.Content[:startY+1], buf.Content[endY+1:]...)
-	}
+	startX, startY, endX, endY := buf.orderRange(selStartX, selStartY, selEndX, selEndY)
+	text := buf.deleteText(startX, startY, endX, endY)
 
 	// Remove blank line if selection covered a full empty line
-	if len(buf.Content) > 0 && len(buf.Content[startY]) == 0 {
-		buf.Content = append(buf.Content[:startY], buf.Content[startY+1:]...)
+	if buf.lineLen(startY) == 0 && startY < buf.text.Lines()-1 {
+		buf.deleteText(0, startY, 0, startY+1)
 	}
 
 	buf.CursorY = startY
 	buf.CursorX = startX
 
-	return text
+	return []rune(text)
 }
 
-// PasteClipboard inserts text at cursor
+// PasteClipboard inserts text at cursor, its line breaks become '\n'
 func (buf *Buffer) PasteClipboard(text []rune) {
-	if buf.CursorY >= len(buf.Content) {
-		buf.Content = append(buf.Content, []rune{})
-	}
+	buf.mu.Lock()
+	defer buf.mu.Unlock()
+	buf.openStep()
+	defer buf.closeStep()
+
+	buf.clampCursor()
+	buf.CursorX, buf.CursorY = buf.insertText(buf.CursorX, buf.CursorY, normalizeNewlines(text), false)
+}
+
+// --- Low level edits ---
+// Every mutation goes through insertText/deleteText so it lands in the history.
 
-	lines := [][]rune{}
-	curr := []rune{}
+// insertText inserts text at (x, y) and returns the position right after it
+func (b *Buffer) insertText(x, y int, text []rune, typed bool) (int, int) {
+	endX, endY := x, y
 	for _, r := range text {
 		if r == '\n' {
-			lines = append(lines, curr)
-			curr = []rune{}
+			endX, endY = 0, endY+1
 		} else {
-			curr = append(curr, r)
+			endX++
 		}
 	}
-	lines = append(lines, curr)
 
-	// Insert lines at cursor
-	line := buf.Content[buf.CursorY]
-	before := line[:buf.CursorX]
-	after := line[buf.CursorX:]
+	b.text.Insert(b.offset(x, y), string(text))
+	b.revision++
+	b.logEdit(y, 1, endY-y+1)
+	b.record(editOp{insert: true, x: x, y: y, text: string(text), typed: typed})
+	return endX, endY
+}
 
-	buf.Content[buf.CursorY] = append(before, lines[0]...)
-	if len(lines) > 1 {
-		buf.Content = append(buf.Content[:buf.CursorY+1], append(lines[1:], buf.Content[buf.CursorY+1:]...)...)
+// deleteText removes the ordered range (sx, sy) -> (ex, ey) and returns it
+func (b *Buffer) deleteText(sx, sy, ex, ey int) string {
+	removed := b.text.Slice(b.offset(sx, sy), b.offset(ex, ey))
+	if removed == "" {
+		return removed
 	}
-	// Append rest of original line to last pasted line
-	lastLineIdx := buf.CursorY + len(lines) - 1
-	buf.Content[lastLineIdx] = append(buf.Content[lastLineIdx], after...)
 
-	buf.CursorY = lastLineIdx
-	buf.CursorX = len(lines[len(lines)-1])
+	b.text.Delete(b.offset(sx, sy), b.offset(ex, ey))
+	b.revision++
+	b.logEdit(sy, ey-sy+1, 1)
+	b.record(editOp{insert: false, x: sx, y: sy, text: removed})
+	return removed
+}
+
+// textRange returns the text between two ordered positions, lines joined by '\n'
+func (b *Buffer) textRange(sx, sy, ex, ey int) []rune {
+	return []rune(b.text.Slice(b.offset(sx, sy), b.offset(ex, ey)))
+}
+
+// offset converts a (column, line) position to a rune offset in the rope
+func (b *Buffer) offset(x, y int) int {
+	start, end := b.text.LineBounds(y)
+	return start + max(0, min(x, end-start))
+}
+
+func (b *Buffer) line(y int) string {
+	return b.text.Slice(b.text.LineBounds(y))
+}
+
+func (b *Buffer) lineLen(y int) int {
+	start, end := b.text.LineBounds(y)
+	return end - start
+}
+
+// orderRange makes sure the start of a range comes before its end and clamps it to the content
+func (b *Buffer) orderRange(sx, sy, ex, ey int) (int, int, int, int) {
+	if sy > ey || (sy == ey && sx > ex) {
+		sx, ex = ex, sx
+		sy, ey = ey, sy
+	}
+	last := b.text.Lines() - 1
+	sy = max(0, min(sy, last))
+	ey = max(0, min(ey, last))
+	sx = max(0, min(sx, b.lineLen(sy)))
+	ex = max(0, min(ex, b.lineLen(ey)))
+	return sx, sy, ex, ey
+}
+
+func (b *Buffer) clampCursor() {
+	b.CursorY = max(0, min(b.CursorY, b.text.Lines()-1))
+	b.CursorX = max(0, min(b.CursorX, b.lineLen(b.CursorY)))
 }
 
 func (b *Buffer) ParentFolder() string {
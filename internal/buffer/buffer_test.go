@@ -0,0 +1,76 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+)
+
+// benchSizes are the buffer sizes typing is measured on; the cost of an
+// edit should barely move between them
+var benchSizes = []struct {
+	name  string
+	bytes int
+}{
+	{"1MB", 1 << 20},
+	{"300MB", 300 << 20},
+}
+
+const benchLine = "the quick brown fox jumps over the lazy dog, then does it again\n"
+
+// benchBuffer is an unnamed buffer of about size bytes with the cursor in
+// the middle of its middle line
+func benchBuffer(b *testing.B, size int) *Buffer {
+	b.Helper()
+	if size > 32<<20 && testing.Short() {
+		b.Skip("large buffer skipped in short mode")
+	}
+	buf := NewBuffer("")
+	buf.text = newRope(strings.Repeat(benchLine, size/len(benchLine)))
+	buf.CursorY = buf.LineCount() / 2
+	buf.CursorX = len(benchLine) / 2
+	return buf
+}
+
+func BenchmarkInsertRune(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			buf := benchBuffer(b, size.bytes)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				buf.InsertRune('x')
+			}
+		})
+	}
+}
+
+func BenchmarkDeleteRune(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			buf := benchBuffer(b, size.bytes)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if buf.CursorX == 0 && buf.CursorY == 0 {
+					// ran out of text before the cursor, start over
+					b.StopTimer()
+					buf = benchBuffer(b, size.bytes)
+					b.StartTimer()
+				}
+				buf.DeleteRune()
+			}
+		})
+	}
+}
+
+func BenchmarkLine(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			buf := benchBuffer(b, size.bytes)
+			lines := buf.LineCount()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// stride through the file so the lookups aren't all alike
+				buf.Line(i * 7919 % lines)
+			}
+		})
+	}
+}
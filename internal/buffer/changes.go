@@ -0,0 +1,54 @@
+package buffer
+
+// Only this many line edits are remembered, readers further behind start over
+const maxLineEdits = 1024
+
+// LineEdit describes a text change in lines: the Old lines starting at Line
+// were replaced by New lines. Revision is the buffer revision after the edit.
+type LineEdit struct {
+	Revision int
+	Line     int
+	Old      int
+	New      int
+}
+
+// changeLog keeps recent line edits so views such as the highlighter can
+// catch up on what changed instead of starting over
+type changeLog struct {
+	edits []LineEdit
+	floor int // the log is complete for revisions >= floor
+}
+
+// logEdit records an edit at the current revision; callers hold b.mu
+func (b *Buffer) logEdit(line, old, new int) {
+	c := &b.changes
+	c.edits = append(c.edits, LineEdit{Revision: b.revision, Line: line, Old: old, New: new})
+	if len(c.edits) > maxLineEdits {
+		drop := len(c.edits) / 2
+		c.floor = c.edits[drop-1].Revision
+		c.edits = append([]LineEdit{}, c.edits[drop:]...)
+	}
+}
+
+// resetChanges forgets the log after the whole text was replaced; callers hold b.mu
+func (b *Buffer) resetChanges() {
+	b.changes = changeLog{floor: b.revision}
+}
+
+// EditsSince returns the edits made after revision, oldest first. ok is
+// false when the log doesn't reach back that far.
+func (b *Buffer) EditsSince(revision int) (edits []LineEdit, ok bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	c := &b.changes
+	if revision < c.floor {
+		return nil, false
+	}
+	for i, e := range c.edits {
+		if e.Revision > revision {
+			return append([]LineEdit{}, c.edits[i:]...), true
+		}
+	}
+	return nil, true
+}
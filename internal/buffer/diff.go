@@ -0,0 +1,182 @@
+package buffer
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+// Above this many edits the diff gives up and replaces everything, the
+// trace memory grows with the square of the edit count
+const maxDiffEdits = 2000
+
+type diffKind int
+
+const (
+	diffEqual diffKind = iota
+	diffDelete
+	diffInsert
+)
+
+type diffLine struct {
+	kind diffKind
+	text string
+	a, b int // line index in each side
+}
+
+func splitDiffLines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, LF), LF)
+}
+
+// UnifiedDiff renders the changes from a to b in unified diff format,
+// empty when both are equal
+func UnifiedDiff(nameA, nameB string, a, b []string) string {
+	lines := diffLines(a, b)
+
+	var sb strings.Builder
+	for start := 0; start < len(lines); {
+		// find the next change
+		for start < len(lines) && lines[start].kind == diffEqual {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+
+		// extend the hunk while changes are close together
+		end := start
+		for i := start; i < len(lines); i++ {
+			if lines[i].kind != diffEqual {
+				end = i + 1
+			} else if i-end >= diffContext*2 {
+				break
+			}
+		}
+		from := max(0, start-diffContext)
+		to := min(len(lines), end+diffContext)
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		writeHunk(&sb, lines[from:to])
+		start = to
+	}
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, hunk []diffLine) {
+	var countA, countB int
+	startA, startB := -1, -1
+	for _, l := range hunk {
+		if l.kind != diffInsert {
+			countA++
+			if startA < 0 {
+				startA = l.a
+			}
+		}
+		if l.kind != diffDelete {
+			countB++
+			if startB < 0 {
+				startB = l.b
+			}
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", startA+1, countA, startB+1, countB)
+	for _, l := range hunk {
+		prefix := " "
+		switch l.kind {
+		case diffDelete:
+			prefix = "-"
+		case diffInsert:
+			prefix = "+"
+		}
+		sb.WriteString(prefix + l.text + "\n")
+	}
+}
+
+// diffLines computes a shortest edit script with Myers' algorithm
+func diffLines(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	maxD := min(n+m, maxDiffEdits)
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+	var trace [][]int
+
+	for d := 0; d <= maxD; d++ {
+		// only diagonals -d..d can be reached, keep just that window
+		snapshot := make([]int, 2*d+3)
+		copy(snapshot, v[offset-d-1:offset+d+2])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+	return replaceAll(a, b)
+}
+
+// replaceAll is the fallback edit script: delete every line of a, insert b
+func replaceAll(a, b []string) []diffLine {
+	out := make([]diffLine, 0, len(a)+len(b))
+	for i, l := range a {
+		out = append(out, diffLine{kind: diffDelete, text: l, a: i})
+	}
+	for i, l := range b {
+		out = append(out, diffLine{kind: diffInsert, text: l, a: len(a), b: i})
+	}
+	return out
+}
+
+func backtrack(a, b []string, trace [][]int) []diffLine {
+	var out []diffLine
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		base := d + 1 // index of diagonal 0 in the window
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[base+k-1] < v[base+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[base+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			out = append(out, diffLine{kind: diffEqual, text: a[x], a: x, b: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				out = append(out, diffLine{kind: diffInsert, text: b[y], a: x, b: y})
+			} else {
+				x--
+				out = append(out, diffLine{kind: diffDelete, text: a[x], a: x, b: y})
+			}
+		}
+	}
+
+	// reverse into document order
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
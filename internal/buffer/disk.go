@@ -0,0 +1,137 @@
+package buffer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// diskState identifies a version of the file on disk
+type diskState struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+	hash    []byte
+}
+
+func newDiskState(path string, hash []byte) diskState {
+	d := diskState{hash: hash}
+	if info, err := os.Stat(path); err == nil {
+		d.exists = true
+		d.modTime = info.ModTime()
+		d.size = info.Size()
+	}
+	return d
+}
+
+// update records the state after the buffer wrote the file itself
+func (d *diskState) update(path string, hash []byte) {
+	*d = diskState{hash: hash}
+	if info, err := os.Stat(path); err == nil {
+		d.exists = true
+		d.modTime = info.ModTime()
+		d.size = info.Size()
+	}
+}
+
+// DiskChanged reports whether the file on disk is no longer the version the
+// buffer was loaded from or saved to. mtime and size are checked first, the
+// content hash settles it when they differ (e.g. a touch without changes).
+func (b *Buffer) DiskChanged() bool {
+	if b.File == "" {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info, err := os.Stat(b.File)
+	if err != nil {
+		return b.disk.exists
+	}
+	if b.disk.exists && info.ModTime().Equal(b.disk.modTime) && info.Size() == b.disk.size {
+		return false
+	}
+
+	sum, err := fileHash(b.File)
+	if err != nil {
+		return false
+	}
+	if bytes.Equal(sum, b.disk.hash) {
+		b.disk = newDiskState(b.File, sum)
+		return false
+	}
+	return true
+}
+
+// fileHash is the sha256 of a file, read in chunks
+func fileHash(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// ErrDeleted is returned by Reload when the file is gone from disk
+var ErrDeleted = errors.New("file was deleted on disk")
+
+// Reload replaces the content with the file on disk, keeping the encoding
+// and, as far as possible, the cursor. When the file was deleted the text
+// is kept and the buffer turns modified, so saving writes it back.
+func (b *Buffer) Reload() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := os.Stat(b.File); errors.Is(err, fs.ErrNotExist) {
+		b.disk = newDiskState(b.File, nil)
+		b.formatChanged = true
+		return ErrDeleted
+	}
+
+	x, y := b.CursorX, b.CursorY
+	if err := b.load(b.Encoding); err != nil {
+		return err
+	}
+	b.CursorX, b.CursorY = x, y
+	b.clampCursor()
+	return nil
+}
+
+// KeepLocal ignores the current version on disk; the buffer stays modified
+// and the next Save overwrites the file
+func (b *Buffer) KeepLocal() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sum, _ := fileHash(b.File)
+	b.disk = newDiskState(b.File, sum)
+	b.formatChanged = true
+}
+
+// DiffWithDisk returns a unified diff from the file on disk to the buffer
+func (b *Buffer) DiffWithDisk() (string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	data, err := os.ReadFile(b.File)
+	if err != nil {
+		return "", err
+	}
+	disk, err := decode(data, b.Encoding)
+	if err != nil {
+		return "", err
+	}
+	disk = strings.TrimPrefix(strings.ReplaceAll(disk, CRLF, LF), utf8BOM)
+	return UnifiedDiff(b.File+" (disk)", b.File+" (buffer)", splitDiffLines(disk), splitDiffLines(b.text.String())), nil
+}
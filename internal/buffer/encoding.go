@@ -0,0 +1,173 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+const (
+	UTF8        = "UTF-8"
+	UTF16LE     = "UTF-16LE"
+	UTF16BE     = "UTF-16BE"
+	Latin1      = "ISO-8859-1"
+	Windows1252 = "Windows-1252"
+	ShiftJIS    = "Shift_JIS"
+)
+
+// Encodings offered by detection and the encoding commands; UTF-8 needs no transform
+var encodings = map[string]encoding.Encoding{
+	UTF8:        nil,
+	UTF16LE:     xunicode.UTF16(xunicode.LittleEndian, xunicode.IgnoreBOM),
+	UTF16BE:     xunicode.UTF16(xunicode.BigEndian, xunicode.IgnoreBOM),
+	Latin1:      charmap.ISO8859_1,
+	Windows1252: charmap.Windows1252,
+	ShiftJIS:    japanese.ShiftJIS,
+}
+
+// LookupEncoding resolves a user supplied encoding name such as "utf-16le",
+// "latin1" or "sjis" to its canonical name
+func LookupEncoding(name string) (string, encoding.Encoding, error) {
+	for canonical, enc := range encodings {
+		if strings.EqualFold(canonical, name) {
+			return canonical, enc, nil
+		}
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return "", nil, fmt.Errorf("unknown encoding %q", name)
+	}
+	canonical, _ := htmlindex.Name(enc)
+	if strings.EqualFold(canonical, "utf-8") {
+		return UTF8, nil, nil
+	}
+	return canonical, enc, nil
+}
+
+// DetectEncoding guesses the encoding of raw file data the way Load does
+func DetectEncoding(data []byte) string {
+	return detectEncoding(data)
+}
+
+// IsUTF16 reports whether the named encoding is UTF-16, whose text is full
+// of zero bytes without being binary
+func IsUTF16(name string) bool {
+	return name == UTF16LE || name == UTF16BE
+}
+
+// DecodeText converts file data in the named encoding to the text a buffer
+// holds, without a BOM, so columns match those of an open buffer
+func DecodeText(data []byte, name string) (string, error) {
+	text, err := decode(data, name)
+	return strings.TrimPrefix(text, utf8BOM), err
+}
+
+// detectEncoding guesses the encoding of raw file data
+func detectEncoding(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return UTF8
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return UTF16LE
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return UTF16BE
+	}
+	if le, ok := looksLikeUTF16(data); ok {
+		if le {
+			return UTF16LE
+		}
+		return UTF16BE
+	}
+	if utf8.Valid(data) {
+		return UTF8
+	}
+	if looksLikeShiftJIS(data) {
+		return ShiftJIS
+	}
+	if decoded, err := charmap.Windows1252.NewDecoder().Bytes(data); err == nil && !bytes.ContainsRune(decoded, utf8.RuneError) {
+		return Windows1252
+	}
+	return Latin1
+}
+
+// looksLikeUTF16 spots BOM-less UTF-16 text by its zero bytes: mostly ASCII
+// text has a zero in every other byte
+func looksLikeUTF16(data []byte) (littleEndian bool, ok bool) {
+	n := min(len(data), 4096) &^ 1
+	if n < 4 {
+		return false, false
+	}
+	var evenZeros, oddZeros int
+	for i := 0; i < n; i += 2 {
+		if data[i] == 0 {
+			evenZeros++
+		}
+		if data[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	pairs := n / 2
+	switch {
+	case oddZeros > pairs*2/5 && evenZeros <= pairs/20:
+		return true, true
+	case evenZeros > pairs*2/5 && oddZeros <= pairs/20:
+		return false, true
+	}
+	return false, false
+}
+
+// looksLikeShiftJIS decodes data as Shift-JIS and accepts it when nothing is
+// invalid and the non-ASCII text is mostly Japanese
+func looksLikeShiftJIS(data []byte) bool {
+	decoded, err := japanese.ShiftJIS.NewDecoder().Bytes(data)
+	if err != nil || bytes.ContainsRune(decoded, utf8.RuneError) {
+		return false
+	}
+	var japaneseRunes, other int
+	for _, r := range string(decoded) {
+		switch {
+		case r < utf8.RuneSelf:
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han) || (r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF):
+			japaneseRunes++
+		default:
+			other++
+		}
+	}
+	return japaneseRunes > 0 && japaneseRunes >= other*2
+}
+
+// decode converts raw data in the named encoding to UTF-8
+func decode(data []byte, name string) (string, error) {
+	enc, ok := encodings[name]
+	if !ok {
+		var err error
+		if _, enc, err = LookupEncoding(name); err != nil {
+			return "", err
+		}
+	}
+	if enc == nil {
+		return string(data), nil
+	}
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", fmt.Errorf("decode as %s: %w", name, err)
+	}
+	return string(out), nil
+}
+
+// encoderFor returns the encoding used to write a buffer, nil for UTF-8
+func encoderFor(name string) encoding.Encoding {
+	if enc, ok := encodings[name]; ok {
+		return enc
+	}
+	_, enc, _ := LookupEncoding(name)
+	return enc
+}
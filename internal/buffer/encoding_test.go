@@ -0,0 +1,136 @@
+package buffer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// encodeText writes text in the named encoding the way Save does
+func encodeText(t *testing.T, text, name string) []byte {
+	t.Helper()
+	enc := encoderFor(name)
+	if enc == nil {
+		return []byte(text)
+	}
+	data, err := enc.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatalf("encode %q as %s: %v", text, name, err)
+	}
+	return data
+}
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, UTF8},
+		{"ascii", []byte("plain text\n"), UTF8},
+		{"utf-8", []byte("naïve café\n"), UTF8},
+		{"utf-8 bom", []byte("\xef\xbb\xbfhi"), UTF8},
+		{"utf-16le bom", []byte("\xff\xfeh\x00i\x00"), UTF16LE},
+		{"utf-16be bom", []byte("\xfe\xff\x00h\x00i"), UTF16BE},
+		{"utf-16le without bom", []byte("h\x00e\x00l\x00l\x00o\x00"), UTF16LE},
+		{"utf-16be without bom", []byte("\x00h\x00e\x00l\x00l\x00o"), UTF16BE},
+		{"shift-jis", []byte("\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd\n"), ShiftJIS},
+		// curly quotes only exist in Windows-1252
+		{"windows-1252", []byte("\x93quoted\x94 caf\xe9"), Windows1252},
+		// 0x81 is unassigned in Windows-1252
+		{"latin-1", []byte("caf\xe9 \x81"), Latin1},
+	}
+	for _, tt := range tests {
+		if got := DetectEncoding(tt.data); got != tt.want {
+			t.Errorf("%s: DetectEncoding(%q) = %s, want %s", tt.name, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestLookupEncoding(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"utf-8", UTF8},
+		{"UTF8", UTF8},
+		{"utf-16le", UTF16LE},
+		{"latin1", "windows-1252"},
+		{"sjis", "shift_jis"},
+		{"Shift_JIS", ShiftJIS},
+	}
+	for _, tt := range tests {
+		if got, _, err := LookupEncoding(tt.in); err != nil || got != tt.want {
+			t.Errorf("LookupEncoding(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+	if _, _, err := LookupEncoding("klingon"); err == nil {
+		t.Error("LookupEncoding of an unknown name succeeded")
+	}
+}
+
+func TestDecodeUnknownEncoding(t *testing.T) {
+	if text, err := decode([]byte("abc"), "klingon"); err == nil {
+		t.Fatalf("decode with an unknown encoding = %q, want an error", text)
+	}
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	tests := []struct {
+		enc, text string
+		detected  string
+	}{
+		{UTF8, "naïve 漢字 🙂\n", UTF8},
+		{UTF16LE, "naïve 漢字 🙂\n", UTF16LE},
+		{UTF16BE, "naïve 漢字 🙂\n", UTF16BE},
+		// Windows-1252 is a superset, the bytes come out the same
+		{Latin1, "naïve café\n", Windows1252},
+		{Windows1252, "“naïve” café €5\n", Windows1252},
+		{ShiftJIS, "こんにちは、世界\n", ShiftJIS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.enc, func(t *testing.T) {
+			data := encodeText(t, tt.text, tt.enc)
+			got, err := DecodeText(data, tt.enc)
+			if err != nil || got != tt.text {
+				t.Fatalf("DecodeText = %q, %v, want %q", got, err, tt.text)
+			}
+
+			// the buffer detects it and writes the same bytes back
+			path := filepath.Join(t.TempDir(), "file.txt")
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			b := NewBuffer(path)
+			if b.Encoding != tt.detected || b.String() != tt.text[:len(tt.text)-1] {
+				t.Fatalf("loaded as %s: %q", b.Encoding, b.String())
+			}
+			if err := b.Save(); err != nil {
+				t.Fatal(err)
+			}
+			if saved, _ := os.ReadFile(path); string(saved) != string(data) {
+				t.Fatalf("saved % x, want % x", saved, data)
+			}
+		})
+	}
+}
+
+func TestSaveWithEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	b := NewBuffer(path)
+	b.PasteClipboard([]rune("café"))
+	if err := b.SaveWithEncoding("latin1"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "caf\xe9\n" {
+		t.Fatalf("saved %q", data)
+	}
+
+	// text the new encoding can't hold keeps the old one
+	b.SetLine(0, "漢字")
+	if err := b.SaveWithEncoding(Latin1); err == nil {
+		t.Fatal("saved 漢字 as Latin-1")
+	}
+	if b.Encoding != "windows-1252" {
+		t.Fatalf("encoding after a failed save = %s", b.Encoding)
+	}
+}
@@ -0,0 +1,275 @@
+package buffer
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// The undo history keeps at most this many steps and about this many bytes
+// of edited text; the oldest steps are dropped first. The newest step is
+// always kept, so even a huge Replace All can be undone.
+const (
+	maxUndoSteps = 5000
+	maxUndoBytes = 64 << 20
+)
+
+// Selection mirrors the editor selection so undo steps can restore it
+type Selection struct {
+	Active bool
+	StartX int
+	StartY int
+	EndX   int
+	EndY   int
+}
+
+// editOp is a single insertion or deletion starting at (x, y)
+type editOp struct {
+	insert bool
+	x, y   int
+	text   string
+	typed  bool // inserted by InsertRune, may be merged with neighbouring typing
+}
+
+// undoStep groups the ops of one user action together with the cursor and
+// selection state before and after it
+type undoStep struct {
+	id    int
+	ops   []editOp
+	bytes int // text held by ops
+
+	cursorBeforeX, cursorBeforeY int
+	cursorAfterX, cursorAfterY   int
+	selBefore, selAfter          Selection
+}
+
+type history struct {
+	undo []*undoStep
+	redo []*undoStep
+
+	open     *undoStep
+	depth    int
+	replay   bool // applying undo/redo, don't record
+	noMerge  bool // next step must not be merged into the previous one
+	selStart Selection
+
+	nextID  int
+	savedID int // id of the top undo step when the buffer was last saved
+
+	bytes   int // text held by the undo steps
+	floorID int // id of the newest step dropped to stay within the limits
+}
+
+// top returns the id of the most recent undo step; with none left it is the
+// step the oldest one was built on, 0 for the loaded file
+func (h *history) top() int {
+	if len(h.undo) == 0 {
+		return h.floorID
+	}
+	return h.undo[len(h.undo)-1].id
+}
+
+// push adds a committed step to the undo stack and drops the oldest steps
+// over the limits
+func (h *history) push(step *undoStep) {
+	h.undo = append(h.undo, step)
+	h.bytes += step.bytes
+	drop := 0
+	for len(h.undo)-drop > 1 && (len(h.undo)-drop > maxUndoSteps || h.bytes > maxUndoBytes) {
+		h.bytes -= h.undo[drop].bytes
+		h.floorID = h.undo[drop].id
+		drop++
+	}
+	if drop > 0 {
+		// copy so the dropped steps can be collected
+		h.undo = append([]*undoStep(nil), h.undo[drop:]...)
+	}
+}
+
+// markSaved remembers the current state as the one on disk
+func (h *history) markSaved() {
+	h.savedID = h.top()
+	h.noMerge = true
+}
+
+// typing reports whether the step only contains typed runes
+func (s *undoStep) typing() bool {
+	for _, op := range s.ops {
+		if !op.insert || !op.typed {
+			return false
+		}
+	}
+	return len(s.ops) > 0
+}
+
+// BeginStep starts a group of edits that is undone as a whole.
+// sel is the editor selection before the edit.
+func (b *Buffer) BeginStep(sel Selection) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.history.depth == 0 {
+		b.history.selStart = sel
+	}
+	b.openStep()
+}
+
+// EndStep closes a group started with BeginStep.
+// sel is the editor selection after the edit.
+func (b *Buffer) EndStep(sel Selection) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.history.open != nil && b.history.depth == 1 {
+		b.history.open.selAfter = sel
+	}
+	b.closeStep()
+}
+
+// BreakUndoGroup stops the next typed runes from merging into the current undo step
+func (b *Buffer) BreakUndoGroup() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.history.noMerge = true
+}
+
+func (b *Buffer) CanUndo() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.history.undo) > 0
+}
+
+func (b *Buffer) CanRedo() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.history.redo) > 0
+}
+
+// Undo reverts the last step and returns the selection to restore
+func (b *Buffer) Undo() (Selection, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := &b.history
+	if len(h.undo) == 0 {
+		return Selection{}, false
+	}
+	step := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	h.bytes -= step.bytes
+
+	h.replay = true
+	for i := len(step.ops) - 1; i >= 0; i-- {
+		b.applyOp(step.ops[i], true)
+	}
+	h.replay = false
+
+	h.redo = append(h.redo, step)
+	h.noMerge = true
+	b.CursorX, b.CursorY = step.cursorBeforeX, step.cursorBeforeY
+	b.clampCursor()
+	return step.selBefore, true
+}
+
+// Redo re-applies the last undone step and returns the selection to restore
+func (b *Buffer) Redo() (Selection, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := &b.history
+	if len(h.redo) == 0 {
+		return Selection{}, false
+	}
+	step := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+
+	h.replay = true
+	for _, op := range step.ops {
+		b.applyOp(op, false)
+	}
+	h.replay = false
+
+	h.push(step)
+	h.noMerge = true
+	b.CursorX, b.CursorY = step.cursorAfterX, step.cursorAfterY
+	b.clampCursor()
+	return step.selAfter, true
+}
+
+// applyOp replays an op, or its inverse when reverse is set
+func (b *Buffer) applyOp(op editOp, reverse bool) {
+	if op.insert != reverse {
+		b.insertText(op.x, op.y, []rune(op.text), false)
+		return
+	}
+	lines := strings.Count(op.text, "\n")
+	ex := utf8.RuneCountInString(op.text[strings.LastIndexByte(op.text, '\n')+1:])
+	if lines == 0 {
+		ex += op.x
+	}
+	b.deleteText(op.x, op.y, ex, op.y+lines)
+}
+
+// openStep starts (or nests into) the current step; callers hold b.mu
+func (b *Buffer) openStep() {
+	h := &b.history
+	if h.depth == 0 {
+		h.open = &undoStep{
+			cursorBeforeX: b.CursorX,
+			cursorBeforeY: b.CursorY,
+			selBefore:     h.selStart,
+		}
+		h.selStart = Selection{}
+	}
+	h.depth++
+}
+
+// closeStep commits the current step once the outermost caller is done
+func (b *Buffer) closeStep() {
+	h := &b.history
+	if h.depth == 0 {
+		return
+	}
+	h.depth--
+	if h.depth > 0 {
+		return
+	}
+
+	step := h.open
+	h.open = nil
+	if step == nil || len(step.ops) == 0 {
+		return
+	}
+	step.cursorAfterX, step.cursorAfterY = b.CursorX, b.CursorY
+
+	// coalesce consecutive typing into one step
+	if n := len(h.undo); n > 0 && !h.noMerge && step.typing() {
+		prev := h.undo[n-1]
+		if prev.typing() && prev.cursorAfterX == step.cursorBeforeX && prev.cursorAfterY == step.cursorBeforeY {
+			prev.ops = append(prev.ops, step.ops...)
+			prev.bytes += step.bytes
+			h.bytes += step.bytes
+			prev.cursorAfterX, prev.cursorAfterY = step.cursorAfterX, step.cursorAfterY
+			prev.selAfter = step.selAfter
+			h.redo = nil
+			return
+		}
+	}
+
+	h.nextID++
+	step.id = h.nextID
+	h.push(step)
+	h.redo = nil
+	h.noMerge = false
+}
+
+// record adds an op to the open step, creating one if needed
+func (b *Buffer) record(op editOp) {
+	h := &b.history
+	if h.replay {
+		return
+	}
+	if h.open == nil {
+		b.openStep()
+		defer b.closeStep()
+	}
+	h.open.ops = append(h.open.ops, op)
+	h.open.bytes += len(op.text)
+}
@@ -0,0 +1,205 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+)
+
+// typeText types s rune by rune the way the editor does, one step per key
+func typeText(b *Buffer, s string) {
+	for _, r := range s {
+		b.BeginStep(Selection{})
+		if r == '\n' {
+			b.BreakUndoGroup()
+			b.InsertLine()
+		} else {
+			b.InsertRune(r)
+		}
+		b.EndStep(Selection{})
+	}
+}
+
+// undoAll undoes until nothing is left and returns the number of steps
+func undoAll(b *Buffer) int {
+	n := 0
+	for b.CanUndo() {
+		b.Undo()
+		n++
+	}
+	return n
+}
+
+func TestHistoryCoalescing(t *testing.T) {
+	tests := []struct {
+		name  string
+		edit  func(b *Buffer)
+		steps int
+		// still modified once everything is undone
+		modified bool
+	}{
+		{"typing is one step", func(b *Buffer) { typeText(b, "hello") }, 1, false},
+		{"a new line breaks the group", func(b *Buffer) { typeText(b, "ab\ncd") }, 3, false},
+		{"a cursor jump breaks the group", func(b *Buffer) {
+			typeText(b, "ab")
+			b.CursorX = 0
+			typeText(b, "cd")
+		}, 2, false},
+		{"BreakUndoGroup breaks the group", func(b *Buffer) {
+			typeText(b, "ab")
+			b.BreakUndoGroup()
+			typeText(b, "cd")
+		}, 2, false},
+		{"deleting is not typing", func(b *Buffer) {
+			typeText(b, "ab")
+			b.DeleteRune()
+			typeText(b, "c")
+		}, 3, false},
+		{"saving breaks the group", func(b *Buffer) {
+			typeText(b, "ab")
+			b.history.markSaved()
+			typeText(b, "cd")
+		}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBuffer("")
+			tt.edit(b)
+			if steps := undoAll(b); steps != tt.steps {
+				t.Fatalf("undid %d steps, want %d", steps, tt.steps)
+			}
+			if got := b.String(); got != "" {
+				t.Fatalf("text after undoing everything = %q", got)
+			}
+			if b.Modified() != tt.modified {
+				t.Fatalf("Modified() = %v after undoing everything, want %v", b.Modified(), tt.modified)
+			}
+		})
+	}
+}
+
+func TestHistoryGroupedSteps(t *testing.T) {
+	b := NewScratchBuffer("", "one\ntwo\nthree")
+
+	// several edits between BeginStep and EndStep undo as one
+	b.BeginStep(Selection{})
+	b.SetLine(0, "ONE")
+	b.CursorX, b.CursorY = 3, 1
+	b.InsertRune('!')
+	b.CursorX, b.CursorY = 0, 2
+	b.InsertLine()
+	b.EndStep(Selection{})
+
+	// nested steps belong to the outermost one
+	b.BeginStep(Selection{})
+	b.BeginStep(Selection{})
+	b.SetLine(0, "uno")
+	b.EndStep(Selection{})
+	b.SetLine(1, "dos")
+	b.EndStep(Selection{})
+
+	states := []string{"uno\ndos\n\nthree", "ONE\ntwo!\n\nthree", "one\ntwo\nthree"}
+	if got := b.String(); got != states[0] {
+		t.Fatalf("text = %q, want %q", got, states[0])
+	}
+	for _, want := range states[1:] {
+		b.Undo()
+		if got := b.String(); got != want {
+			t.Fatalf("after undo: %q, want %q", got, want)
+		}
+	}
+	if b.CanUndo() {
+		t.Fatal("more steps than grouped")
+	}
+	for i := len(states) - 2; i >= 0; i-- {
+		b.Redo()
+		if got := b.String(); got != states[i] {
+			t.Fatalf("after redo: %q, want %q", got, states[i])
+		}
+	}
+
+	// a new edit drops what could be redone
+	b.Undo()
+	typeText(b, "x")
+	if b.CanRedo() {
+		t.Fatal("redo survived a new edit")
+	}
+}
+
+func TestHistoryCursorAndSelection(t *testing.T) {
+	b := NewScratchBuffer("", "hello world\nsecond")
+	before := Selection{Active: true, StartX: 6, StartY: 0, EndX: 11, EndY: 0}
+	after := Selection{}
+
+	b.CursorX, b.CursorY = 11, 0
+	b.BeginStep(before)
+	b.DeleteSelection(6, 0, 11, 0)
+	b.InsertRune('X')
+	b.EndStep(after)
+	if got := b.String(); got != "hello X\nsecond" {
+		t.Fatalf("text = %q", got)
+	}
+
+	b.CursorX, b.CursorY = 0, 1
+	sel, ok := b.Undo()
+	if !ok || sel != before {
+		t.Fatalf("Undo selection = %+v, %v, want %+v", sel, ok, before)
+	}
+	if b.CursorX != 11 || b.CursorY != 0 {
+		t.Fatalf("cursor after undo = %d,%d, want 11,0", b.CursorX, b.CursorY)
+	}
+
+	sel, ok = b.Redo()
+	if !ok || sel != after {
+		t.Fatalf("Redo selection = %+v, %v, want %+v", sel, ok, after)
+	}
+	if b.CursorX != 7 || b.CursorY != 0 {
+		t.Fatalf("cursor after redo = %d,%d, want 7,0", b.CursorX, b.CursorY)
+	}
+}
+
+func TestHistoryMultilineOps(t *testing.T) {
+	text := "añb\n漢字\n\nend"
+	b := NewScratchBuffer("", text)
+	b.BeginStep(Selection{})
+	b.CutSelection(1, 0, 1, 3)
+	b.EndStep(Selection{})
+	b.BeginStep(Selection{})
+	b.PasteClipboard([]rune("x\ny\n"))
+	b.EndStep(Selection{})
+
+	b.Undo()
+	b.Undo()
+	if got := b.String(); got != text {
+		t.Fatalf("after undo: %q, want %q", got, text)
+	}
+}
+
+func TestHistoryLimits(t *testing.T) {
+	b := NewBuffer("")
+	for i := 0; i < maxUndoSteps+10; i++ {
+		b.BreakUndoGroup()
+		typeText(b, "x")
+	}
+	if n := len(b.history.undo); n != maxUndoSteps {
+		t.Fatalf("%d undo steps kept, want %d", n, maxUndoSteps)
+	}
+	// the oldest edits can't be undone any more, the buffer stays modified
+	undoAll(b)
+	if got := b.String(); got != strings.Repeat("x", 10) {
+		t.Fatalf("text after undoing everything = %q", got)
+	}
+	if !b.Modified() {
+		t.Fatal("not modified after dropping steps")
+	}
+
+	// a step over the byte limit is kept until the next one
+	var h history
+	h.push(&undoStep{id: 1, bytes: maxUndoBytes + 1})
+	if len(h.undo) != 1 {
+		t.Fatalf("%d undo steps after a huge one, want 1", len(h.undo))
+	}
+	h.push(&undoStep{id: 2, bytes: 1})
+	if len(h.undo) != 1 || h.bytes != 1 || h.top() != 2 || h.floorID != 1 {
+		t.Fatalf("%d undo steps holding %d bytes, top %d floor %d, want only the second", len(h.undo), h.bytes, h.top(), h.floorID)
+	}
+}
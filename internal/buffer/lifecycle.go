@@ -0,0 +1,227 @@
+package buffer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CanonicalPath is the key a file's buffer is stored under: absolute,
+// cleaned and with symlinked folders resolved. A symlinked file keeps its
+// own name so it reads the way it was opened.
+func CanonicalPath(path string) string {
+	path = absPath(path)
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		path = filepath.Join(dir, filepath.Base(path))
+	}
+	return path
+}
+
+// within reports whether path is root or inside it
+func within(path, root string) bool {
+	return path == root || strings.HasPrefix(path, root+string(filepath.Separator))
+}
+
+// SetOnClosed sets the callback run after a buffer was closed. It is
+// called on the goroutine that closed it.
+func (bm *BufferManager) SetOnClosed(cb func(*Buffer)) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bm.onClosed = cb
+}
+
+// SetOnRenamed sets the callback run after Rename moved a buffer's file;
+// buf.File already holds the new path
+func (bm *BufferManager) SetOnRenamed(cb func(buf *Buffer, oldPath string)) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bm.onRenamed = cb
+}
+
+// activate makes buf the active buffer and the most recently used; bm.mu
+// must be held
+func (bm *BufferManager) activate(buf *Buffer) {
+	bm.active = buf
+	bm.recent = slices.DeleteFunc(bm.recent, func(b *Buffer) bool { return b == buf })
+	bm.recent = slices.Insert(bm.recent, 0, buf)
+}
+
+// Activate marks buf as the buffer being worked on, e.g. when its tab is
+// selected
+func (bm *BufferManager) Activate(buf *Buffer) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	if bm.buffers[buf.File] == buf {
+		bm.activate(buf)
+	}
+}
+
+// List returns the open buffers, most recently used first
+func (bm *BufferManager) List() []*Buffer {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+	return slices.Clone(bm.recent)
+}
+
+// release forgets buf: its file is no longer watched and its recovery
+// snapshot is dropped. The active buffer falls back to the next most
+// recently used. bm.mu must be held.
+func (bm *BufferManager) release(buf *Buffer) bool {
+	if bm.buffers[buf.File] != buf {
+		return false
+	}
+	delete(bm.buffers, buf.File)
+	bm.recent = slices.DeleteFunc(bm.recent, func(b *Buffer) bool { return b == buf })
+	if bm.active == buf {
+		bm.active = nil
+		if len(bm.recent) > 0 {
+			bm.active = bm.recent[0]
+		}
+	}
+	if bm.watcher != nil {
+		bm.watcher.Remove(buf.File)
+	}
+	bm.dropSnapshot(buf)
+	return true
+}
+
+// dropSnapshot removes buf's recovery snapshot, if one was written; bm.mu
+// must be held
+func (bm *BufferManager) dropSnapshot(buf *Buffer) {
+	if _, ok := bm.snapshots[buf]; ok {
+		os.Remove(filepath.Join(bm.recoveryDir, recoveryName(buf)))
+		delete(bm.snapshots, buf)
+	}
+}
+
+// closeMatching releases the buffers match picks and tells the onClosed
+// callback about each, most recently used first. Buffers loaded in the
+// background, e.g. by ReplaceInFiles, aren't in recent and go last.
+func (bm *BufferManager) closeMatching(match func(*Buffer) bool) {
+	bm.mu.Lock()
+	all := slices.Clone(bm.recent)
+	for _, buf := range bm.buffers {
+		if !slices.Contains(all, buf) {
+			all = append(all, buf)
+		}
+	}
+	var closed []*Buffer
+	for _, buf := range all {
+		if match(buf) && bm.release(buf) {
+			closed = append(closed, buf)
+		}
+	}
+	cb := bm.onClosed
+	bm.mu.Unlock()
+
+	if cb != nil {
+		for _, buf := range closed {
+			cb(buf)
+		}
+	}
+}
+
+// Close closes a buffer. Unsaved changes are dropped; ask before calling.
+func (bm *BufferManager) Close(buf *Buffer) {
+	bm.closeMatching(func(b *Buffer) bool { return b == buf })
+}
+
+// CloseAll closes every open buffer
+func (bm *BufferManager) CloseAll() {
+	bm.closeMatching(func(*Buffer) bool { return true })
+}
+
+// ClosePath closes the buffer of path, or of every file under it when it
+// is a folder, e.g. after it was deleted
+func (bm *BufferManager) ClosePath(path string) {
+	path = CanonicalPath(path)
+	bm.closeMatching(func(b *Buffer) bool { return within(b.File, path) })
+}
+
+// ModifiedIn returns the buffers with unsaved changes at or under path,
+// e.g. before it is deleted
+func (bm *BufferManager) ModifiedIn(path string) []*Buffer {
+	path = CanonicalPath(path)
+	return slices.DeleteFunc(bm.Modified(), func(b *Buffer) bool { return !within(b.File, path) })
+}
+
+// SaveAll saves every buffer with unsaved changes, carrying on past
+// failures; the error lists each file that couldn't be saved
+func (bm *BufferManager) SaveAll() error {
+	var errs []error
+	for _, buf := range bm.Modified() {
+		if err := buf.Save(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(buf.File), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Rename moves a file or folder on disk and carries the open buffers at or
+// under it along to the new path
+func (bm *BufferManager) Rename(oldPath, newPath string) error {
+	oldPath, newPath = CanonicalPath(oldPath), CanonicalPath(newPath)
+	if oldPath == newPath {
+		return nil
+	}
+	if within(newPath, oldPath) {
+		return fmt.Errorf("cannot move %s into itself", filepath.Base(oldPath))
+	}
+	if _, err := os.Lstat(newPath); err == nil {
+		return fmt.Errorf("%s already exists", filepath.Base(newPath))
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+
+	type renamed struct {
+		buf     *Buffer
+		oldPath string
+	}
+	var moved []renamed
+
+	bm.mu.Lock()
+	for path, buf := range bm.buffers {
+		if !within(path, oldPath) {
+			continue
+		}
+		// the snapshot records the path, write it again with the new one
+		bm.dropSnapshot(buf)
+		if bm.watcher != nil {
+			bm.watcher.Remove(path)
+		}
+		moved = append(moved, renamed{buf, path})
+	}
+	for _, m := range moved {
+		path := newPath + strings.TrimPrefix(m.oldPath, oldPath)
+		delete(bm.buffers, m.oldPath)
+		m.buf.setFile(path)
+		bm.buffers[path] = m.buf
+		if bm.watcher != nil {
+			if err := bm.watcher.Add(path); err != nil {
+				log.Printf("Unable to watch %s: %v", path, err)
+			}
+		}
+	}
+	cb := bm.onRenamed
+	bm.mu.Unlock()
+
+	if cb != nil {
+		for _, m := range moved {
+			cb(m.buf, m.oldPath)
+		}
+	}
+	return nil
+}
+
+// setFile points b at a new path; Save and the recovery snapshots read
+// File from other goroutines
+func (b *Buffer) setFile(path string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.File = path
+}
@@ -0,0 +1,68 @@
+package buffer
+
+import (
+	"bytes"
+	"io"
+	"slices"
+)
+
+// lineEndingFor picks the style used by the majority of lines out of the
+// '\n' and "\r\n" counts, LF when there are none
+func lineEndingFor(lf, crlf int) string {
+	if crlf > 0 && crlf*2 >= lf {
+		return CRLF
+	}
+	return LF
+}
+
+// normalizeNewlines turns the "\r\n" and lone '\r' line breaks of pasted
+// text into the '\n' a buffer holds
+func normalizeNewlines(text []rune) []rune {
+	if !slices.Contains(text, '\r') {
+		return text
+	}
+	out := make([]rune, 0, len(text))
+	for i, r := range text {
+		switch {
+		case r != '\r':
+			out = append(out, r)
+		case i+1 < len(text) && text[i+1] == '\n':
+		default:
+			out = append(out, '\n')
+		}
+	}
+	return out
+}
+
+// lineEndingWriter rewrites '\n' to the buffer's line ending while saving
+type lineEndingWriter struct {
+	w  io.Writer
+	le []byte
+}
+
+func newLineEndingWriter(w io.Writer, le string) io.Writer {
+	if le == "" || le == LF {
+		return w
+	}
+	return &lineEndingWriter{w: w, le: []byte(le)}
+}
+
+func (lw *lineEndingWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			n, err := lw.w.Write(p)
+			return written + n, err
+		}
+		if _, err := lw.w.Write(p[:i]); err != nil {
+			return written, err
+		}
+		if _, err := lw.w.Write(lw.le); err != nil {
+			return written, err
+		}
+		written += i + 1
+		p = p[i+1:]
+	}
+	return written, nil
+}
@@ -0,0 +1,76 @@
+package buffer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPasteNewlines(t *testing.T) {
+	tests := []struct {
+		paste, want string
+	}{
+		{"a\nb", "a\nb"},
+		{"a\r\nb\r\n", "a\nb\n"},
+		{"a\rb\r", "a\nb\n"},
+		{"a\r\r\nb", "a\n\nb"},
+		{"\r", "\n"},
+	}
+	for _, tt := range tests {
+		b := NewScratchBuffer("", "")
+		b.PasteClipboard([]rune(tt.paste))
+		if got := b.String(); got != tt.want {
+			t.Errorf("paste %q: text %q, want %q", tt.paste, got, tt.want)
+		}
+		if wantY := len(b.Lines()) - 1; b.CursorY != wantY {
+			t.Errorf("paste %q: cursor on line %d, want %d", tt.paste, b.CursorY, wantY)
+		}
+	}
+
+	b := NewScratchBuffer("", "ab")
+	b.CursorX = 1
+	b.InsertRune('\r')
+	if got := b.String(); got != "a\nb" {
+		t.Errorf("typing a CR: text %q, want %q", got, "a\nb")
+	}
+}
+
+func TestLoadSaveRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		lineEnding string
+		bom        bool
+	}{
+		{"lf", "one\ntwo\n", LF, false},
+		{"crlf", "one\r\ntwo\r\n", CRLF, false},
+		{"bom", "\ufeffone\ntwo\n", LF, true},
+		{"bom crlf", "\ufeffone\r\ntwo\r\n", CRLF, true},
+		{"no final newline", "one\r\ntwo", CRLF, false},
+		{"empty lines", "\n\n", LF, false},
+		// the minority keeps its CR in the text
+		{"mixed", "one\r\ntwo\nthree\n", LF, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file.txt")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			b := NewBuffer(path)
+			if b.LineEnding != tt.lineEnding || b.BOM != tt.bom {
+				t.Fatalf("loaded with line ending %q, BOM %v, want %q, %v", b.LineEnding, b.BOM, tt.lineEnding, tt.bom)
+			}
+			if err := b.Save(); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.data {
+				t.Fatalf("saved %q, want %q", data, tt.data)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Encoding detection looks at this much of a file. Big files are read in
+// chunks, so a legacy byte far past it ends up as is in a UTF-8 buffer.
+const detectSampleBytes = 1 << 20
+
+// detectSample is the start of the file for detectEncoding, cut after the
+// last full line when the file goes on so no character is split
+func detectSample(r *bufio.Reader) []byte {
+	sample, err := r.Peek(detectSampleBytes)
+	if err != nil {
+		// that's the whole file
+		return sample
+	}
+	if i := bytes.LastIndexByte(sample, '\n'); i >= 0 {
+		return sample[:i+1]
+	}
+	return sample
+}
+
+// loadedText is a file's text and what Save needs to write it back the way
+// it was
+type loadedText struct {
+	text         *rope
+	bom          bool
+	lineEnding   string
+	finalNewline bool
+}
+
+// readText streams decoded text into a rope: the BOM is cut, CRLF line
+// endings become '\n' when they are the majority and the final newline is
+// dropped
+func readText(r io.Reader) (loadedText, error) {
+	var lt loadedText
+	br := bufio.NewReaderSize(r, 64*1024)
+	if bom, _ := br.Peek(len(utf8BOM)); string(bom) == utf8BOM {
+		lt.bom = true
+		br.Discard(len(utf8BOM))
+	}
+
+	var rb ropeBuilder
+	var lf, crlf int
+	prevCR := false
+	chunk := make([]byte, 64*1024)
+	for {
+		n, err := br.Read(chunk)
+		if n > 0 {
+			data := chunk[:n]
+			lf += bytes.Count(data, []byte(LF))
+			crlf += bytes.Count(data, []byte(CRLF))
+			if prevCR && data[0] == '\n' {
+				crlf++
+			}
+			prevCR = data[n-1] == '\r'
+			rb.Write(data)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return loadedText{}, err
+		}
+	}
+
+	lt.lineEnding = lineEndingFor(lf, crlf)
+	if lt.lineEnding == CRLF {
+		rb.dropCR()
+	}
+	lt.finalNewline = rb.trimNewline()
+	lt.text = rb.rope()
+	return lt, nil
+}
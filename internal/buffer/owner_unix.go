@@ -0,0 +1,16 @@
+//go:build !windows
+
+package buffer
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the uid/gid of a file, or -1 when unknown
+func fileOwner(info os.FileInfo) (int, int) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(st.Uid), int(st.Gid)
+	}
+	return -1, -1
+}
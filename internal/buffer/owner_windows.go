@@ -0,0 +1,10 @@
+//go:build windows
+
+package buffer
+
+import "os"
+
+// fileOwner is not supported on windows
+func fileOwner(info os.FileInfo) (int, int) {
+	return -1, -1
+}
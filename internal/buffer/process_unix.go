@@ -0,0 +1,13 @@
+//go:build !windows
+
+package buffer
+
+import "syscall"
+
+// processAlive reports whether a process with pid is running
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	return syscall.Kill(pid, 0) == nil
+}
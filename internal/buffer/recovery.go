@@ -0,0 +1,205 @@
+package buffer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const recoveryInterval = 10 * time.Second
+
+// RecoveryFile is a snapshot of an unsaved buffer written in the background
+// so the edits survive a crash
+type RecoveryFile struct {
+	Path       string    `json:"path"`
+	Encoding   string    `json:"encoding"`
+	LineEnding string    `json:"line_ending"`
+	Content    string    `json:"content"`
+	SavedAt    time.Time `json:"saved_at"`
+	PID        int       `json:"pid"`
+
+	file string // where the snapshot lives
+}
+
+// RecoveryDir is where snapshots are kept, ~/.cache/bitcode/recovery on Linux
+func RecoveryDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "bitcode", "recovery")
+}
+
+// recoveryName is the snapshot file of buf, named by process and buffer so
+// it doesn't change on rename and two sessions never share one
+func recoveryName(buf *Buffer) string {
+	return fmt.Sprintf("%d-%d.json", os.Getpid(), buf.id)
+}
+
+// ListRecovery returns snapshots left behind by sessions that are no longer running
+func ListRecovery(dir string) []RecoveryFile {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []RecoveryFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		full := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(full)
+		if err != nil {
+			continue
+		}
+		var rf RecoveryFile
+		if err := json.Unmarshal(data, &rf); err != nil {
+			continue
+		}
+		if rf.PID != os.Getpid() && processAlive(rf.PID) {
+			// another bitcode is still editing this file
+			continue
+		}
+		rf.file = full
+		files = append(files, rf)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// Remove deletes the snapshot
+func (rf RecoveryFile) Remove() error {
+	return os.Remove(rf.file)
+}
+
+// StartRecovery snapshots modified buffers to dir every few seconds
+func (bm *BufferManager) StartRecovery(dir string) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.Printf("Crash recovery disabled: %v", err)
+		return
+	}
+	bm.mu.Lock()
+	bm.recoveryDir = dir
+	bm.snapshots = map[*Buffer]int{}
+	stop := make(chan struct{})
+	bm.stopRecovery = stop
+	bm.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(recoveryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				bm.writeSnapshots()
+			}
+		}
+	}()
+}
+
+// writeSnapshots persists buffers changed since the last tick and drops the
+// snapshots of buffers that were saved in the meantime. The buffers are
+// picked under bm.mu, their text is copied and written without it.
+func (bm *BufferManager) writeSnapshots() {
+	var writes []*Buffer
+	var stale []string
+
+	bm.mu.Lock()
+	for _, buf := range bm.buffers {
+		if !buf.Modified() {
+			if _, ok := bm.snapshots[buf]; ok {
+				stale = append(stale, filepath.Join(bm.recoveryDir, recoveryName(buf)))
+				delete(bm.snapshots, buf)
+			}
+			continue
+		}
+		if v, ok := bm.snapshots[buf]; !ok || v != buf.Revision() {
+			writes = append(writes, buf)
+		}
+	}
+	dir := bm.recoveryDir
+	bm.mu.Unlock()
+
+	for _, name := range stale {
+		os.Remove(name)
+	}
+	for _, buf := range writes {
+		name := filepath.Join(dir, recoveryName(buf))
+		buf.mu.RLock()
+		rf := RecoveryFile{
+			Path:       absPath(buf.File),
+			Encoding:   buf.Encoding,
+			LineEnding: buf.LineEnding,
+			Content:    buf.text.String(),
+			SavedAt:    time.Now(),
+			PID:        os.Getpid(),
+		}
+		version := buf.revision
+		buf.mu.RUnlock()
+
+		err := writeFileAtomic(name, 0600, func(w io.Writer) error {
+			return json.NewEncoder(w).Encode(rf)
+		})
+		if err != nil {
+			log.Printf("Unable to write recovery file: %v", err)
+			continue
+		}
+
+		bm.mu.Lock()
+		switch {
+		case bm.snapshots == nil || bm.buffers[buf.File] != buf:
+			// closed or shut down while writing
+			os.Remove(name)
+		case absPath(buf.File) != rf.Path:
+			// renamed while writing, write it again with the new path
+			bm.snapshots[buf] = -1
+		default:
+			bm.snapshots[buf] = version
+		}
+		bm.mu.Unlock()
+	}
+}
+
+// clearSnapshots removes every snapshot this session wrote and stops
+// recording new ones
+func (bm *BufferManager) clearSnapshots() {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	for buf := range bm.snapshots {
+		os.Remove(filepath.Join(bm.recoveryDir, recoveryName(buf)))
+	}
+	bm.snapshots = nil
+}
+
+// Restore replaces the content with a recovered snapshot as one undoable
+// step, leaving the buffer modified
+func (b *Buffer) Restore(rf RecoveryFile) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.openStep()
+	b.deleteText(0, 0, b.lineLen(b.text.Lines()-1), b.text.Lines()-1)
+	b.insertText(0, 0, []rune(rf.Content), false)
+	b.CursorX, b.CursorY = 0, 0
+	b.closeStep()
+
+	if rf.LineEnding != "" && rf.LineEnding != b.LineEnding {
+		b.LineEnding = rf.LineEnding
+		b.formatChanged = true
+		b.revision++
+	}
+	if rf.Encoding != "" && rf.Encoding != b.Encoding {
+		b.Encoding = rf.Encoding
+		b.formatChanged = true
+		b.revision++
+	}
+}
@@ -0,0 +1,434 @@
+package buffer
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Leaves hold at most this many bytes; bigger text is split into several leaves
+const maxLeafBytes = 2048
+
+// rope is an AVL balanced tree of immutable string chunks. Every node caches
+// the rune and newline counts of its subtree so positions and lines can be
+// found in O(log n) without ever converting the whole text to runes.
+type rope struct {
+	root *ropeNode
+}
+
+type ropeNode struct {
+	left, right *ropeNode
+	text        string // leaves only
+
+	runes  int
+	lines  int // number of '\n' in the subtree
+	height int
+}
+
+func newRope(s string) *rope {
+	return &rope{root: buildLeaves(s)}
+}
+
+func (r *rope) Len() int   { return r.root.runeCount() }
+func (r *rope) Lines() int { return r.root.lineCount() + 1 }
+
+// LineStart returns the rune offset of the first rune of line y
+func (r *rope) LineStart(y int) int {
+	if y <= 0 || r.root == nil {
+		return 0
+	}
+	if y > r.root.lines {
+		return r.Len()
+	}
+	return r.root.newlineOffset(y) + 1
+}
+
+// LineBounds returns the rune offsets of line y, excluding its '\n'
+func (r *rope) LineBounds(y int) (int, int) {
+	y = max(0, y)
+	start := r.LineStart(y)
+	if y >= r.root.lineCount() {
+		return start, r.Len()
+	}
+	return start, r.root.newlineOffset(y + 1)
+}
+
+// Slice returns the text between two rune offsets
+func (r *rope) Slice(start, end int) string {
+	var sb strings.Builder
+	r.root.collect(start, end, &sb)
+	return sb.String()
+}
+
+func (r *rope) Insert(off int, s string) {
+	if s == "" {
+		return
+	}
+	off = max(0, min(off, r.Len()))
+	if r.root != nil && r.root.insertInLeaf(off, s) {
+		return
+	}
+	left, right := split(r.root, off)
+	r.root = join(join(left, buildLeaves(s)), right)
+}
+
+func (r *rope) Delete(start, end int) {
+	start, end = max(0, start), min(end, r.Len())
+	if start >= end {
+		return
+	}
+	if r.root.deleteInLeaf(start, end) {
+		return
+	}
+	left, rest := split(r.root, start)
+	_, right := split(rest, end-start)
+	r.root = join(left, right)
+}
+
+// WriteTo streams the rope leaves to w
+func (r *rope) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	var err error
+	r.root.walk(func(s string) bool {
+		var n int
+		n, err = io.WriteString(w, s)
+		total += int64(n)
+		return err == nil
+	})
+	return total, err
+}
+
+func (r *rope) String() string {
+	return r.Slice(0, r.Len())
+}
+
+// ---- nodes ----
+
+func newLeaf(s string) *ropeNode {
+	return &ropeNode{
+		text:   s,
+		runes:  utf8.RuneCountInString(s),
+		lines:  strings.Count(s, "\n"),
+		height: 1,
+	}
+}
+
+func newNode(left, right *ropeNode) *ropeNode {
+	n := &ropeNode{left: left, right: right}
+	n.update()
+	return n
+}
+
+func (n *ropeNode) isLeaf() bool { return n.left == nil && n.right == nil }
+
+func (n *ropeNode) runeCount() int {
+	if n == nil {
+		return 0
+	}
+	return n.runes
+}
+
+func (n *ropeNode) lineCount() int {
+	if n == nil {
+		return 0
+	}
+	return n.lines
+}
+
+func (n *ropeNode) depth() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+func (n *ropeNode) update() {
+	n.runes = n.left.runeCount() + n.right.runeCount()
+	n.lines = n.left.lineCount() + n.right.lineCount()
+	n.height = max(n.left.depth(), n.right.depth()) + 1
+}
+
+// buildLeaves cuts s into leaves on rune boundaries and builds a balanced tree
+func buildLeaves(s string) *ropeNode {
+	if s == "" {
+		return nil
+	}
+	var leaves []*ropeNode
+	for len(s) > maxLeafBytes {
+		cut := maxLeafBytes
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		leaves = append(leaves, newLeaf(s[:cut]))
+		s = s[cut:]
+	}
+	leaves = append(leaves, newLeaf(s))
+	return buildBalanced(leaves)
+}
+
+// ropeBuilder makes a rope from text written to it in pieces, so a file is
+// never held in memory as one big string next to its rope
+type ropeBuilder struct {
+	leaves  []*ropeNode
+	pending []byte
+}
+
+func (rb *ropeBuilder) Write(p []byte) (int, error) {
+	rb.pending = append(rb.pending, p...)
+	off := 0
+	for len(rb.pending)-off > maxLeafBytes {
+		cut := maxLeafBytes
+		for cut > 0 && !utf8.RuneStart(rb.pending[off+cut]) {
+			cut--
+		}
+		if cut == 0 {
+			// not UTF-8, any cut will do
+			cut = maxLeafBytes
+		}
+		rb.leaves = append(rb.leaves, newLeaf(string(rb.pending[off:off+cut])))
+		off += cut
+	}
+	rb.pending = rb.pending[:copy(rb.pending, rb.pending[off:])]
+	return len(p), nil
+}
+
+// flush turns what is left over into a last leaf
+func (rb *ropeBuilder) flush() {
+	if len(rb.pending) > 0 {
+		rb.leaves = append(rb.leaves, newLeaf(string(rb.pending)))
+		rb.pending = nil
+	}
+}
+
+// dropCR turns the CRLF line endings written so far into '\n'
+func (rb *ropeBuilder) dropCR() {
+	rb.flush()
+	for i, leaf := range rb.leaves {
+		text := strings.ReplaceAll(leaf.text, CRLF, LF)
+		// a CRLF split between two leaves
+		if strings.HasSuffix(text, "\r") && i+1 < len(rb.leaves) && strings.HasPrefix(rb.leaves[i+1].text, LF) {
+			text = text[:len(text)-1]
+		}
+		if text != leaf.text {
+			rb.leaves[i] = newLeaf(text)
+		}
+	}
+}
+
+// trimNewline drops a '\n' ending the text, reporting whether there was one
+func (rb *ropeBuilder) trimNewline() bool {
+	rb.flush()
+	for i := len(rb.leaves) - 1; i >= 0; i-- {
+		text := rb.leaves[i].text
+		if text == "" {
+			continue
+		}
+		if !strings.HasSuffix(text, LF) {
+			return false
+		}
+		rb.leaves[i] = newLeaf(text[:len(text)-1])
+		return true
+	}
+	return false
+}
+
+func (rb *ropeBuilder) rope() *rope {
+	rb.flush()
+	leaves := slices.DeleteFunc(rb.leaves, func(n *ropeNode) bool { return n.text == "" })
+	return &rope{root: buildBalanced(leaves)}
+}
+
+func buildBalanced(leaves []*ropeNode) *ropeNode {
+	switch len(leaves) {
+	case 0:
+		return nil
+	case 1:
+		return leaves[0]
+	}
+	mid := len(leaves) / 2
+	return newNode(buildBalanced(leaves[:mid]), buildBalanced(leaves[mid:]))
+}
+
+// byteIndex converts a rune offset inside a leaf to a byte offset
+func byteIndex(s string, runeOff int) int {
+	i := 0
+	for runeOff > 0 && i < len(s) {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		runeOff--
+	}
+	return i
+}
+
+// newlineOffset returns the rune offset of the k-th '\n' (1-based)
+func (n *ropeNode) newlineOffset(k int) int {
+	off := 0
+	for !n.isLeaf() {
+		if k <= n.left.lineCount() {
+			n = n.left
+		} else {
+			k -= n.left.lineCount()
+			off += n.left.runeCount()
+			n = n.right
+		}
+	}
+	for _, r := range n.text {
+		if r == '\n' {
+			k--
+			if k == 0 {
+				return off
+			}
+		}
+		off++
+	}
+	return off
+}
+
+func (n *ropeNode) collect(start, end int, sb *strings.Builder) {
+	if n == nil || start >= end || end <= 0 || start >= n.runes {
+		return
+	}
+	if n.isLeaf() {
+		from := byteIndex(n.text, max(0, start))
+		to := byteIndex(n.text, min(end, n.runes))
+		sb.WriteString(n.text[from:to])
+		return
+	}
+	leftRunes := n.left.runeCount()
+	n.left.collect(start, end, sb)
+	n.right.collect(start-leftRunes, end-leftRunes, sb)
+}
+
+func (n *ropeNode) walk(fn func(string) bool) bool {
+	if n == nil {
+		return true
+	}
+	if n.isLeaf() {
+		return fn(n.text)
+	}
+	return n.left.walk(fn) && n.right.walk(fn)
+}
+
+// insertInLeaf is the fast path used while typing: when the insertion fits in
+// the target leaf it is patched in place and only the counts on the path change
+func (n *ropeNode) insertInLeaf(off int, s string) bool {
+	if n.isLeaf() {
+		if len(n.text)+len(s) > maxLeafBytes {
+			return false
+		}
+		i := byteIndex(n.text, off)
+		*n = *newLeaf(n.text[:i] + s + n.text[i:])
+		return true
+	}
+	var ok bool
+	if off <= n.left.runeCount() {
+		ok = n.left.insertInLeaf(off, s)
+	} else {
+		ok = n.right.insertInLeaf(off-n.left.runeCount(), s)
+	}
+	if ok {
+		n.update()
+	}
+	return ok
+}
+
+// deleteInLeaf removes [start, end) in place when it lies inside a single leaf
+func (n *ropeNode) deleteInLeaf(start, end int) bool {
+	if n.isLeaf() {
+		if end-start >= n.runes {
+			return false
+		}
+		from, to := byteIndex(n.text, start), byteIndex(n.text, end)
+		*n = *newLeaf(n.text[:from] + n.text[to:])
+		return true
+	}
+	leftRunes := n.left.runeCount()
+	var ok bool
+	switch {
+	case end <= leftRunes:
+		ok = n.left.deleteInLeaf(start, end)
+	case start >= leftRunes:
+		ok = n.right.deleteInLeaf(start-leftRunes, end-leftRunes)
+	}
+	if ok {
+		n.update()
+	}
+	return ok
+}
+
+// split cuts the tree at a rune offset
+func split(n *ropeNode, off int) (*ropeNode, *ropeNode) {
+	if n == nil {
+		return nil, nil
+	}
+	if off <= 0 {
+		return nil, n
+	}
+	if off >= n.runes {
+		return n, nil
+	}
+	if n.isLeaf() {
+		i := byteIndex(n.text, off)
+		return newLeaf(n.text[:i]), newLeaf(n.text[i:])
+	}
+	leftRunes := n.left.runeCount()
+	if off <= leftRunes {
+		l, r := split(n.left, off)
+		return l, join(r, n.right)
+	}
+	l, r := split(n.right, off-leftRunes)
+	return join(n.left, l), r
+}
+
+// join concatenates two trees keeping the AVL height invariant
+func join(l, r *ropeNode) *ropeNode {
+	switch {
+	case l == nil:
+		return r
+	case r == nil:
+		return l
+	case l.isLeaf() && r.isLeaf() && len(l.text)+len(r.text) <= maxLeafBytes/2:
+		// merge tiny neighbours so repeated edits don't fragment the tree
+		return newLeaf(l.text + r.text)
+	case l.height > r.height+1:
+		return rebalance(newNode(l.left, join(l.right, r)))
+	case r.height > l.height+1:
+		return rebalance(newNode(join(l, r.left), r.right))
+	}
+	return newNode(l, r)
+}
+
+func rebalance(n *ropeNode) *ropeNode {
+	switch diff := n.left.depth() - n.right.depth(); {
+	case diff > 1:
+		if n.left.left.depth() < n.left.right.depth() {
+			n.left = rotateLeft(n.left)
+		}
+		return rotateRight(n)
+	case diff < -1:
+		if n.right.right.depth() < n.right.left.depth() {
+			n.right = rotateRight(n.right)
+		}
+		return rotateLeft(n)
+	}
+	return n
+}
+
+func rotateLeft(n *ropeNode) *ropeNode {
+	r := n.right
+	n.right = r.left
+	n.update()
+	r.left = n
+	r.update()
+	return r
+}
+
+func rotateRight(n *ropeNode) *ropeNode {
+	l := n.left
+	n.left = l.right
+	n.update()
+	l.right = n
+	l.update()
+	return l
+}
@@ -0,0 +1,202 @@
+package buffer
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// checkNode verifies the cached counts and the AVL balance of a subtree
+func checkNode(t *testing.T, n *ropeNode) {
+	t.Helper()
+	if n == nil {
+		return
+	}
+	if n.isLeaf() {
+		if n.runes != utf8.RuneCountInString(n.text) || n.lines != strings.Count(n.text, "\n") || n.height != 1 {
+			t.Fatalf("leaf %q has stale counts: runes %d lines %d height %d", n.text, n.runes, n.lines, n.height)
+		}
+		if len(n.text) > maxLeafBytes {
+			t.Fatalf("leaf of %d bytes is over maxLeafBytes", len(n.text))
+		}
+		return
+	}
+	checkNode(t, n.left)
+	checkNode(t, n.right)
+	if n.runes != n.left.runeCount()+n.right.runeCount() || n.lines != n.left.lineCount()+n.right.lineCount() {
+		t.Fatalf("node has stale counts: runes %d lines %d", n.runes, n.lines)
+	}
+	if n.height != max(n.left.depth(), n.right.depth())+1 {
+		t.Fatalf("node height %d is stale", n.height)
+	}
+	if diff := n.left.depth() - n.right.depth(); diff > 1 || diff < -1 {
+		t.Fatalf("node is out of balance by %d", diff)
+	}
+}
+
+// checkRope compares a rope with the text it should hold
+func checkRope(t *testing.T, r *rope, want string) {
+	t.Helper()
+	checkNode(t, r.root)
+	if got := r.String(); got != want {
+		t.Fatalf("text = %q, want %q", got, want)
+	}
+	if got, want := r.Len(), utf8.RuneCountInString(want); got != want {
+		t.Fatalf("Len() = %d, want %d", got, want)
+	}
+	lines := strings.Split(want, "\n")
+	if r.Lines() != len(lines) {
+		t.Fatalf("Lines() = %d, want %d", r.Lines(), len(lines))
+	}
+	off := 0
+	for y, line := range lines {
+		start, end := r.LineBounds(y)
+		n := utf8.RuneCountInString(line)
+		if start != off || end != off+n {
+			t.Fatalf("LineBounds(%d) = %d, %d, want %d, %d", y, start, end, off, off+n)
+		}
+		if got := r.Slice(start, end); got != line {
+			t.Fatalf("line %d = %q, want %q", y, got, line)
+		}
+		off += n + 1
+	}
+}
+
+func TestRopeInsertDelete(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		edit func(r *rope)
+		want string
+	}{
+		{"insert into empty", "", func(r *rope) { r.Insert(0, "abc") }, "abc"},
+		{"insert at start", "world", func(r *rope) { r.Insert(0, "hello ") }, "hello world"},
+		{"insert at end", "hello", func(r *rope) { r.Insert(5, "\nworld") }, "hello\nworld"},
+		{"insert past end clamps", "ab", func(r *rope) { r.Insert(10, "c") }, "abc"},
+		{"insert multibyte", "añb", func(r *rope) { r.Insert(2, "漢字") }, "añ漢字b"},
+		{"insert empty", "ab", func(r *rope) { r.Insert(1, "") }, "ab"},
+		{"delete middle", "abcdef", func(r *rope) { r.Delete(1, 4) }, "aef"},
+		{"delete newline joins lines", "ab\ncd", func(r *rope) { r.Delete(2, 3) }, "abcd"},
+		{"delete everything", "ab\ncd", func(r *rope) { r.Delete(0, 5) }, ""},
+		{"delete out of range clamps", "abc", func(r *rope) { r.Delete(-3, 2) }, "c"},
+		{"delete reversed range", "abc", func(r *rope) { r.Delete(2, 1) }, "abc"},
+		{"delete multibyte", "a漢字b", func(r *rope) { r.Delete(1, 3) }, "ab"},
+		{
+			"insert across leaves",
+			strings.Repeat("x", 3*maxLeafBytes),
+			func(r *rope) { r.Insert(maxLeafBytes, strings.Repeat("y\n", maxLeafBytes)) },
+			strings.Repeat("x", maxLeafBytes) + strings.Repeat("y\n", maxLeafBytes) + strings.Repeat("x", 2*maxLeafBytes),
+		},
+		{
+			"delete across leaves",
+			strings.Repeat("ab\n", 2*maxLeafBytes),
+			func(r *rope) { r.Delete(10, 4*maxLeafBytes) },
+			strings.Repeat("ab\n", 2*maxLeafBytes)[:10] + strings.Repeat("ab\n", 2*maxLeafBytes)[4*maxLeafBytes:],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRope(tt.text)
+			tt.edit(r)
+			checkRope(t, r, tt.want)
+		})
+	}
+}
+
+func TestRopeLineBounds(t *testing.T) {
+	tests := []struct {
+		text       string
+		y          int
+		start, end int
+	}{
+		{"", 0, 0, 0},
+		{"abc", 0, 0, 3},
+		{"abc\n", 1, 4, 4},
+		{"a\nbc\n\nd", 1, 2, 4},
+		{"a\nbc\n\nd", 2, 5, 5},
+		{"a\nbc\n\nd", 3, 6, 7},
+		{"ñ\n漢字", 1, 2, 4},
+		// out of range lines clamp to the ends
+		{"a\nb", -1, 0, 1},
+		{"a\nb", 5, 3, 3},
+	}
+	for _, tt := range tests {
+		start, end := newRope(tt.text).LineBounds(tt.y)
+		if start != tt.start || end != tt.end {
+			t.Errorf("LineBounds(%q, %d) = %d, %d, want %d, %d", tt.text, tt.y, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestRopeSplitJoin(t *testing.T) {
+	text := strings.Repeat("line ñ 漢\n", 1000)
+	root := newRope(text).root
+	for _, off := range []int{0, 1, 7, maxLeafBytes, root.runes / 2, root.runes - 1, root.runes} {
+		l, r := split(root, off)
+		checkNode(t, l)
+		checkNode(t, r)
+		if l.runeCount() != off {
+			t.Fatalf("split(%d) left has %d runes", off, l.runeCount())
+		}
+		joined := &rope{root: join(l, r)}
+		checkRope(t, joined, text)
+	}
+
+	// joining trees of very different heights keeps the result balanced
+	small := buildLeaves("tiny")
+	big := buildLeaves(strings.Repeat("b", 200*maxLeafBytes))
+	checkNode(t, join(small, big))
+	checkNode(t, join(big, small))
+}
+
+func TestRopeRebalance(t *testing.T) {
+	// always inserting at the front builds the tree from one side
+	r := newRope("")
+	want := ""
+	chunk := strings.Repeat("z", maxLeafBytes-1) + "\n"
+	for i := 0; i < 500; i++ {
+		r.Insert(0, chunk)
+		want = chunk + want
+	}
+	checkRope(t, r, want)
+
+	leaves := 0
+	r.root.walk(func(string) bool { leaves++; return true })
+	if limit := int(1.45*math.Log2(float64(leaves+2))) + 1; r.root.height > limit {
+		t.Fatalf("height %d over the AVL bound %d for %d leaves", r.root.height, limit, leaves)
+	}
+}
+
+func TestRopeRandomEdits(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []rune("ab\nñ漢 ")
+	randText := func(n int) string {
+		rs := make([]rune, n)
+		for i := range rs {
+			rs[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return string(rs)
+	}
+
+	want := []rune(randText(5000))
+	r := newRope(string(want))
+	for i := 0; i < 2000; i++ {
+		if rng.Intn(2) == 0 || len(want) == 0 {
+			off := rng.Intn(len(want) + 1)
+			s := randText(rng.Intn(3 * maxLeafBytes / 2))
+			r.Insert(off, s)
+			want = append(want[:off], append([]rune(s), want[off:]...)...)
+		} else {
+			start := rng.Intn(len(want))
+			end := start + rng.Intn(min(len(want)-start, maxLeafBytes)+1)
+			r.Delete(start, end)
+			want = append(want[:start], want[end:]...)
+		}
+		if i%100 == 0 {
+			checkRope(t, r, string(want))
+		}
+	}
+	checkRope(t, r, string(want))
+}
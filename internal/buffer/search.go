@@ -0,0 +1,253 @@
+package buffer
+
+import (
+	"errors"
+	"regexp"
+	"unicode/utf8"
+)
+
+// Stop collecting after this many matches so huge files stay responsive
+const maxMatches = 100000
+
+type SearchOptions struct {
+	CaseSensitive bool
+	WholeWord     bool
+	Regexp        bool
+}
+
+// Match is a hit on a single line, Start/End are rune columns
+type Match struct {
+	Line  int
+	Start int
+	End   int
+}
+
+// CompileSearch turns a query and options into a regexp
+func CompileSearch(query string, opts SearchOptions) (*regexp.Regexp, error) {
+	pattern := query
+	if !opts.Regexp {
+		pattern = regexp.QuoteMeta(query)
+	}
+	if opts.WholeWord {
+		pattern = `\b(?:` + pattern + `)\b`
+	}
+	if !opts.CaseSensitive {
+		pattern = `(?i)` + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// FindAll returns every match of query in the buffer, in document order
+func (b *Buffer) FindAll(query string, opts SearchOptions) ([]Match, error) {
+	if query == "" {
+		return nil, nil
+	}
+	re, err := CompileSearch(query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var matches []Match
+	for y := 0; y < b.text.Lines() && len(matches) < maxMatches; y++ {
+		matches = append(matches, MatchLine(re, b.line(y), y)...)
+	}
+	return matches, nil
+}
+
+// MatchLine finds the matches of re on line y, skipping empty matches
+func MatchLine(re *regexp.Regexp, line string, y int) []Match {
+	var matches []Match
+	for _, loc := range re.FindAllStringIndex(line, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		start := utf8.RuneCountInString(line[:loc[0]])
+		end := start + utf8.RuneCountInString(line[loc[0]:loc[1]])
+		matches = append(matches, Match{Line: y, Start: start, End: end})
+	}
+	return matches
+}
+
+// Range is an ordered span of text, End is exclusive
+type Range struct {
+	StartX, StartY int
+	EndX, EndY     int
+}
+
+// Contains reports whether m lies entirely inside the range
+func (r Range) Contains(m Match) bool {
+	afterStart := m.Line > r.StartY || (m.Line == r.StartY && m.Start >= r.StartX)
+	beforeEnd := m.Line < r.EndY || (m.Line == r.EndY && m.End <= r.EndX)
+	return afterStart && beforeEnd
+}
+
+// Replace substitutes the matches of query accepted by accept (all when nil)
+// with replacement, as a single undo step. In regexp mode the replacement
+// may refer to capture groups as $1 or ${name}. It returns the number of
+// replacements made.
+func (b *Buffer) Replace(query, replacement string, opts SearchOptions, accept func(Match) bool) (int, error) {
+	if query == "" {
+		return 0, nil
+	}
+	re, err := CompileSearch(query, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.openStep()
+	defer b.closeStep()
+
+	count := 0
+	// bottom up and right to left so earlier positions stay valid
+	for y := b.text.Lines() - 1; y >= 0; y-- {
+		line := b.line(y)
+		locs := re.FindAllStringSubmatchIndex(line, -1)
+		for i := len(locs) - 1; i >= 0; i-- {
+			loc := locs[i]
+			if loc[0] == loc[1] {
+				continue
+			}
+			start := utf8.RuneCountInString(line[:loc[0]])
+			end := start + utf8.RuneCountInString(line[loc[0]:loc[1]])
+			m := Match{Line: y, Start: start, End: end}
+			if accept != nil && !accept(m) {
+				continue
+			}
+
+			text := replacement
+			if opts.Regexp {
+				text = string(re.ExpandString(nil, replacement, line, loc))
+			}
+			b.deleteText(start, y, end, y)
+			b.insertText(start, y, []rune(text), false)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ReplacementFor returns what Replace would put in place of m, for previews
+func (b *Buffer) ReplacementFor(m Match, query, replacement string, opts SearchOptions) (string, error) {
+	if !opts.Regexp {
+		return replacement, nil
+	}
+	re, err := CompileSearch(query, opts)
+	if err != nil {
+		return "", err
+	}
+	return ExpandMatch(re, b.Line(m.Line), m, replacement), nil
+}
+
+// ExpandMatch expands $1 style references in replacement for the match of re
+// starting at m.Start on line
+func ExpandMatch(re *regexp.Regexp, line string, m Match, replacement string) string {
+	for _, loc := range re.FindAllStringSubmatchIndex(line, -1) {
+		if utf8.RuneCountInString(line[:loc[0]]) == m.Start {
+			return string(re.ExpandString(nil, replacement, line, loc))
+		}
+	}
+	return replacement
+}
+
+// FileEdit picks the matches to replace in one file
+type FileEdit struct {
+	Path    string
+	Matches []Match
+}
+
+// ReplaceResult is the outcome of replacing in one file
+type ReplaceResult struct {
+	Path  string
+	Count int
+	// picked matches that no longer line up with the text
+	Missed int
+	Err    error
+
+	// the edited buffer and its revision after the replace, for UndoReplace
+	Buf      *Buffer
+	Revision int
+	// Loaded is set when the file wasn't open and was saved by the replace
+	Loaded bool
+}
+
+// ReplaceInFiles replaces the picked matches of query in several files.
+// Files that are open are edited in memory and left for the user to save;
+// other files are opened, edited and saved atomically. Either way each file
+// gets a single undo step, and matches that moved since the search are left
+// alone and counted as missed. Files opened for the replace and changed by
+// it stay loaded so UndoReplace can revert them; close them when done.
+func (bm *BufferManager) ReplaceInFiles(edits []FileEdit, query, replacement string, opts SearchOptions) []ReplaceResult {
+	results := make([]ReplaceResult, 0, len(edits))
+	for _, edit := range edits {
+		result := ReplaceResult{Path: edit.Path}
+
+		path := CanonicalPath(edit.Path)
+		bm.mu.Lock()
+		buf, wasOpen := bm.buffers[path]
+		if !wasOpen {
+			buf, result.Err = bm.load(path)
+		}
+		bm.mu.Unlock()
+		if result.Err != nil {
+			results = append(results, result)
+			continue
+		}
+		result.Buf, result.Loaded = buf, !wasOpen
+
+		picked := make(map[Match]bool, len(edit.Matches))
+		for _, m := range edit.Matches {
+			picked[m] = true
+		}
+		result.Count, result.Err = buf.Replace(query, replacement, opts, func(m Match) bool {
+			return picked[m]
+		})
+		if result.Err == nil {
+			result.Missed = max(0, len(picked)-result.Count)
+		}
+		if result.Err == nil && !wasOpen && result.Count > 0 {
+			result.Err = buf.Save()
+		}
+		if !wasOpen && (result.Err != nil || result.Count == 0) {
+			// nothing to undo, don't keep the file around
+			bm.mu.Lock()
+			bm.release(buf)
+			bm.mu.Unlock()
+			result.Buf, result.Loaded = nil, false
+			results = append(results, result)
+			continue
+		}
+		result.Revision = buf.Revision()
+		results = append(results, result)
+	}
+	return results
+}
+
+// UndoReplace reverts what ReplaceInFiles did, as long as the files weren't
+// edited since. Files it saved are saved again, open ones are left for the
+// user to save.
+func (bm *BufferManager) UndoReplace(results []ReplaceResult) []ReplaceResult {
+	undone := make([]ReplaceResult, 0, len(results))
+	for _, r := range results {
+		if r.Err != nil || r.Count == 0 {
+			continue
+		}
+		result := ReplaceResult{Path: r.Path, Count: r.Count, Buf: r.Buf, Loaded: r.Loaded}
+		switch {
+		case r.Buf.Revision() != r.Revision:
+			result.Err = errors.New("changed since the replace")
+		default:
+			r.Buf.Undo()
+			if r.Loaded {
+				result.Err = r.Buf.Save()
+			}
+		}
+		result.Revision = r.Buf.Revision()
+		undone = append(undone, result)
+	}
+	return undone
+}
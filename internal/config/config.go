@@ -0,0 +1,234 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/uditrawat03/bitcode/internal/keymap"
+	"github.com/uditrawat03/bitcode/internal/theme"
+)
+
+// Config holds the user's settings. The user file is read first, then the
+// project's .bitcode.toml so a project can override it:
+//
+//	theme = "light"
+//
+//	[editor]
+//	tab_size = 2
+//	wrap = "prose"   # or "on", "off"
+//
+//	[layout]
+//	sidebar_width = 30
+//
+//	[terminal]
+//	mouse = true
+//	paste = true
+//
+//	[keys]
+//	"ctrl+shift+p" = "palette.open"
+//
+//	[keys.editor]
+//	"ctrl+k ctrl+c" = "editor.copy"
+//	"ctrl+e" = ""   # unbind
+type Config struct {
+	Theme    string
+	Editor   EditorConfig
+	Layout   LayoutConfig
+	Terminal TerminalConfig
+
+	// Keys are bindings added to the default keymap, in file order
+	Keys []KeyBinding
+}
+
+type EditorConfig struct {
+	TabSize int
+	// Wrap is "on", "off" or "prose" (Markdown and text files)
+	Wrap string
+}
+
+type LayoutConfig struct {
+	// SidebarWidth is 0 unless set, leaving it to fit the screen
+	SidebarWidth int
+}
+
+type TerminalConfig struct {
+	Mouse bool
+	Paste bool
+}
+
+// KeyBinding is one line of a [keys] table. Path and Line point at it for
+// errors found when the command is looked up.
+type KeyBinding struct {
+	Context keymap.Context
+	Keys    string
+	Command string
+	Path    string
+	Line    int
+}
+
+// Default returns the settings used when there is no config file
+func Default() *Config {
+	return &Config{
+		Theme:    "dark",
+		Editor:   EditorConfig{TabSize: 4, Wrap: "prose"},
+		Terminal: TerminalConfig{Mouse: true, Paste: true},
+	}
+}
+
+// UserPath is the per-user config file, ~/.config/bitcode/config.toml on Linux
+func UserPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "bitcode", "config.toml")
+}
+
+// ProjectPath is the config file inside a project
+func ProjectPath(root string) string {
+	return filepath.Join(root, ".bitcode.toml")
+}
+
+// Load reads the given files on top of the defaults; missing files are
+// skipped. Invalid settings are reported and left at their previous value,
+// so the returned config is always usable.
+func Load(paths ...string) (*Config, error) {
+	cfg := Default()
+	var errs []error
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		entries, err := parseTOML(string(data))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s:%w", path, err))
+			continue
+		}
+		for _, e := range entries {
+			if err := cfg.set(path, e); err != nil {
+				errs = append(errs, fmt.Errorf("%s:%d: %s: %w", path, e.line, strings.Join(e.key, "."), err))
+			}
+		}
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// set applies one setting after checking it
+func (cfg *Config) set(path string, e entry) error {
+	if e.key[0] == "keys" {
+		return cfg.setKey(path, e)
+	}
+
+	switch strings.Join(e.key, ".") {
+	case "theme":
+		name, err := asString(e.value)
+		if err != nil {
+			return err
+		}
+		if _, err := theme.Find(name); err != nil {
+			return fmt.Errorf("%w, have %s", err, strings.Join(theme.Names(), ", "))
+		}
+		cfg.Theme = name
+	case "editor.tab_size":
+		n, err := asInt(e.value, 1, 16)
+		if err != nil {
+			return err
+		}
+		cfg.Editor.TabSize = n
+	case "editor.wrap":
+		mode, err := asString(e.value)
+		if err != nil {
+			return err
+		}
+		if mode != "on" && mode != "off" && mode != "prose" {
+			return errors.New(`want "on", "off" or "prose"`)
+		}
+		cfg.Editor.Wrap = mode
+	case "layout.sidebar_width":
+		n, err := asInt(e.value, 10, 200)
+		if err != nil {
+			return err
+		}
+		cfg.Layout.SidebarWidth = n
+	case "terminal.mouse":
+		return asBool(e.value, &cfg.Terminal.Mouse)
+	case "terminal.paste":
+		return asBool(e.value, &cfg.Terminal.Paste)
+	default:
+		return errors.New("unknown setting")
+	}
+	return nil
+}
+
+// setKey reads a binding from [keys] (global) or [keys.<context>]
+func (cfg *Config) setKey(path string, e entry) error {
+	ctx := keymap.Global
+	switch len(e.key) {
+	case 2:
+	case 3:
+		c, err := keymap.ParseContext(e.key[1])
+		if err != nil {
+			return err
+		}
+		ctx = c
+	default:
+		return errors.New(`want "<keys>" = "<command>" inside [keys] or [keys.<context>]`)
+	}
+	keys := e.key[len(e.key)-1]
+	if _, err := keymap.ParseSequence(keys); err != nil {
+		return err
+	}
+	cmd, err := asString(e.value)
+	if err != nil {
+		return err
+	}
+	cfg.Keys = append(cfg.Keys, KeyBinding{Context: ctx, Keys: keys, Command: cmd, Path: path, Line: e.line})
+	return nil
+}
+
+func asString(v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("want a string, got %s", describe(v))
+	}
+	return s, nil
+}
+
+func asInt(v any, lo, hi int) (int, error) {
+	n, ok := v.(int64)
+	if !ok {
+		return 0, fmt.Errorf("want a whole number, got %s", describe(v))
+	}
+	if n < int64(lo) || n > int64(hi) {
+		return 0, fmt.Errorf("%d is out of range %d-%d", n, lo, hi)
+	}
+	return int(n), nil
+}
+
+func asBool(v any, dst *bool) error {
+	b, ok := v.(bool)
+	if !ok {
+		return fmt.Errorf("want true or false, got %s", describe(v))
+	}
+	*dst = b
+	return nil
+}
+
+func describe(v any) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []any:
+		return "an array"
+	default:
+		return fmt.Sprint(v)
+	}
+}
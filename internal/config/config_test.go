@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/uditrawat03/bitcode/internal/keymap"
+)
+
+// writeConfig writes data to a config file in a fresh directory
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	// keep user themes out of the messages
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `theme = "light"
+
+[editor]
+tab_size = 2
+wrap = "off"
+
+[layout]
+sidebar_width = 30
+
+[terminal]
+mouse = false
+
+[keys]
+"ctrl+shift+p" = "palette.open"
+
+[keys.editor]
+"ctrl+e" = ""
+`)
+	cfg, err := Load(path, filepath.Join(filepath.Dir(path), "missing.toml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := Default()
+	want.Theme = "light"
+	want.Editor = EditorConfig{TabSize: 2, Wrap: "off"}
+	want.Layout.SidebarWidth = 30
+	want.Terminal.Mouse = false
+	want.Keys = []KeyBinding{
+		{Context: keymap.Global, Keys: "ctrl+shift+p", Command: "palette.open", Path: path, Line: 14},
+		{Context: keymap.Editor, Keys: "ctrl+e", Command: "", Path: path, Line: 17},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Fatalf("Load = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`theme = "nope"`, `1: theme: unknown theme "nope", have dark, light, solarized-dark`},
+		{"theme = 1", "1: theme: want a string, got 1"},
+		{"editor.tab_size = 0", "1: editor.tab_size: 0 is out of range 1-16"},
+		{"editor.tab_size = 17", "1: editor.tab_size: 17 is out of range 1-16"},
+		{"editor.tab_size = 2.5", "1: editor.tab_size: want a whole number, got 2.5"},
+		{`editor.tab_size = "4"`, `1: editor.tab_size: want a whole number, got "4"`},
+		{`editor.wrap = "soft"`, `1: editor.wrap: want "on", "off" or "prose"`},
+		{"layout.sidebar_width = 5", "1: layout.sidebar_width: 5 is out of range 10-200"},
+		{"terminal.mouse = [true]", "1: terminal.mouse: want true or false, got an array"},
+		{"editor.font = 1", "1: editor.font: unknown setting"},
+		{`keys.nowhere."ctrl+a" = "x"`, `1: keys.nowhere.ctrl+a: unknown key context "nowhere"`},
+		{`keys = "x"`, `1: keys: want "<keys>" = "<command>" inside [keys] or [keys.<context>]`},
+		{`keys." " = "x"`, "1: keys. : empty key sequence"},
+		{`keys."ctrl+a" = true`, "1: keys.ctrl+a: want a string, got true"},
+		{"editor.tab_size = 0x10", `1: invalid value "0x10" (strings need quotes)`},
+		{"\n\neditor.tab_size = 010", `3: invalid number "010" (leading zeros are not allowed)`},
+	}
+	for _, tt := range tests {
+		path := writeConfig(t, tt.data)
+		cfg, err := Load(path)
+		if err == nil {
+			t.Errorf("Load(%q) succeeded, want %q", tt.data, tt.want)
+			continue
+		}
+		if want := path + ":" + tt.want; !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("Load(%q) error = %q, want %q", tt.data, err, want)
+		}
+		// invalid settings keep the defaults
+		if !reflect.DeepEqual(cfg, Default()) {
+			t.Errorf("Load(%q) = %+v, want the defaults", tt.data, cfg)
+		}
+	}
+}
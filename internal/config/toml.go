@@ -0,0 +1,270 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// entry is one "key = value" line; key holds the table path and the key
+type entry struct {
+	key   []string
+	value any
+	line  int
+}
+
+// parseTOML reads the subset of TOML the config needs: tables, dotted and
+// quoted keys, strings, integers, floats, booleans and single line arrays
+func parseTOML(data string) ([]entry, error) {
+	var entries []entry
+	var table []string
+	seen := map[string]int{}
+
+	for i, text := range strings.Split(data, "\n") {
+		p := &parser{s: strings.TrimSuffix(text, "\r"), line: i + 1}
+		p.skipSpace()
+		if p.done() {
+			continue
+		}
+
+		if p.peek() == '[' {
+			p.pos++
+			if p.peek() == '[' {
+				return nil, p.errorf("arrays of tables are not supported")
+			}
+			key, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume(']') {
+				return nil, p.errorf("expected ] after table name")
+			}
+			if err := p.end(); err != nil {
+				return nil, err
+			}
+			table = key
+			continue
+		}
+
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume('=') {
+			return nil, p.errorf("expected = after key %q", strings.Join(key, "."))
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.end(); err != nil {
+			return nil, err
+		}
+
+		full := append(append([]string{}, table...), key...)
+		name := strings.Join(full, ".")
+		if prev, ok := seen[name]; ok {
+			return nil, p.errorf("%s is already set on line %d", name, prev)
+		}
+		seen[name] = p.line
+		entries = append(entries, entry{key: full, value: value, line: p.line})
+	}
+	return entries, nil
+}
+
+// parser scans a single line
+type parser struct {
+	s    string
+	pos  int
+	line int
+}
+
+type lineError struct {
+	line int
+	msg  string
+}
+
+func (e *lineError) Error() string { return fmt.Sprintf("%d: %s", e.line, e.msg) }
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &lineError{line: p.line, msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) done() bool { return p.pos >= len(p.s) || p.s[p.pos] == '#' }
+
+func (p *parser) peek() byte {
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// consume skips c and the space around it
+func (p *parser) consume(c byte) bool {
+	p.skipSpace()
+	if p.peek() != c {
+		return false
+	}
+	p.pos++
+	p.skipSpace()
+	return true
+}
+
+// end checks nothing but a comment follows
+func (p *parser) end() error {
+	p.skipSpace()
+	if !p.done() {
+		return p.errorf("unexpected %q", p.s[p.pos:])
+	}
+	return nil
+}
+
+// key reads a dotted key such as editor.tab_size or keys."ctrl+p"
+func (p *parser) key() ([]string, error) {
+	var parts []string
+	for {
+		p.skipSpace()
+		var part string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			s, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && isBare(p.s[p.pos]) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected a key")
+			}
+			part = p.s[start:p.pos]
+		}
+		parts = append(parts, part)
+		p.skipSpace()
+		if p.peek() != '.' {
+			return parts, nil
+		}
+		p.pos++
+	}
+}
+
+func isBare(c byte) bool {
+	return c == '_' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *parser) value() (any, error) {
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '[':
+		p.pos++
+		var list []any
+		for !p.consume(']') {
+			if p.pos >= len(p.s) {
+				return nil, p.errorf("arrays must be closed on the same line")
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			if !p.consume(',') && p.peek() != ']' {
+				if p.pos >= len(p.s) {
+					return nil, p.errorf("arrays must be closed on the same line")
+				}
+				return nil, p.errorf("expected , or ] in array")
+			}
+		}
+		return list, nil
+	case c == '{':
+		return nil, p.errorf("inline tables are not supported")
+	}
+
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(" \t,]#", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	word := p.s[start:p.pos]
+	switch word {
+	case "":
+		return nil, p.errorf("expected a value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 10, 64); err == nil {
+		// 010 reads as octal in some languages, TOML rejects it
+		if digits := strings.TrimLeft(word, "+-"); len(digits) > 1 && digits[0] == '0' {
+			return nil, p.errorf("invalid number %q (leading zeros are not allowed)", word)
+		}
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(strings.ReplaceAll(word, "_", ""), 64); err == nil {
+		return f, nil
+	}
+	return nil, p.errorf("invalid value %q (strings need quotes)", word)
+}
+
+// str reads a basic "..." or literal '...' string
+func (p *parser) str() (string, error) {
+	quote := p.s[p.pos]
+	if strings.HasPrefix(p.s[p.pos:], strings.Repeat(string(quote), 3)) {
+		return "", p.errorf("multi-line strings are not supported")
+	}
+	p.pos++
+	var sb strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return sb.String(), nil
+		case c == '\\' && quote == '"':
+			if p.pos >= len(p.s) {
+				return "", p.errorf("unterminated string")
+			}
+			esc := p.s[p.pos]
+			p.pos++
+			switch esc {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case '"', '\\':
+				sb.WriteByte(esc)
+			case 'u', 'U':
+				n := 4
+				if esc == 'U' {
+					n = 8
+				}
+				if p.pos+n > len(p.s) {
+					return "", p.errorf("invalid escape \\%c", esc)
+				}
+				r, err := strconv.ParseUint(p.s[p.pos:p.pos+n], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(r)) {
+					return "", p.errorf("invalid escape \\%c%s", esc, p.s[p.pos:p.pos+n])
+				}
+				sb.WriteRune(rune(r))
+				p.pos += n
+			default:
+				return "", p.errorf("invalid escape \\%c", esc)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
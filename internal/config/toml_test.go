@@ -0,0 +1,87 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []entry
+	}{
+		{"empty", "", nil},
+		{"comments and blank lines", "# comment\n\n  # indented\n", nil},
+		{"string", `theme = "light"`, []entry{{[]string{"theme"}, "light", 1}}},
+		{"literal string", `theme = 'C:\dark'`, []entry{{[]string{"theme"}, `C:\dark`, 1}}},
+		{"escapes", `s = "a\tb\"c\u00e9"`, []entry{{[]string{"s"}, "a\tb\"cé", 1}}},
+		{"integer", "n = 42", []entry{{[]string{"n"}, int64(42), 1}}},
+		{"signed integer", "n = -7", []entry{{[]string{"n"}, int64(-7), 1}}},
+		{"underscores", "n = 1_000", []entry{{[]string{"n"}, int64(1000), 1}}},
+		{"zero", "n = 0", []entry{{[]string{"n"}, int64(0), 1}}},
+		{"float", "f = 1.5", []entry{{[]string{"f"}, 1.5, 1}}},
+		{"booleans", "a = true\nb = false", []entry{{[]string{"a"}, true, 1}, {[]string{"b"}, false, 2}}},
+		{"array", `a = [1, "x", true]`, []entry{{[]string{"a"}, []any{int64(1), "x", true}, 1}}},
+		{"trailing comment", "n = 1 # one", []entry{{[]string{"n"}, int64(1), 1}}},
+		{"crlf", "n = 1\r\nm = 2\r\n", []entry{{[]string{"n"}, int64(1), 1}, {[]string{"m"}, int64(2), 2}}},
+		{
+			"tables and dotted keys",
+			"[editor]\ntab_size = 2\n[keys.editor]\n\"ctrl+k ctrl+c\" = \"editor.copy\"\nlayout.sidebar_width = 30",
+			[]entry{
+				{[]string{"editor", "tab_size"}, int64(2), 2},
+				{[]string{"keys", "editor", "ctrl+k ctrl+c"}, "editor.copy", 4},
+				{[]string{"keys", "editor", "layout", "sidebar_width"}, int64(30), 5},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOML(tt.data)
+			if err != nil {
+				t.Fatalf("parseTOML(%q): %v", tt.data, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseTOML(%q) = %#v, want %#v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"n = 0x10", `1: invalid value "0x10" (strings need quotes)`},
+		{"n = 0o10", `1: invalid value "0o10" (strings need quotes)`},
+		{"n = 010", `1: invalid number "010" (leading zeros are not allowed)`},
+		{"n = -01", `1: invalid number "-01" (leading zeros are not allowed)`},
+		{"theme = light", `1: invalid value "light" (strings need quotes)`},
+		{"n =", "1: expected a value"},
+		{"n 1", `1: expected = after key "n"`},
+		{"= 1", "1: expected a key"},
+		{"n = 1 2", `1: unexpected "2"`},
+		{"[editor", "1: expected ] after table name"},
+		{"[[keys]]", "1: arrays of tables are not supported"},
+		{"t = {a = 1}", "1: inline tables are not supported"},
+		{`s = """x"""`, "1: multi-line strings are not supported"},
+		{`s = "abc`, "1: unterminated string"},
+		{`s = "\q"`, `1: invalid escape \q`},
+		{`s = "\u12"`, `1: invalid escape \u`},
+		{"a = [1, 2", "1: arrays must be closed on the same line"},
+		{"a = [1 2]", "1: expected , or ] in array"},
+		{"# ok\nn = 1\nn = 2", "3: n is already set on line 2"},
+		{"[editor]\ntab_size = 1\n[editor]\ntab_size = 2", "4: editor.tab_size is already set on line 2"},
+	}
+	for _, tt := range tests {
+		_, err := parseTOML(tt.data)
+		if err == nil {
+			t.Errorf("parseTOML(%q) succeeded, want %q", tt.data, tt.want)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("parseTOML(%q) error = %q, want %q", tt.data, err, tt.want)
+		}
+	}
+}
@@ -5,14 +5,26 @@ import (
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/uditrawat03/bitcode/internal/buffer"
+	"github.com/uditrawat03/bitcode/internal/syntax"
+	"github.com/uditrawat03/bitcode/internal/theme"
 )
 
+// gutterWidth is the room taken by line numbers left of the text
+const gutterWidth = 4
+
 type Editor struct {
 	x, y, width, height int
 	scrollY             int
+	scrollX             int
 	focused             bool
 	buffer              *buffer.Buffer
 
+	// soft wrap, see editor_wrap.go; scrollSub is the first shown row of
+	// line scrollY
+	wrap      bool
+	wrapMode  WrapMode
+	scrollSub int
+
 	clipboard []rune
 
 	selecting bool
@@ -21,20 +33,100 @@ type Editor struct {
 	selEndX   int
 	selEndY   int
 
-	focusCb func()
+	find findBar
+
+	// own cursor when not working on the buffer, see editor_cursor.go
+	cursor viewCursor
+
+	tabSize int
+
+	// nil when the language isn't known
+	highlight *syntax.Highlighter
+	// a CatchUp is posted and not run yet
+	lexPosted bool
+
+	focusCb  func()
+	statusCb func(msg string)
 }
 
 func (ed *Editor) SetFocusCallback(cb func()) {
 	ed.focusCb = cb
 }
 
+// SetStatusCallback sets where the editor reports messages such as save errors
+func (ed *Editor) SetStatusCallback(cb func(msg string)) {
+	ed.statusCb = cb
+}
+
+func (ed *Editor) status(msg string) {
+	if ed.statusCb != nil {
+		ed.statusCb(msg)
+	}
+}
+
 func CreateEditor(x, y, width, height int) *Editor {
-	return &Editor{x: x, y: y, width: width, height: height}
+	return &Editor{x: x, y: y, width: width, height: height, tabSize: 4}
+}
+
+// SetBounds moves and resizes the editor
+func (ed *Editor) SetBounds(x, y, width, height int) {
+	defer ed.useCursor()()
+	ed.x, ed.y, ed.width, ed.height = x, y, width, height
+	ed.ensureCursorVisible()
+}
+
+// SetTabSize sets how many spaces Tab inserts
+func (ed *Editor) SetTabSize(n int) {
+	ed.tabSize = max(1, n)
 }
 
 func (ed *Editor) SetBuffer(buf *buffer.Buffer) {
 	ed.buffer = buf
+	if buf != nil {
+		// start where the buffer was last left
+		ed.cursor = viewCursor{x: buf.CursorX, y: buf.CursorY, revision: buf.Revision(), held: ed.cursor.held}
+	}
 	ed.scrollY = 0
+	ed.scrollX = 0
+	ed.scrollSub = 0
+	ed.updateWrap()
+	ed.highlight = nil
+	if buf == nil {
+		ed.find.open = false
+	} else {
+		ed.highlight = syntax.NewHighlighter(syntax.ForFile(buf.File))
+	}
+	ed.refreshFind()
+}
+
+// GotoPosition moves the cursor to a 0-based line and column and scrolls it
+// into the middle of the view
+func (ed *Editor) GotoPosition(line, col int) {
+	defer ed.useCursor()()
+	if ed.buffer == nil {
+		return
+	}
+	ed.selecting = false
+	ed.buffer.CursorY = max(0, min(line, ed.buffer.LineCount()-1))
+	ed.buffer.CursorX = max(0, min(col, ed.buffer.LineLen(ed.buffer.CursorY)))
+	if ed.buffer.CursorY < ed.scrollY || ed.buffer.CursorY >= ed.scrollY+ed.textHeight() {
+		ed.scrollY = max(0, ed.buffer.CursorY-ed.textHeight()/2)
+		ed.scrollSub = 0
+	}
+	ed.ensureCursorVisible()
+}
+
+// textHeight is the number of rows available for buffer lines
+func (ed *Editor) textHeight() int {
+	if ed.find.open {
+		return max(0, ed.height-1)
+	}
+	return ed.height
+}
+
+// textWidth is the number of columns available for buffer text
+func (ed *Editor) textWidth() int {
+	return max(0, ed.width-gutterWidth)
 }
 
 // Focusable methods
@@ -44,18 +136,21 @@ func (ed *Editor) IsFocused() bool { return ed.focused }
 
 // Draw editor content
 func (ed *Editor) Draw(screen tcell.Screen) {
-	bg := tcell.ColorBlack
-
-	style := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(bg)
+	defer ed.useCursor()()
+	th := theme.Current()
+	style := th.Style(theme.EditorText)
 
 	// Line highlight style (full width)
-	highlightStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.NewRGBColor(50, 50, 80))
+	highlightStyle := th.Style(theme.EditorCurrentLine)
+	selectionStyle := th.Style(theme.EditorSelection)
+	lineNumberStyle := th.Style(theme.EditorLineNumber)
+
+	rows := ed.visibleRows()
 
 	// background + line highlighting
 	for row := 0; row < ed.height; row++ {
-		idx := row + ed.scrollY
 		currentLineStyle := style
-		if ed.buffer != nil && idx == ed.buffer.CursorY {
+		if row < len(rows) && rows[row].line == ed.buffer.CursorY {
 			currentLineStyle = highlightStyle
 		}
 
@@ -68,14 +163,34 @@ func (ed *Editor) Draw(screen tcell.Screen) {
 		return
 	}
 
-	// draw buffer lines with line numbers
-	for row := 0; row < ed.height; row++ {
-		idx := row + ed.scrollY
-		if idx >= len(ed.buffer.Content) {
-			break
+	ed.refreshFind()
+	if ed.highlight != nil && len(rows) > 0 {
+		if last := rows[len(rows)-1].line; !ed.highlight.Prepare(ed.buffer, last) {
+			ed.postCatchUp(screen, last)
+		}
+	}
+	matchStyle := th.Style(theme.EditorMatch)
+	currentMatchStyle := th.Style(theme.EditorCurrentMatch)
+	syntaxStyles := th.Syntax()
+	textWidth := ed.textWidth()
+
+	// draw buffer lines with line numbers, once per line when wrapped
+	var runes []rune
+	var cells []cell
+	var matches []buffer.Match
+	var firstMatch int
+	var lineTokens []syntax.Token
+	for row, seg := range rows {
+		idx := seg.line
+		if row == 0 || rows[row-1].line != idx {
+			runes = []rune(ed.buffer.Line(idx))
+			cells = layoutLine(runes, ed.tabSize)
+			matches, firstMatch = ed.lineMatches(idx)
+			lineTokens = nil
+			if ed.highlight != nil {
+				lineTokens = ed.highlight.Tokens(ed.buffer, idx)
+			}
 		}
-		line := string(ed.buffer.Content[idx])
-		lnStr := fmt.Sprintf("%3d ", idx+1)
 
 		// Determine style for this line
 		currentLineStyle := style
@@ -83,35 +198,112 @@ func (ed *Editor) Draw(screen tcell.Screen) {
 			currentLineStyle = highlightStyle
 		}
 
-		if idx == ed.buffer.CursorY || ed.isLineSelected(idx) {
-			currentLineStyle = highlightStyle // full-width highlight
+		if ed.isLineSelected(idx) {
+			currentLineStyle = selectionStyle // full-width highlight
 		}
 
 		// Draw line numbers
-		for i, r := range lnStr {
-			if i >= 4 || i >= ed.width {
-				break
+		numberStyle := lineNumberStyle
+		if currentLineStyle != style {
+			numberStyle = currentLineStyle
+		}
+		if seg.start == 0 || !ed.wrap {
+			for i, r := range fmt.Sprintf("%3d ", idx+1) {
+				if i >= gutterWidth || i >= ed.width {
+					break
+				}
+				screen.SetContent(ed.x+i, ed.y+row, r, nil, numberStyle)
 			}
-			screen.SetContent(ed.x+i, ed.y+row, r, nil, currentLineStyle)
 		}
 
-		// Draw text
-		for i, r := range line {
-			if i+4 >= ed.width {
+		// lines cut off by the scroll or the right edge get a mark there
+		markLeft := !ed.wrap && textWidth > 1 && ed.scrollX > 0 && len(cells) > 0
+		markRight := !ed.wrap && textWidth > 1 && lineWidth(cells) > ed.scrollX+textWidth
+
+		// Draw text, a cell per character
+		tokens := lineTokens
+		for _, c := range cells {
+			if c.start < seg.start || c.end > seg.end {
+				continue
+			}
+			col := seg.indent + c.col - seg.startCol
+			if markLeft && col < 1 {
+				continue
+			}
+			if col+c.width > textWidth || (markRight && col+c.width > textWidth-1) {
 				break
 			}
-			screen.SetContent(ed.x+4+i, ed.y+row, r, nil, currentLineStyle)
+			i := c.start
+			for len(tokens) > 0 && tokens[0].End <= i {
+				tokens = tokens[1:]
+			}
+			cellStyle := currentLineStyle
+			if len(tokens) > 0 && tokens[0].Start <= i {
+				cellStyle = syntaxStyles.Apply(currentLineStyle, tokens[0].Class)
+			}
+			for j, m := range matches {
+				if i >= m.Start && i < m.End {
+					cellStyle = matchStyle
+					if firstMatch+j == ed.find.current {
+						cellStyle = currentMatchStyle
+					}
+					break
+				}
+			}
+			if runes[i] == '\t' {
+				for j := 0; j < c.width; j++ {
+					screen.SetContent(ed.x+gutterWidth+col+j, ed.y+row, ' ', nil, cellStyle)
+				}
+				continue
+			}
+			screen.SetContent(ed.x+gutterWidth+col, ed.y+row, drawRune(runes[i]), runes[i+1:c.end], cellStyle)
 		}
+
+		markStyle := currentLineStyle.Foreground(th.Foreground(theme.EditorLineNumber))
+		if markLeft {
+			screen.SetContent(ed.x+gutterWidth, ed.y+row, '‹', nil, markStyle)
+		}
+		if markRight {
+			screen.SetContent(ed.x+gutterWidth+textWidth-1, ed.y+row, '›', nil, markStyle)
+		}
+	}
+
+	if ed.find.open {
+		ed.drawFindBar(screen)
+		return
 	}
 
-	// draw cursor
+	// draw cursor on the row showing its column
 	if ed.focused {
-		cx := ed.x + 4 + ed.buffer.CursorX
-		cy := ed.y + ed.buffer.CursorY - ed.scrollY
-		if cy >= 0 && cy < ed.height && cx >= ed.x && cx < ed.x+ed.width {
-			screen.ShowCursor(cx, cy)
+		for row, seg := range rows {
+			if seg.line != ed.buffer.CursorY || !seg.contains(ed.buffer.CursorX) {
+				continue
+			}
+			cx := ed.x + gutterWidth + seg.screenCol(ed.lineCells(seg.line), ed.buffer.CursorX)
+			if cx >= ed.x+gutterWidth && cx < ed.x+ed.width {
+				screen.ShowCursor(cx, ed.y+row)
+			}
+			break
+		}
+	}
+}
+
+// postCatchUp lexes the rest of the visible lines after this frame is
+// drawn; the redraw after the event shows them highlighted
+func (ed *Editor) postCatchUp(screen tcell.Screen, last int) {
+	if ed.lexPosted {
+		return
+	}
+	h, buf := ed.highlight, ed.buffer
+	catchUp := func() {
+		ed.lexPosted = false
+		// the tab may have changed in the meantime
+		if ed.highlight == h && ed.buffer == buf {
+			h.CatchUp(buf, last)
 		}
 	}
+	// with a full queue the next draw tries again
+	ed.lexPosted = screen.PostEvent(tcell.NewEventInterrupt(catchUp)) == nil
 }
 
 func (ed *Editor) GetBuffer() *buffer.Buffer {
@@ -0,0 +1,109 @@
+package editor
+
+import (
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+)
+
+// Buffer columns count runes, the screen counts display columns. A line is
+// laid out as cells so the two can be mapped both ways.
+
+// cell is one grapheme cluster of a line on screen: a character with any
+// combining marks, a wide character taking two columns, or a tab stretched
+// to the next tab stop
+type cell struct {
+	start, end int // rune range in the line
+	col, width int // display columns
+}
+
+// layoutLine splits a line into cells. Widths follow the cluster's first
+// rune, the same way tcell sizes what SetContent draws.
+func layoutLine(runes []rune, tabSize int) []cell {
+	cells := make([]cell, 0, len(runes))
+	col, i := 0, 0
+	g := uniseg.NewGraphemes(string(runes))
+	for g.Next() {
+		cluster := g.Runes()
+		c := cell{start: i, end: i + len(cluster), col: col, width: 1}
+		if cluster[0] == '\t' {
+			c.width = tabSize - col%tabSize
+		} else if w := runewidth.RuneWidth(cluster[0]); w > 1 {
+			c.width = w
+		}
+		cells = append(cells, c)
+		col += c.width
+		i = c.end
+	}
+	return cells
+}
+
+// lineCells lays out a buffer line
+func (ed *Editor) lineCells(line int) []cell {
+	return layoutLine([]rune(ed.buffer.Line(line)), ed.tabSize)
+}
+
+// lineWidth is how many columns the laid out line takes
+func lineWidth(cells []cell) int {
+	if len(cells) == 0 {
+		return 0
+	}
+	last := cells[len(cells)-1]
+	return last.col + last.width
+}
+
+// displayCol is the screen column of rune x; past the end it is the
+// column after the last cell
+func displayCol(cells []cell, x int) int {
+	for _, c := range cells {
+		if x < c.end {
+			return c.col
+		}
+	}
+	return lineWidth(cells)
+}
+
+// runeAt is the rune index of the cell covering screen column col, or the
+// end of the line when col is past it
+func runeAt(cells []cell, col int) int {
+	for _, c := range cells {
+		if col < c.col+c.width {
+			return c.start
+		}
+	}
+	if len(cells) == 0 {
+		return 0
+	}
+	return cells[len(cells)-1].end
+}
+
+// prevCluster is where the cluster before rune x starts, so Left skips a
+// character with its combining marks in one step
+func prevCluster(cells []cell, x int) int {
+	prev := 0
+	for _, c := range cells {
+		if c.start >= x {
+			break
+		}
+		prev = c.start
+	}
+	return prev
+}
+
+// nextCluster is where the cluster after the one at rune x starts
+func nextCluster(cells []cell, x int) int {
+	for _, c := range cells {
+		if x < c.end {
+			return c.end
+		}
+	}
+	return x + 1
+}
+
+// drawRune is what to put in a cell for a rune: control characters other
+// than tabs would upset the terminal, so they get a placeholder
+func drawRune(r rune) rune {
+	if r < ' ' || r == 0x7f {
+		return '�'
+	}
+	return r
+}
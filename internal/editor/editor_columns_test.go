@@ -0,0 +1,76 @@
+package editor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLayoutLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		tabSize int
+		want    []cell
+	}{
+		{"empty", "", 4, []cell{}},
+		{"ascii", "ab", 4, []cell{{0, 1, 0, 1}, {1, 2, 1, 1}}},
+		{"leading tab", "\tx", 4, []cell{{0, 1, 0, 4}, {1, 2, 4, 1}}},
+		// a tab reaches the next stop, not a fixed width
+		{"tab after text", "ab\tc", 4, []cell{{0, 1, 0, 1}, {1, 2, 1, 1}, {2, 3, 2, 2}, {3, 4, 4, 1}}},
+		{"tab on a stop", "abcd\tx", 4, []cell{{0, 1, 0, 1}, {1, 2, 1, 1}, {2, 3, 2, 1}, {3, 4, 3, 1}, {4, 5, 4, 4}, {5, 6, 8, 1}}},
+		{"tab size 8", "a\tb", 8, []cell{{0, 1, 0, 1}, {1, 2, 1, 7}, {2, 3, 8, 1}}},
+		{"wide runes", "漢a字", 4, []cell{{0, 1, 0, 2}, {1, 2, 2, 1}, {2, 3, 3, 2}}},
+		{"tab after a wide rune", "漢\tx", 4, []cell{{0, 1, 0, 2}, {1, 2, 2, 2}, {2, 3, 4, 1}}},
+		// clusters take one cell sized by their first rune
+		{"combining mark", "éx", 4, []cell{{0, 2, 0, 1}, {2, 3, 1, 1}}},
+		{"emoji with a modifier", "👍🏽a", 4, []cell{{0, 2, 0, 2}, {2, 3, 2, 1}}},
+		{"control character", "a\x01b", 4, []cell{{0, 1, 0, 1}, {1, 2, 1, 1}, {2, 3, 2, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := layoutLine([]rune(tt.line), tt.tabSize); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("layoutLine(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+// wrapRows draws the rows wrapLine splits line into, indents as spaces
+func wrapRows(line string, tabSize, width int) []string {
+	runes := []rune(line)
+	var rows []string
+	for _, s := range wrapLine(0, runes, layoutLine(runes, tabSize), width) {
+		rows = append(rows, strings.Repeat(" ", s.indent)+string(runes[s.start:s.end]))
+	}
+	return rows
+}
+
+func TestWrapLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		width int
+		want  []string
+	}{
+		{"fits", "short", 10, []string{"short"}},
+		{"empty", "", 10, []string{""}},
+		// the last row keeps a column for the cursor, a full line gets an
+		// empty row after it
+		{"exactly the width", "0123456789", 10, []string{"0123456789", "  "}},
+		{"break before words", "hello world again", 10, []string{"hello ", "  world ", "  again"}},
+		{"long word is cut", "abcdefghijklmnop", 8, []string{"abcdefgh", "  ijklmn", "  op"}},
+		{"keeps the indentation", "    foo bar baz", 12, []string{"    foo bar ", "      baz"}},
+		{"tab indentation", "\tfoo bar baz", 12, []string{"\tfoo bar ", "      baz"}},
+		// wide runes don't straddle rows
+		{"wide runes", "漢字漢字漢字", 7, []string{"漢字漢", "  字漢", "  字"}},
+		{"too narrow to wrap", "abc def", 1, []string{"abc def"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapRows(tt.line, 4, tt.width); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("wrapLine(%q, %d) = %q, want %q", tt.line, tt.width, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package editor
+
+// A buffer can be shown in several editors, each with its own cursor. The
+// buffer holds the cursor of whichever editor is working on it; an editor
+// keeps its own between calls and catches up on edits made elsewhere
+// through the buffer's change log.
+
+// viewCursor is where an editor left its cursor
+type viewCursor struct {
+	x, y     int
+	revision int // buffer revision when it was saved
+	held     bool
+}
+
+// useCursor puts the editor's cursor into the buffer for the rest of a
+// call; the returned func saves it back. Nested calls share the outer one.
+func (ed *Editor) useCursor() func() {
+	if ed.buffer == nil || ed.cursor.held {
+		return func() {}
+	}
+	ed.cursor.held = true
+	ed.restoreCursor()
+	return func() {
+		ed.cursor.held = false
+		ed.saveCursor()
+	}
+}
+
+// saveCursor takes the cursor from the buffer
+func (ed *Editor) saveCursor() {
+	if ed.buffer == nil {
+		return
+	}
+	ed.cursor.x, ed.cursor.y = ed.buffer.CursorX, ed.buffer.CursorY
+	ed.cursor.revision = ed.buffer.Revision()
+}
+
+// restoreCursor puts the saved cursor back into the buffer
+func (ed *Editor) restoreCursor() {
+	x, y, moved := ed.caughtUpCursor()
+	if moved {
+		// a selection may now span other text
+		ed.selecting = false
+	}
+	ed.buffer.CursorX, ed.buffer.CursorY = x, y
+}
+
+// caughtUpCursor is the saved cursor moved along with lines added or
+// removed since it was saved, and whether the buffer changed since
+func (ed *Editor) caughtUpCursor() (x, y int, changed bool) {
+	x, y = ed.cursor.x, ed.cursor.y
+	changed = ed.buffer.Revision() != ed.cursor.revision
+	if changed {
+		edits, _ := ed.buffer.EditsSince(ed.cursor.revision)
+		for _, e := range edits {
+			switch {
+			case y >= e.Line+e.Old:
+				y += e.New - e.Old
+			case y >= e.Line:
+				y = min(y, e.Line+max(0, e.New-1))
+			}
+		}
+	}
+	y = max(0, min(y, ed.buffer.LineCount()-1))
+	x = max(0, min(x, ed.buffer.LineLen(y)))
+	return x, y, changed
+}
+
+// Cursor is the editor's cursor as 0-based line and column
+func (ed *Editor) Cursor() (line, col int) {
+	if ed.buffer == nil {
+		return 0, 0
+	}
+	if ed.cursor.held {
+		return ed.buffer.CursorY, ed.buffer.CursorX
+	}
+	x, y, _ := ed.caughtUpCursor()
+	return y, x
+}
@@ -0,0 +1,371 @@
+package editor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/uditrawat03/bitcode/internal/buffer"
+	"github.com/uditrawat03/bitcode/internal/theme"
+)
+
+// findBar is the inline search (and replace) prompt drawn on the last editor row
+type findBar struct {
+	open    bool
+	query   []rune
+	opts    buffer.SearchOptions
+	err     error
+	matches []buffer.Match
+	current int
+
+	replacing   bool
+	replacement []rune
+	field       int           // 0 = find input, 1 = replace input
+	scope       *buffer.Range // only search inside the selection when set
+
+	// buffer and revision the matches were computed for
+	revision int
+	buf      *buffer.Buffer
+}
+
+// IsFindOpen reports whether the find bar has the keyboard
+func (ed *Editor) IsFindOpen() bool {
+	return ed.find.open
+}
+
+// Ctrl+F open the find bar, seeded with a single line selection
+func (ed *Editor) openFind() {
+	ed.find.open = true
+	if ed.selecting && ed.selStartY == ed.selEndY && ed.selStartX != ed.selEndX {
+		from, to := min(ed.selStartX, ed.selEndX), max(ed.selStartX, ed.selEndX)
+		line := []rune(ed.buffer.Line(ed.selStartY))
+		if to <= len(line) {
+			ed.find.query = append([]rune{}, line[from:to]...)
+		}
+		ed.selecting = false
+	}
+	ed.runFind(true)
+}
+
+// Ctrl+H open the find bar with a replace input
+func (ed *Editor) openReplace() {
+	ed.find.replacing = true
+	ed.find.field = 0
+	if ed.selecting && ed.selStartY != ed.selEndY {
+		// a multi-line selection is most likely meant as the scope
+		ed.toggleScope()
+	}
+	ed.openFind()
+	if len(ed.find.query) > 0 {
+		ed.find.field = 1
+	}
+}
+
+func (ed *Editor) closeFind() {
+	ed.find.open = false
+	ed.find.replacing = false
+	ed.find.scope = nil
+	ed.find.field = 0
+	ed.find.matches = nil
+	ed.status("")
+}
+
+// input returns the text field being edited
+func (f *findBar) input() *[]rune {
+	if f.replacing && f.field == 1 {
+		return &f.replacement
+	}
+	return &f.query
+}
+
+// findCommands are the find bar actions the keymap can bind; they only run
+// while the bar is open
+var findCommands = map[string]func(ed *Editor){
+	"find.next":     func(ed *Editor) { ed.stepFind(1) },
+	"find.previous": func(ed *Editor) { ed.stepFind(-1) },
+	// Enter in the replace input replaces, in the find input it moves on
+	"find.accept": func(ed *Editor) {
+		if ed.find.replacing && ed.find.field == 1 {
+			ed.replaceCurrent()
+		} else {
+			ed.stepFind(1)
+		}
+	},
+	"find.replaceAll": func(ed *Editor) {
+		if ed.find.replacing {
+			ed.replaceAll()
+		}
+	},
+	"find.toggleSelection": func(ed *Editor) {
+		ed.toggleScope()
+		ed.runFind(true)
+	},
+	"find.toggleCase": func(ed *Editor) {
+		ed.find.opts.CaseSensitive = !ed.find.opts.CaseSensitive
+		ed.runFind(true)
+	},
+	"find.toggleWord": func(ed *Editor) {
+		ed.find.opts.WholeWord = !ed.find.opts.WholeWord
+		ed.runFind(true)
+	},
+	"find.toggleRegexp": func(ed *Editor) {
+		ed.find.opts.Regexp = !ed.find.opts.Regexp
+		ed.runFind(true)
+	},
+}
+
+// handleFindKey edits the find bar's inputs; everything else it does comes
+// in through the find.* commands
+func (ed *Editor) handleFindKey(ev *tcell.EventKey) {
+	switch {
+	case ev.Key() == tcell.KeyEscape:
+		ed.closeFind()
+		return
+	case ev.Key() == tcell.KeyTab || ev.Key() == tcell.KeyBacktab:
+		if ed.find.replacing {
+			ed.find.field = 1 - ed.find.field
+		}
+	case ev.Key() == tcell.KeyBackspace || ev.Key() == tcell.KeyBackspace2:
+		input := ed.find.input()
+		if len(*input) > 0 {
+			*input = (*input)[:len(*input)-1]
+			if input == &ed.find.query {
+				ed.runFind(true)
+			} else {
+				ed.reportFind()
+			}
+		}
+	case ev.Key() == tcell.KeyRune && ev.Modifiers()&tcell.ModAlt == 0:
+		input := ed.find.input()
+		*input = append(*input, ev.Rune())
+		if input == &ed.find.query {
+			ed.runFind(true)
+		} else {
+			ed.reportFind()
+		}
+	}
+	ed.ensureCursorVisible()
+}
+
+// runFind searches the buffer again; with jump set the cursor moves to the
+// first match at or after it, like searching as you type
+func (ed *Editor) runFind(jump bool) {
+	f := &ed.find
+	f.matches, f.err = ed.buffer.FindAll(string(f.query), f.opts)
+	if f.scope != nil {
+		inScope := f.matches[:0]
+		for _, m := range f.matches {
+			if f.scope.Contains(m) {
+				inScope = append(inScope, m)
+			}
+		}
+		f.matches = inScope
+	}
+	f.revision = ed.buffer.Revision()
+	f.buf = ed.buffer
+	f.current = -1
+
+	if len(f.matches) > 0 {
+		f.current = 0
+		for i, m := range f.matches {
+			if m.Line > ed.buffer.CursorY || (m.Line == ed.buffer.CursorY && m.Start >= ed.buffer.CursorX) {
+				f.current = i
+				break
+			}
+		}
+		if jump {
+			ed.gotoMatch()
+		}
+	}
+	ed.reportFind()
+}
+
+// refreshFind recomputes matches after the buffer was edited
+func (ed *Editor) refreshFind() {
+	f := &ed.find
+	if !f.open || ed.buffer == nil {
+		return
+	}
+	if f.buf != ed.buffer || f.revision != ed.buffer.Revision() {
+		ed.runFind(false)
+	}
+}
+
+// stepFind moves to the next (1) or previous (-1) match
+func (ed *Editor) stepFind(dir int) {
+	f := &ed.find
+	ed.refreshFind()
+	if len(f.matches) == 0 {
+		return
+	}
+	f.current = (f.current + dir + len(f.matches)) % len(f.matches)
+	ed.gotoMatch()
+	ed.reportFind()
+}
+
+// replaceCurrent replaces the current match and moves on to the next one
+func (ed *Editor) replaceCurrent() {
+	f := &ed.find
+	ed.refreshFind()
+	if f.current < 0 || f.current >= len(f.matches) {
+		return
+	}
+	m := f.matches[f.current]
+	before := ed.buffer.LineLen(m.Line)
+
+	_, err := ed.buffer.Replace(string(f.query), string(f.replacement), f.opts, func(c buffer.Match) bool {
+		return c == m
+	})
+	if err != nil {
+		ed.status("Replace failed: " + err.Error())
+		return
+	}
+
+	// continue after the replaced text so a replacement containing the
+	// query isn't matched again
+	ed.buffer.CursorY = m.Line
+	ed.buffer.CursorX = m.End + ed.buffer.LineLen(m.Line) - before
+	if f.scope != nil && f.scope.EndY == m.Line {
+		f.scope.EndX += ed.buffer.LineLen(m.Line) - before
+	}
+	ed.runFind(true)
+}
+
+// replaceAll replaces every match (inside the scope when set) as one undo step
+func (ed *Editor) replaceAll() {
+	f := &ed.find
+	var accept func(buffer.Match) bool
+	if f.scope != nil {
+		scope := *f.scope
+		accept = scope.Contains
+	}
+
+	count, err := ed.buffer.Replace(string(f.query), string(f.replacement), f.opts, accept)
+	if err != nil {
+		ed.status("Replace failed: " + err.Error())
+		return
+	}
+	ed.buffer.CursorX = min(ed.buffer.CursorX, ed.buffer.LineLen(ed.buffer.CursorY))
+	ed.runFind(false)
+	if count == 1 {
+		ed.status("Replaced 1 occurrence")
+	} else {
+		ed.status(fmt.Sprintf("Replaced %d occurrences", count))
+	}
+}
+
+// toggleScope limits the search to the current selection, or lifts the limit
+func (ed *Editor) toggleScope() {
+	f := &ed.find
+	if f.scope != nil {
+		f.scope = nil
+		return
+	}
+	if !ed.selecting {
+		ed.status("No selection to search in")
+		return
+	}
+	sx, sy, ex, ey := ed.selStartX, ed.selStartY, ed.selEndX, ed.selEndY
+	if sy > ey || (sy == ey && sx > ex) {
+		sx, sy, ex, ey = ex, ey, sx, sy
+	}
+	f.scope = &buffer.Range{StartX: sx, StartY: sy, EndX: ex, EndY: ey}
+	ed.selecting = false
+}
+
+func (ed *Editor) gotoMatch() {
+	m := ed.find.matches[ed.find.current]
+	ed.buffer.CursorY = m.Line
+	ed.buffer.CursorX = m.Start
+}
+
+// reportFind shows "3 of 17" in the status bar
+func (ed *Editor) reportFind() {
+	f := &ed.find
+	switch {
+	case f.err != nil:
+		ed.status("Invalid pattern: " + f.err.Error())
+	case len(f.query) == 0:
+		ed.status("")
+	case len(f.matches) == 0:
+		ed.status("No results")
+	case f.replacing && f.current >= 0:
+		// preview what the current match turns into
+		m := f.matches[f.current]
+		with, err := ed.buffer.ReplacementFor(m, string(f.query), string(f.replacement), f.opts)
+		if err != nil {
+			with = string(f.replacement)
+		}
+		line := []rune(ed.buffer.Line(m.Line))
+		ed.status(fmt.Sprintf("%d of %d: %q -> %q", f.current+1, len(f.matches), string(line[m.Start:min(m.End, len(line))]), with))
+	default:
+		ed.status(fmt.Sprintf("%d of %d", f.current+1, len(f.matches)))
+	}
+}
+
+// lineMatches returns the matches on line y and the index of the first one
+func (ed *Editor) lineMatches(y int) ([]buffer.Match, int) {
+	f := &ed.find
+	if !f.open {
+		return nil, 0
+	}
+	first := sort.Search(len(f.matches), func(i int) bool { return f.matches[i].Line >= y })
+	last := first
+	for last < len(f.matches) && f.matches[last].Line == y {
+		last++
+	}
+	return f.matches[first:last], first
+}
+
+// drawFindBar renders the prompt and option toggles on the editor's last row
+func (ed *Editor) drawFindBar(screen tcell.Screen) {
+	th := theme.Current()
+	style := th.Style(theme.FindBarText)
+	onStyle := th.Style(theme.FindBarOptionOn)
+	offStyle := th.Style(theme.FindBarOptionOff)
+	row := ed.y + ed.height - 1
+
+	for col := 0; col < ed.width; col++ {
+		screen.SetContent(ed.x+col, row, ' ', nil, style)
+	}
+
+	col := ed.x
+	put := func(text string, st tcell.Style) {
+		for _, r := range text {
+			if col >= ed.x+ed.width {
+				return
+			}
+			screen.SetContent(col, row, r, nil, st)
+			col++
+		}
+	}
+
+	toggle := func(label string, on bool) {
+		if on {
+			put(label, onStyle)
+		} else {
+			put(label, offStyle)
+		}
+		put(" ", style)
+	}
+	toggle("[Aa]", ed.find.opts.CaseSensitive)
+	toggle("[W]", ed.find.opts.WholeWord)
+	toggle("[.*]", ed.find.opts.Regexp)
+	toggle("[Sel]", ed.find.scope != nil)
+
+	put("Find: ", style)
+	cursorX := col + len(ed.find.query)
+	put(string(ed.find.query), style)
+
+	if ed.find.replacing {
+		put("  Replace: ", style)
+		if ed.find.field == 1 {
+			cursorX = col + len(ed.find.replacement)
+		}
+		put(string(ed.find.replacement), style)
+	}
+
+	if ed.focused && cursorX < ed.x+ed.width {
+		screen.ShowCursor(cursorX, row)
+	}
+}
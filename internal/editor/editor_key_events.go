@@ -1,10 +1,12 @@
 package editor
 
 import (
+	"path/filepath"
 	"strings"
 
 	"github.com/atotto/clipboard"
 	"github.com/gdamore/tcell/v2"
+	"github.com/uditrawat03/bitcode/internal/buffer"
 )
 
 func (ed *Editor) isTypingKey(ev *tcell.EventKey) bool {
@@ -17,10 +19,24 @@ func (ed *Editor) isTypingKey(ev *tcell.EventKey) bool {
 }
 
 func (ed *Editor) HandleKey(ev *tcell.EventKey) {
+	defer ed.useCursor()()
 	if !ed.focused || ed.buffer == nil {
 		return
 	}
 
+	if ed.find.open {
+		ed.handleFindKey(ev)
+		return
+	}
+
+	// Everything done for this key is a single undo step
+	ed.buffer.BeginStep(ed.selection())
+	defer func() { ed.buffer.EndStep(ed.selection()) }()
+
+	if ev.Key() != tcell.KeyRune {
+		ed.buffer.BreakUndoGroup()
+	}
+
 	// Cancel Ctrl+A selection on typing keys (not arrow or meta)
 	if ed.selecting && !ed.isTypingKey(ev) {
 		ed.selecting = false
@@ -54,16 +70,6 @@ func (ed *Editor) HandleKey(ev *tcell.EventKey) {
 		ed.handleEnter()
 	case tcell.KeyBackspace, tcell.KeyBackspace2:
 		ed.handleBackspace()
-	case tcell.KeyCtrlX:
-		ed.handleCut()
-	case tcell.KeyCtrlC:
-		ed.handleCopy()
-	case tcell.KeyCtrlV:
-		ed.handlePaste()
-	case tcell.KeyCtrlS:
-		ed.handleSave()
-	case tcell.KeyCtrlA:
-		ed.handleSelectAll()
 	default:
 		ed.handleRune(ev)
 	}
@@ -77,46 +83,102 @@ func (ed *Editor) HandleKey(ev *tcell.EventKey) {
 	ed.ensureCursorVisible()
 }
 
+// commands are the editor actions the keymap can bind
+var commands = map[string]func(ed *Editor){
+	"editor.find":             (*Editor).openFind,
+	"editor.replace":          (*Editor).openReplace,
+	"editor.undo":             (*Editor).handleUndo,
+	"editor.redo":             (*Editor).handleRedo,
+	"editor.cut":              (*Editor).handleCut,
+	"editor.copy":             (*Editor).handleCopy,
+	"editor.paste":            (*Editor).handlePaste,
+	"editor.save":             (*Editor).handleSave,
+	"editor.selectAll":        (*Editor).handleSelectAll,
+	"editor.toggleLineEnding": (*Editor).handleToggleLineEnding,
+	"editor.toggleWrap":       (*Editor).ToggleWrap,
+}
+
+// Execute runs a named editor command and reports whether it is one
+func (ed *Editor) Execute(cmd string) bool {
+	defer ed.useCursor()()
+	if fn, ok := findCommands[cmd]; ok {
+		if ed.find.open && ed.buffer != nil {
+			fn(ed)
+			ed.ensureCursorVisible()
+		}
+		return true
+	}
+	fn, ok := commands[cmd]
+	if !ok || ed.buffer == nil {
+		return ok
+	}
+
+	switch cmd {
+	case "editor.undo", "editor.redo", "editor.find", "editor.replace", "editor.toggleWrap":
+		// these manage the undo history and selection themselves
+		fn(ed)
+	default:
+		ed.buffer.BeginStep(ed.selection())
+		ed.buffer.BreakUndoGroup()
+		fn(ed)
+		ed.buffer.EndStep(ed.selection())
+	}
+	ed.ensureCursorVisible()
+	return true
+}
+
 func (ed *Editor) handleCursorMovement(ev *tcell.EventKey) {
+	if ed.wrap && (ev.Key() == tcell.KeyUp || ev.Key() == tcell.KeyDown) {
+		// by screen rows
+		if ev.Key() == tcell.KeyUp {
+			ed.moveRows(-1)
+		} else {
+			ed.moveRows(1)
+		}
+		return
+	}
+
 	switch ev.Key() {
 	case tcell.KeyUp:
 		if ed.buffer.CursorY > 0 {
-			ed.buffer.CursorY--
-			if ed.buffer.CursorX > len(ed.buffer.Content[ed.buffer.CursorY]) {
-				ed.buffer.CursorX = len(ed.buffer.Content[ed.buffer.CursorY])
-			}
+			ed.moveLine(-1)
 		}
 	case tcell.KeyDown:
-		if ed.buffer.CursorY < len(ed.buffer.Content)-1 {
-			ed.buffer.CursorY++
-			if ed.buffer.CursorX > len(ed.buffer.Content[ed.buffer.CursorY]) {
-				ed.buffer.CursorX = len(ed.buffer.Content[ed.buffer.CursorY])
-			}
+		if ed.buffer.CursorY < ed.buffer.LineCount()-1 {
+			ed.moveLine(1)
 		}
 	case tcell.KeyLeft:
 		if ed.buffer.CursorX > 0 {
-			ed.buffer.CursorX--
+			ed.buffer.CursorX = prevCluster(ed.lineCells(ed.buffer.CursorY), ed.buffer.CursorX)
 		} else if ed.buffer.CursorY > 0 {
 			ed.buffer.CursorY--
-			ed.buffer.CursorX = len(ed.buffer.Content[ed.buffer.CursorY])
+			ed.buffer.CursorX = ed.buffer.LineLen(ed.buffer.CursorY)
 		}
 	case tcell.KeyRight:
-		if ed.buffer.CursorX < len(ed.buffer.Content[ed.buffer.CursorY]) {
-			ed.buffer.CursorX++
-		} else if ed.buffer.CursorY < len(ed.buffer.Content)-1 {
+		if ed.buffer.CursorX < ed.buffer.LineLen(ed.buffer.CursorY) {
+			ed.buffer.CursorX = min(ed.buffer.LineLen(ed.buffer.CursorY), nextCluster(ed.lineCells(ed.buffer.CursorY), ed.buffer.CursorX))
+		} else if ed.buffer.CursorY < ed.buffer.LineCount()-1 {
 			ed.buffer.CursorY++
 			ed.buffer.CursorX = 0
 		}
 	}
 }
 
+// moveLine moves the cursor dy lines, staying in the same screen column
+// as far as the line allows
+func (ed *Editor) moveLine(dy int) {
+	col := displayCol(ed.lineCells(ed.buffer.CursorY), ed.buffer.CursorX)
+	ed.buffer.CursorY += dy
+	ed.buffer.CursorX = runeAt(ed.lineCells(ed.buffer.CursorY), col)
+}
+
 func (ed *Editor) handleHome() {
 	ed.buffer.CursorX = 0
 }
 
 func (ed *Editor) handleEnd() {
-	if ed.buffer.CursorY < len(ed.buffer.Content) {
-		ed.buffer.CursorX = len(ed.buffer.Content[ed.buffer.CursorY])
+	if ed.buffer.CursorY < ed.buffer.LineCount() {
+		ed.buffer.CursorX = ed.buffer.LineLen(ed.buffer.CursorY)
 	}
 }
 
@@ -129,8 +191,8 @@ func (ed *Editor) handlePageUp() {
 
 func (ed *Editor) handlePageDown() {
 	ed.buffer.CursorY += ed.height
-	if ed.buffer.CursorY >= len(ed.buffer.Content) {
-		ed.buffer.CursorY = len(ed.buffer.Content) - 1
+	if ed.buffer.CursorY >= ed.buffer.LineCount() {
+		ed.buffer.CursorY = ed.buffer.LineCount() - 1
 	}
 }
 
@@ -144,14 +206,12 @@ func (ed *Editor) handleTab() {
 		if startY > endY {
 			startY, endY = endY, startY
 		}
-		const tabSize = 4
 		for y := startY; y <= endY; y++ {
-			line := string(ed.buffer.Content[y])
-			ed.buffer.SetLine(y, strings.Repeat(" ", tabSize)+line)
+			line := ed.buffer.Line(y)
+			ed.buffer.SetLine(y, strings.Repeat(" ", ed.tabSize)+line)
 		}
 	} else {
-		const tabSize = 4
-		for i := 0; i < tabSize; i++ {
+		for i := 0; i < ed.tabSize; i++ {
 			ed.buffer.InsertRune(' ')
 		}
 	}
@@ -172,13 +232,29 @@ func (ed *Editor) handleDelete() {
 }
 
 func (ed *Editor) handleRune(ev *tcell.EventKey) {
-	if ev.Rune() != 0 {
+	// unbound control keys carry their control code as the rune
+	if ev.Key() == tcell.KeyRune && ev.Rune() != 0 {
 		ed.buffer.InsertRune(ev.Rune())
 	}
 }
 
 func (ed *Editor) handleSave() {
-	ed.buffer.Save()
+	if err := ed.buffer.Save(); err != nil {
+		ed.status("Save failed: " + err.Error())
+		return
+	}
+	ed.status("Saved " + filepath.Base(ed.buffer.File))
+}
+
+// Ctrl+E switch the file between LF and CRLF line endings
+func (ed *Editor) handleToggleLineEnding() {
+	if ed.buffer.LineEnding == buffer.CRLF {
+		ed.buffer.SetLineEnding(buffer.LF)
+		ed.status("Line endings: LF")
+	} else {
+		ed.buffer.SetLineEnding(buffer.CRLF)
+		ed.status("Line endings: CRLF")
+	}
 }
 
 // Cut selected text and also write to system clipboard
@@ -215,14 +291,46 @@ func (ed *Editor) handlePaste() {
 
 	text := []rune(str)
 	ed.buffer.PasteClipboard(text)
+	ed.selecting = false
+}
+
+// Ctrl+Z undo
+func (ed *Editor) handleUndo() {
+	if sel, ok := ed.buffer.Undo(); ok {
+		ed.setSelection(sel)
+	}
+}
+
+// Ctrl+Y redo
+func (ed *Editor) handleRedo() {
+	if sel, ok := ed.buffer.Redo(); ok {
+		ed.setSelection(sel)
+	}
+}
+
+// selection returns the current selection in the form kept by the undo history
+func (ed *Editor) selection() buffer.Selection {
+	return buffer.Selection{
+		Active: ed.selecting,
+		StartX: ed.selStartX,
+		StartY: ed.selStartY,
+		EndX:   ed.selEndX,
+		EndY:   ed.selEndY,
+	}
+}
+
+func (ed *Editor) setSelection(sel buffer.Selection) {
+	ed.selecting = sel.Active
+	ed.selStartX, ed.selStartY = sel.StartX, sel.StartY
+	ed.selEndX, ed.selEndY = sel.EndX, sel.EndY
 }
 
 // Ctrl+A select all
 func (ed *Editor) handleSelectAll() {
 	ed.selStartX, ed.selStartY = 0, 0
-	lastLine := len(ed.buffer.Content) - 1
+	lastLine := ed.buffer.LineCount() - 1
 	ed.selEndY = lastLine
-	ed.selEndX = len(ed.buffer.Content[lastLine])
+	ed.selEndX = ed.buffer.LineLen(lastLine)
 	ed.selecting = true
 }
 
@@ -242,10 +350,34 @@ func (ed *Editor) ensureCursorVisible() {
 	if ed.buffer == nil {
 		return
 	}
+	if ed.wrap {
+		ed.ensureWrappedCursorVisible()
+		return
+	}
 	if ed.buffer.CursorY < ed.scrollY {
 		ed.scrollY = ed.buffer.CursorY
 	}
-	if ed.buffer.CursorY >= ed.scrollY+ed.height {
-		ed.scrollY = ed.buffer.CursorY - ed.height + 1
+	if ed.buffer.CursorY >= ed.scrollY+ed.textHeight() {
+		ed.scrollY = ed.buffer.CursorY - ed.textHeight() + 1
+	}
+	ed.scrollToCursorX()
+}
+
+// scrollToCursorX scrolls sideways so the cursor stays in view, clear of the
+// truncation marks at either edge
+func (ed *Editor) scrollToCursorX() {
+	if ed.buffer == nil || ed.wrap {
+		return
+	}
+	cx, width := displayCol(ed.lineCells(ed.buffer.CursorY), ed.buffer.CursorX), ed.textWidth()
+	if width < 3 {
+		ed.scrollX = max(0, cx-max(0, width-1))
+		return
+	}
+	if ed.scrollX > 0 && cx < ed.scrollX+1 {
+		ed.scrollX = max(0, cx-1)
+	}
+	if cx > ed.scrollX+width-2 {
+		ed.scrollX = cx - width + 2
 	}
 }
@@ -2,28 +2,43 @@ package editor
 
 import "github.com/gdamore/tcell/v2"
 
+// How many lines or columns one wheel step scrolls
+const wheelStep = 3
+
 func (ed *Editor) HandleMouse(ev *tcell.EventMouse) {
+	defer ed.useCursor()()
 	x, y := ev.Position()
+	inside := x >= ed.x && x < ed.x+ed.width && y >= ed.y && y < ed.y+ed.height
+
+	// wheel, Shift turns it sideways
+	shift := ev.Modifiers()&tcell.ModShift != 0
+	if inside {
+		switch {
+		case ev.Buttons()&tcell.WheelUp != 0 && shift, ev.Buttons()&tcell.WheelLeft != 0:
+			ed.ScrollX(-wheelStep)
+		case ev.Buttons()&tcell.WheelDown != 0 && shift, ev.Buttons()&tcell.WheelRight != 0:
+			ed.ScrollX(wheelStep)
+		case ev.Buttons()&tcell.WheelUp != 0:
+			ed.Scroll(-wheelStep)
+		case ev.Buttons()&tcell.WheelDown != 0:
+			ed.Scroll(wheelStep)
+		}
+	}
+
 	if ev.Buttons()&tcell.Button1 != 0 { // left click
-		if x >= ed.x && x < ed.x+ed.width && y >= ed.y && y < ed.y+ed.height {
+		if inside {
 			if ed.focusCb != nil {
 				ed.focusCb() // tell ScreenManager to focus editor
 			}
 
 			// update cursor to click position
 			if ed.buffer != nil {
-				col := x - ed.x - 4 // account for line number gutter
-				if col < 0 {
-					col = 0
-				}
-				if col > len(ed.buffer.Content[ed.buffer.CursorY]) {
-					col = len(ed.buffer.Content[ed.buffer.CursorY])
-				}
-				ed.buffer.CursorX = col
-
-				row := y - ed.y + ed.scrollY
-				if row >= 0 && row < len(ed.buffer.Content) {
-					ed.buffer.CursorY = row
+				// map the screen row back to its line and columns
+				rows := ed.visibleRows()
+				if row := y - ed.y; row < len(rows) {
+					seg := rows[row]
+					ed.buffer.CursorY = seg.line
+					ed.buffer.CursorX = seg.column(ed.lineCells(seg.line), x-ed.x-gutterWidth) // account for line number gutter
 				}
 			}
 		}
@@ -34,11 +49,28 @@ func (ed *Editor) Scroll(dy int) {
 	if ed.buffer == nil {
 		return
 	}
+	if ed.wrap {
+		ed.scrollRows(dy)
+		return
+	}
 	ed.scrollY += dy
 	if ed.scrollY < 0 {
 		ed.scrollY = 0
 	}
-	if ed.scrollY > len(ed.buffer.Content)-ed.height {
-		ed.scrollY = max(0, len(ed.buffer.Content)-ed.height)
+	if ed.scrollY > ed.buffer.LineCount()-ed.height {
+		ed.scrollY = max(0, ed.buffer.LineCount()-ed.height)
+	}
+}
+
+// ScrollX scrolls sideways without moving the cursor, up to the end of the
+// longest visible line
+func (ed *Editor) ScrollX(dx int) {
+	if ed.buffer == nil || ed.wrap {
+		return
+	}
+	longest := 0
+	for row := 0; row < ed.textHeight() && ed.scrollY+row < ed.buffer.LineCount(); row++ {
+		longest = max(longest, lineWidth(ed.lineCells(ed.scrollY+row)))
 	}
+	ed.scrollX = max(0, min(ed.scrollX+dx, longest-ed.textWidth()+2))
 }
@@ -0,0 +1,312 @@
+package editor
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Soft wrap shows long lines over several screen rows. The buffer is not
+// changed; segments map screen rows back to buffer columns.
+
+// wrapIndent is added to a line's own indentation on its continuation rows
+const wrapIndent = 2
+
+// WrapMode says which buffers are soft wrapped
+type WrapMode int
+
+const (
+	WrapOff WrapMode = iota
+	WrapOn
+	// WrapProse wraps Markdown and plain text only
+	WrapProse
+)
+
+// ParseWrapMode reads "off", "on" or "prose"
+func ParseWrapMode(s string) (WrapMode, bool) {
+	switch s {
+	case "off":
+		return WrapOff, true
+	case "on":
+		return WrapOn, true
+	case "prose":
+		return WrapProse, true
+	}
+	return WrapOff, false
+}
+
+// isProse reports whether a file is text meant to be read, not code
+func isProse(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".txt", ".rst", ".adoc", ".org":
+		return true
+	}
+	return false
+}
+
+// SetWrapMode sets which buffers wrap; the current buffer follows it
+func (ed *Editor) SetWrapMode(mode WrapMode) {
+	defer ed.useCursor()()
+	ed.wrapMode = mode
+	ed.updateWrap()
+}
+
+// ToggleWrap turns soft wrap on or off for the current buffer
+func (ed *Editor) ToggleWrap() {
+	defer ed.useCursor()()
+	ed.setWrap(!ed.wrap)
+	if ed.wrap {
+		ed.status("Word wrap on")
+	} else {
+		ed.status("Word wrap off")
+	}
+}
+
+// Wrapping reports whether the current buffer is soft wrapped
+func (ed *Editor) Wrapping() bool {
+	return ed.wrap
+}
+
+func (ed *Editor) updateWrap() {
+	file := ""
+	if ed.buffer != nil {
+		file = ed.buffer.File
+	}
+	ed.setWrap(ed.wrapMode == WrapOn || (ed.wrapMode == WrapProse && isProse(file)))
+}
+
+func (ed *Editor) setWrap(wrap bool) {
+	ed.wrap = wrap
+	ed.scrollX = 0
+	ed.scrollSub = 0
+	ed.ensureCursorVisible()
+}
+
+// segment is the part of a buffer line shown on one screen row
+type segment struct {
+	line       int
+	start, end int // rune range in the line
+	startCol   int // display column of start in the whole line
+	indent     int // blank columns before the text
+	last       bool
+}
+
+// column maps a text column on the row to a buffer column. The end of a
+// segment belongs to the next row unless it is the line's last one.
+func (s segment) column(cells []cell, x int) int {
+	col := max(s.start, runeAt(cells, s.startCol+max(0, x-s.indent)))
+	if s.last {
+		return min(col, s.end)
+	}
+	if col >= s.end {
+		return prevCluster(cells, s.end)
+	}
+	return col
+}
+
+// screenCol is where buffer column x is drawn on the row, relative to the
+// text area
+func (s segment) screenCol(cells []cell, x int) int {
+	return s.indent + displayCol(cells, x) - s.startCol
+}
+
+// contains reports whether buffer column col is shown on this row
+func (s segment) contains(col int) bool {
+	return col >= s.start && (col < s.end || s.last)
+}
+
+// wrapLine splits a line into rows of at most width columns, breaking
+// before words where it can. The last row keeps a column free for the
+// cursor at the end of the line.
+func wrapLine(line int, runes []rune, cells []cell, width int) []segment {
+	if width < 2 {
+		return []segment{{line: line, start: 0, end: len(runes), last: true}}
+	}
+
+	lead := 0
+	for _, c := range cells {
+		if !isSpace(runes[c.start]) {
+			break
+		}
+		lead = c.col + c.width
+	}
+	indent := lead + wrapIndent
+	if indent > width/2 {
+		indent = 0
+	}
+
+	var segs []segment
+	total := lineWidth(cells)
+	for ci := 0; ; {
+		seg := segment{line: line, start: len(runes), startCol: total}
+		if ci < len(cells) {
+			seg.start, seg.startCol = cells[ci].start, cells[ci].col
+		}
+		room := width
+		if ci > 0 {
+			seg.indent = indent
+			room -= indent
+		}
+		if total-seg.startCol < room {
+			seg.end, seg.last = len(runes), true
+			return append(segs, seg)
+		}
+
+		// as many cells as fit, at least one
+		cj := ci + 1
+		for cj < len(cells) && cells[cj].col+cells[cj].width-seg.startCol <= room {
+			cj++
+		}
+		for b := cj; b > ci+1 && b < len(cells); b-- {
+			if isSpace(runes[cells[b-1].start]) && !isSpace(runes[cells[b].start]) {
+				cj = b
+				break
+			}
+		}
+		seg.end = cells[cj-1].end
+		segs = append(segs, seg)
+		ci = cj
+	}
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// segments returns the screen rows of a buffer line; unwrapped that is the
+// part scrolled into view
+func (ed *Editor) segments(line int) []segment {
+	runes := []rune(ed.buffer.Line(line))
+	cells := layoutLine(runes, ed.tabSize)
+	if ed.wrap {
+		return wrapLine(line, runes, cells, ed.textWidth())
+	}
+
+	seg := segment{line: line, start: len(runes), end: len(runes), startCol: ed.scrollX, last: true}
+	for _, c := range cells {
+		if c.col >= ed.scrollX && seg.start == len(runes) {
+			seg.start, seg.end = c.start, c.start
+		}
+		if c.col >= ed.scrollX && c.col+c.width <= ed.scrollX+ed.textWidth() {
+			seg.end = c.end
+		}
+	}
+	return []segment{seg}
+}
+
+// cursorSub is the index of the row of its line the cursor is on
+func (ed *Editor) cursorSub(segs []segment) int {
+	for i, s := range segs {
+		if s.contains(ed.buffer.CursorX) {
+			return i
+		}
+	}
+	return len(segs) - 1
+}
+
+// visibleRows lists the segments on screen, top to bottom
+func (ed *Editor) visibleRows() []segment {
+	var rows []segment
+	if ed.buffer == nil {
+		return rows
+	}
+	line, sub := ed.scrollY, ed.scrollSub
+	for line < ed.buffer.LineCount() && len(rows) < ed.textHeight() {
+		segs := ed.segments(line)
+		for _, s := range segs[min(sub, len(segs)-1):] {
+			if len(rows) == ed.textHeight() {
+				break
+			}
+			rows = append(rows, s)
+		}
+		line, sub = line+1, 0
+	}
+	return rows
+}
+
+// stepRows moves a (line, row in line) position by n screen rows, stopping
+// at the start and end of the buffer
+func (ed *Editor) stepRows(line, sub, n int) (int, int) {
+	for ; n > 0; n-- {
+		if sub+1 < len(ed.segments(line)) {
+			sub++
+		} else if line+1 < ed.buffer.LineCount() {
+			line, sub = line+1, 0
+		} else {
+			break
+		}
+	}
+	for ; n < 0; n++ {
+		if sub > 0 {
+			sub--
+		} else if line > 0 {
+			line--
+			sub = len(ed.segments(line)) - 1
+		} else {
+			break
+		}
+	}
+	return line, sub
+}
+
+// ensureWrappedCursorVisible scrolls by screen rows so the cursor's row is
+// in view
+func (ed *Editor) ensureWrappedCursorVisible() {
+	height := ed.textHeight()
+	cursorSub := ed.cursorSub(ed.segments(ed.buffer.CursorY))
+	ed.scrollSub = min(ed.scrollSub, len(ed.segments(ed.scrollY))-1)
+
+	// above the view
+	if ed.buffer.CursorY < ed.scrollY || (ed.buffer.CursorY == ed.scrollY && cursorSub < ed.scrollSub) {
+		ed.scrollY, ed.scrollSub = ed.buffer.CursorY, cursorSub
+		return
+	}
+
+	// count rows down from the top, giving up once past the bottom
+	line, sub := ed.scrollY, ed.scrollSub
+	for rows := 0; rows < height; rows++ {
+		if line == ed.buffer.CursorY && sub == cursorSub {
+			return
+		}
+		nextLine, nextSub := ed.stepRows(line, sub, 1)
+		if nextLine == line && nextSub == sub {
+			break
+		}
+		line, sub = nextLine, nextSub
+	}
+
+	// below it: put the cursor on the last row
+	ed.scrollY, ed.scrollSub = ed.stepRows(ed.buffer.CursorY, cursorSub, -(max(1, height) - 1))
+}
+
+// moveRows moves the cursor up or down by screen rows, keeping its column
+// on screen where the row is long enough
+func (ed *Editor) moveRows(n int) {
+	segs := ed.segments(ed.buffer.CursorY)
+	sub := ed.cursorSub(segs)
+	x := segs[sub].screenCol(ed.lineCells(ed.buffer.CursorY), ed.buffer.CursorX)
+
+	line, sub := ed.stepRows(ed.buffer.CursorY, sub, n)
+	target := ed.segments(line)[sub]
+	ed.buffer.CursorY = line
+	ed.buffer.CursorX = target.column(ed.lineCells(line), x)
+}
+
+// scrollRows scrolls the wrapped view by n screen rows without moving the
+// cursor
+func (ed *Editor) scrollRows(n int) {
+	ed.scrollSub = min(ed.scrollSub, len(ed.segments(ed.scrollY))-1)
+	line, sub := ed.stepRows(ed.scrollY, ed.scrollSub, n)
+
+	// don't scroll past the last row
+	if n > 0 {
+		endLine := ed.buffer.LineCount() - 1
+		lastLine, lastSub := ed.stepRows(endLine, len(ed.segments(endLine))-1, -(max(1, ed.textHeight()) - 1))
+		if line > lastLine || (line == lastLine && sub > lastSub) {
+			line, sub = lastLine, lastSub
+		}
+		if line < ed.scrollY || (line == ed.scrollY && sub < ed.scrollSub) {
+			return
+		}
+	}
+	ed.scrollY, ed.scrollSub = line, sub
+}
@@ -0,0 +1,425 @@
+package findpanel
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/uditrawat03/bitcode/internal/buffer"
+	"github.com/uditrawat03/bitcode/internal/theme"
+	"github.com/uditrawat03/bitcode/internal/workspace"
+)
+
+// row is a line of the result list, either a file header or one of its matches
+type row struct {
+	file  int
+	match int // -1 for the file header
+}
+
+// FindPanel is the "Find in Files" view shown in place of the sidebar
+type FindPanel struct {
+	X, Y, Width, Height int
+	focused             bool
+
+	root    string
+	query   []rune
+	opts    buffer.SearchOptions
+	results []workspace.FileResult
+	folded  map[string]bool
+	rows    []row
+
+	// replace mode, unchecked matches are left alone
+	replacing   bool
+	replacement []rune
+	field       int // 0 = query input, 1 = replacement input
+	unchecked   map[row]bool
+
+	// the query the results belong to, used when replacing
+	searched     string
+	searchedOpts buffer.SearchOptions
+	re           *regexp.Regexp
+
+	selected  int // index into rows, -1 while typing in the query input
+	scrollY   int
+	searching bool
+	err       error
+	total     int
+
+	onSearch  func(query string, opts buffer.SearchOptions)
+	onOpen    func(path string, line, col int)
+	onReplace func(edits []buffer.FileEdit, query, replacement string, opts buffer.SearchOptions)
+	onClose   func()
+	focusCb   func()
+}
+
+func CreateFindPanel(x, y, width, height int, root string) *FindPanel {
+	return &FindPanel{
+		X: x, Y: y, Width: width, Height: height,
+		root:      root,
+		folded:    map[string]bool{},
+		unchecked: map[row]bool{},
+		selected:  -1,
+	}
+}
+
+// SetOnSearch is called when a new search should start
+func (fp *FindPanel) SetOnSearch(cb func(query string, opts buffer.SearchOptions)) {
+	fp.onSearch = cb
+}
+
+// SetOnOpen is called with a 0-based line and column when a match is picked
+func (fp *FindPanel) SetOnOpen(cb func(path string, line, col int)) { fp.onOpen = cb }
+
+// SetOnReplace is called with the checked matches when replacing is confirmed
+func (fp *FindPanel) SetOnReplace(cb func(edits []buffer.FileEdit, query, replacement string, opts buffer.SearchOptions)) {
+	fp.onReplace = cb
+}
+
+func (fp *FindPanel) SetOnClose(cb func())       { fp.onClose = cb }
+func (fp *FindPanel) SetFocusCallback(cb func()) { fp.focusCb = cb }
+
+// Focusable
+func (fp *FindPanel) Focus()          { fp.focused = true }
+func (fp *FindPanel) Blur()           { fp.focused = false }
+func (fp *FindPanel) IsFocused() bool { return fp.focused }
+
+// SetBounds moves the panel, it shares the sidebar's area
+func (fp *FindPanel) SetBounds(x, y, width, height int) {
+	fp.X, fp.Y, fp.Width, fp.Height = x, y, width, height
+}
+
+// SetQuery seeds the input, e.g. with the editor selection
+func (fp *FindPanel) SetQuery(query string) {
+	fp.query = []rune(query)
+	fp.selected = -1
+}
+
+// SetReplacing switches between plain search and search and replace
+func (fp *FindPanel) SetReplacing(replacing bool) {
+	fp.replacing = replacing
+	fp.field = 0
+	if replacing && len(fp.query) > 0 {
+		fp.field = 1
+	}
+	fp.selected = -1
+}
+
+// IsReplacing reports whether the panel is in replace mode
+func (fp *FindPanel) IsReplacing() bool { return fp.replacing }
+
+// Start clears the old results before a search runs
+func (fp *FindPanel) Start() {
+	fp.results = nil
+	fp.rows = nil
+	fp.folded = map[string]bool{}
+	fp.unchecked = map[row]bool{}
+	fp.total = 0
+	fp.err = nil
+	fp.scrollY = 0
+	fp.selected = -1
+	fp.searching = true
+}
+
+// AddResults appends files streamed in by a running search
+func (fp *FindPanel) AddResults(results []workspace.FileResult) {
+	for _, r := range results {
+		fp.results = append(fp.results, r)
+		fp.total += len(r.Matches)
+	}
+	fp.rebuildRows()
+}
+
+// Finish marks the search as done; err is shown in the panel
+func (fp *FindPanel) Finish(err error) {
+	fp.searching = false
+	fp.err = err
+}
+
+// rebuildRows flattens the results into file headers and match rows
+func (fp *FindPanel) rebuildRows() {
+	fp.rows = fp.rows[:0]
+	for i, r := range fp.results {
+		fp.rows = append(fp.rows, row{file: i, match: -1})
+		if fp.folded[r.Path] {
+			continue
+		}
+		for j := range r.Matches {
+			fp.rows = append(fp.rows, row{file: i, match: j})
+		}
+	}
+	if fp.selected >= len(fp.rows) {
+		fp.selected = len(fp.rows) - 1
+	}
+}
+
+// summary is the text on the options line
+func (fp *FindPanel) summary() string {
+	switch {
+	case fp.err == workspace.ErrTooManyMatches:
+		return fmt.Sprintf("%d+ results", fp.total)
+	case fp.err != nil:
+		return fp.err.Error()
+	case fp.searching:
+		return fmt.Sprintf("Searching… %d", fp.total)
+	case len(fp.query) == 0:
+		return ""
+	case fp.total == 0 && fp.results == nil:
+		return "No results"
+	}
+	files := "files"
+	if len(fp.results) == 1 {
+		files = "file"
+	}
+	return fmt.Sprintf("%d in %d %s", fp.total, len(fp.results), files)
+}
+
+// headerRows is the number of rows above the result list: the inputs and
+// the options/status line
+func (fp *FindPanel) headerRows() int {
+	if fp.replacing {
+		return 3
+	}
+	return 2
+}
+
+// listHeight is the number of result rows that fit
+func (fp *FindPanel) listHeight() int {
+	return max(0, fp.Height-fp.headerRows())
+}
+
+// checkedEdits collects the checked matches per file
+func (fp *FindPanel) checkedEdits() ([]buffer.FileEdit, int) {
+	var edits []buffer.FileEdit
+	count := 0
+	for i, r := range fp.results {
+		edit := buffer.FileEdit{Path: r.Path}
+		for j, m := range r.Matches {
+			if fp.unchecked[row{file: i, match: j}] {
+				continue
+			}
+			edit.Matches = append(edit.Matches, buffer.Match{Line: m.Line, Start: m.Start, End: m.End})
+		}
+		if len(edit.Matches) > 0 {
+			edits = append(edits, edit)
+			count += len(edit.Matches)
+		}
+	}
+	return edits, count
+}
+
+// fileChecked reports whether any match of the file is checked
+func (fp *FindPanel) fileChecked(file int) bool {
+	for j := range fp.results[file].Matches {
+		if !fp.unchecked[row{file: file, match: j}] {
+			return true
+		}
+	}
+	return false
+}
+
+// replacementFor previews what a match turns into
+func (fp *FindPanel) replacementFor(m workspace.LineMatch) string {
+	if !fp.searchedOpts.Regexp || fp.re == nil {
+		return string(fp.replacement)
+	}
+	return buffer.ExpandMatch(fp.re, m.Text, buffer.Match{Line: m.Line, Start: m.Start, End: m.End}, string(fp.replacement))
+}
+
+// Draw the panel
+func (fp *FindPanel) Draw(s tcell.Screen) {
+	th := theme.Current()
+	style := th.Style(theme.SidebarText)
+	fileStyle := style.Bold(true)
+	dimStyle := th.Style(theme.PanelDim)
+	onStyle := style.Foreground(th.Foreground(theme.FindBarOptionOn)).Bold(true)
+	matchStyle := th.Style(theme.EditorMatch)
+	selectedStyle := th.Style(theme.SidebarSelected)
+	removedStyle := th.Style(theme.DiffRemoved)
+	addedStyle := th.Style(theme.DiffAdded)
+
+	// Fill background with right border
+	for r := 0; r < fp.Height; r++ {
+		for col := 0; col < fp.Width; col++ {
+			ch := ' '
+			if col == fp.Width-1 {
+				ch = '│'
+			}
+			s.SetContent(fp.X+col, fp.Y+r, ch, nil, style)
+		}
+	}
+	width := fp.Width - 1
+
+	// Inputs
+	if fp.focused && fp.selected >= 0 {
+		s.HideCursor()
+	}
+	fp.drawInput(s, fp.Y, width, fp.query, "Search", fp.field == 0)
+	if fp.replacing {
+		fp.drawInput(s, fp.Y+1, width, fp.replacement, "Replace", fp.field == 1)
+	}
+
+	// Options and summary
+	optY := fp.Y + fp.headerRows() - 1
+	col := fp.X
+	for _, t := range []struct {
+		label string
+		on    bool
+	}{{"[Aa]", fp.opts.CaseSensitive}, {"[W]", fp.opts.WholeWord}, {"[.*]", fp.opts.Regexp}} {
+		st := dimStyle
+		if t.on {
+			st = onStyle
+		}
+		col += put(s, col, optY, fp.X+width-col, t.label, st) + 1
+	}
+	put(s, col, optY, fp.X+width-col, fp.summary(), dimStyle)
+
+	// Results
+	for i := 0; i < fp.listHeight(); i++ {
+		idx := i + fp.scrollY
+		if idx >= len(fp.rows) {
+			break
+		}
+		y := fp.Y + fp.headerRows() + i
+		rw := fp.rows[idx]
+		file := fp.results[rw.file]
+
+		rowStyle, hitStyle, prefixStyle, headerStyle := style, matchStyle, dimStyle, fileStyle
+		if idx == fp.selected {
+			rowStyle, hitStyle = selectedStyle, selectedStyle.Bold(true).Underline(true)
+			prefixStyle, headerStyle = selectedStyle, selectedStyle
+			for col := 0; col < width; col++ {
+				s.SetContent(fp.X+col, y, ' ', nil, rowStyle)
+			}
+		}
+
+		col := fp.X
+		if fp.replacing {
+			checked := fp.fileChecked(rw.file)
+			if rw.match >= 0 {
+				checked = !fp.unchecked[rw]
+			}
+			box := "[ ] "
+			if checked {
+				box = "[x] "
+			}
+			col += put(s, col, y, fp.X+width-col, box, prefixStyle)
+		}
+
+		if rw.match < 0 {
+			arrow := "▼ "
+			if fp.folded[file.Path] {
+				arrow = "▶ "
+			}
+			text := fmt.Sprintf("%s%s (%d)", arrow, fp.RelPath(file.Path), len(file.Matches))
+			put(s, col, y, fp.X+width-col, text, headerStyle)
+			continue
+		}
+
+		m := file.Matches[rw.match]
+		prefix := fmt.Sprintf("  %d: ", m.Line+1)
+		if fp.replacing {
+			prefix = fmt.Sprintf("%d: ", m.Line+1)
+		}
+		col += put(s, col, y, fp.X+width-col, prefix, prefixStyle)
+		if fp.replacing && !fp.unchecked[rw] {
+			with := fp.replacementFor(m)
+			drawMatchLine(s, col, y, fp.X+width-col, m, &with, rowStyle, removedStyle, addedStyle)
+		} else {
+			drawMatchLine(s, col, y, fp.X+width-col, m, nil, rowStyle, hitStyle, hitStyle)
+		}
+	}
+}
+
+// drawInput draws a text field, scrolled so its end stays visible
+func (fp *FindPanel) drawInput(s tcell.Screen, y, width int, text []rune, placeholder string, active bool) {
+	th := theme.Current()
+	inputStyle := th.Style(theme.PanelInput)
+	if !active || fp.selected >= 0 {
+		inputStyle = th.Style(theme.PanelInputInactive)
+	}
+	for col := 0; col < width; col++ {
+		s.SetContent(fp.X+col, y, ' ', nil, inputStyle)
+	}
+	if len(text) == 0 {
+		put(s, fp.X+1, y, width-1, placeholder, inputStyle.Foreground(th.Foreground(theme.PanelDim)))
+	}
+	input := []rune(" " + string(text))
+	if len(input) >= width {
+		input = input[len(input)-width+1:]
+	}
+	if len(text) > 0 {
+		put(s, fp.X, y, width, string(input), inputStyle)
+	}
+	if fp.focused && active && fp.selected < 0 {
+		s.ShowCursor(fp.X+min(len(input), width-1), y)
+	}
+}
+
+// RelPath shows paths relative to the workspace root
+func (fp *FindPanel) RelPath(path string) string {
+	if rel, err := filepath.Rel(fp.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// drawMatchLine draws the line around a match, trimming indentation and
+// starting later when the match would not fit. With a replacement the new
+// text is shown right after the old one.
+func drawMatchLine(s tcell.Screen, x, y, width int, m workspace.LineMatch, with *string, style, hitStyle, withStyle tcell.Style) {
+	if width <= 0 {
+		return
+	}
+	line := []rune(m.Text)
+	start := 0
+	for start < m.Start && (line[start] == ' ' || line[start] == '\t') {
+		start++
+	}
+	if m.End-start > width {
+		start = max(start, m.Start-width/3)
+	}
+
+	col := 0
+	draw := func(r rune, st tcell.Style) {
+		if col >= width {
+			return
+		}
+		if r == '\t' {
+			r = ' '
+		}
+		s.SetContent(x+col, y, r, nil, st)
+		col++
+	}
+	for i := start; i < len(line); i++ {
+		if i == m.End && with != nil {
+			for _, r := range *with {
+				draw(r, withStyle)
+			}
+		}
+		st := style
+		if i >= m.Start && i < m.End {
+			st = hitStyle
+		}
+		draw(line[i], st)
+	}
+	if m.End >= len(line) && with != nil {
+		for _, r := range *with {
+			draw(r, withStyle)
+		}
+	}
+}
+
+// put draws text clipped to width and returns the number of cells used
+func put(s tcell.Screen, x, y, width int, text string, style tcell.Style) int {
+	n := 0
+	for _, r := range text {
+		if n >= width {
+			break
+		}
+		s.SetContent(x+n, y, r, nil, style)
+		n++
+	}
+	return n
+}
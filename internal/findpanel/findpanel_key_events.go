@@ -0,0 +1,164 @@
+package findpanel
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/uditrawat03/bitcode/internal/buffer"
+)
+
+// HandleKey handles typing in the query input and moving through results
+func (fp *FindPanel) HandleKey(ev *tcell.EventKey) {
+	alt := ev.Modifiers()&tcell.ModAlt != 0
+
+	switch {
+	case ev.Key() == tcell.KeyEscape:
+		if fp.onClose != nil {
+			fp.onClose()
+		}
+	case ev.Key() == tcell.KeyTab || ev.Key() == tcell.KeyBacktab:
+		if fp.replacing {
+			fp.field = 1 - fp.field
+			fp.selected = -1
+		}
+	case ev.Key() == tcell.KeyUp:
+		fp.move(-1)
+	case ev.Key() == tcell.KeyDown:
+		fp.move(1)
+	case ev.Key() == tcell.KeyPgUp:
+		fp.move(-fp.listHeight())
+	case ev.Key() == tcell.KeyPgDn:
+		fp.move(fp.listHeight())
+	case ev.Key() == tcell.KeyEnter:
+		if fp.selected < 0 {
+			fp.search()
+		} else {
+			fp.activate(fp.selected)
+		}
+	case ev.Key() == tcell.KeyRight || ev.Key() == tcell.KeyLeft:
+		if fp.selected >= 0 {
+			fp.fold(fp.rows[fp.selected].file, ev.Key() == tcell.KeyLeft)
+		}
+	case ev.Key() == tcell.KeyRune && ev.Rune() == ' ' && fp.replacing && fp.selected >= 0:
+		fp.toggleChecked(fp.selected)
+	case ev.Key() == tcell.KeyBackspace || ev.Key() == tcell.KeyBackspace2:
+		fp.selected = -1
+		input := fp.input()
+		if len(*input) > 0 {
+			*input = (*input)[:len(*input)-1]
+		}
+	case ev.Key() == tcell.KeyRune && !alt:
+		// typing always goes to the inputs
+		fp.selected = -1
+		input := fp.input()
+		*input = append(*input, ev.Rune())
+	}
+}
+
+// Execute runs a search panel command from the keymap and reports whether
+// it is one
+func (fp *FindPanel) Execute(cmd string) bool {
+	switch cmd {
+	case "search.toggleCase":
+		fp.opts.CaseSensitive = !fp.opts.CaseSensitive
+	case "search.toggleWord":
+		fp.opts.WholeWord = !fp.opts.WholeWord
+	case "search.toggleRegexp":
+		fp.opts.Regexp = !fp.opts.Regexp
+	case "search.replaceChecked":
+		fp.replaceChecked()
+		return true
+	default:
+		return false
+	}
+	fp.search()
+	return true
+}
+
+// input returns the text field being edited
+func (fp *FindPanel) input() *[]rune {
+	if fp.replacing && fp.field == 1 {
+		return &fp.replacement
+	}
+	return &fp.query
+}
+
+// search asks for the query to be run again
+func (fp *FindPanel) search() {
+	if fp.onSearch == nil || len(fp.query) == 0 {
+		return
+	}
+	fp.searched, fp.searchedOpts = string(fp.query), fp.opts
+	fp.re, _ = buffer.CompileSearch(fp.searched, fp.searchedOpts)
+	fp.onSearch(fp.searched, fp.searchedOpts)
+}
+
+// toggleChecked flips a match, or every match of a file on its header row
+func (fp *FindPanel) toggleChecked(idx int) {
+	rw := fp.rows[idx]
+	if rw.match >= 0 {
+		fp.unchecked[rw] = !fp.unchecked[rw]
+		return
+	}
+	checked := fp.fileChecked(rw.file)
+	for j := range fp.results[rw.file].Matches {
+		fp.unchecked[row{file: rw.file, match: j}] = checked
+	}
+}
+
+// replaceChecked hands the checked matches over to be replaced
+func (fp *FindPanel) replaceChecked() {
+	if !fp.replacing || fp.searching || fp.onReplace == nil {
+		return
+	}
+	edits, _ := fp.checkedEdits()
+	if len(edits) == 0 {
+		return
+	}
+	fp.onReplace(edits, fp.searched, string(fp.replacement), fp.searchedOpts)
+}
+
+// CheckedCount returns how many matches and files would be replaced
+func (fp *FindPanel) CheckedCount() (matches, files int) {
+	edits, count := fp.checkedEdits()
+	return count, len(edits)
+}
+
+// move the selection by delta rows; moving up past the first row goes back to the input
+func (fp *FindPanel) move(delta int) {
+	fp.selected = max(-1, min(fp.selected+delta, len(fp.rows)-1))
+	if fp.selected < 0 {
+		return
+	}
+	// Keep selected in visible window
+	if fp.selected < fp.scrollY {
+		fp.scrollY = fp.selected
+	} else if fp.selected >= fp.scrollY+fp.listHeight() {
+		fp.scrollY = fp.selected - fp.listHeight() + 1
+	}
+}
+
+// activate opens a match or folds/unfolds a file header
+func (fp *FindPanel) activate(idx int) {
+	rw := fp.rows[idx]
+	file := fp.results[rw.file]
+	if rw.match < 0 {
+		fp.fold(rw.file, !fp.folded[file.Path])
+		return
+	}
+	if fp.onOpen != nil {
+		m := file.Matches[rw.match]
+		fp.onOpen(file.Path, m.Line, m.Start)
+	}
+}
+
+// fold collapses or expands a file and keeps its header selected
+func (fp *FindPanel) fold(file int, folded bool) {
+	fp.folded[fp.results[file].Path] = folded
+	fp.rebuildRows()
+	for i, rw := range fp.rows {
+		if rw.file == file && rw.match < 0 {
+			fp.selected = i
+			fp.move(0)
+			break
+		}
+	}
+}
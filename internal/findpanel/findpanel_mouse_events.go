@@ -0,0 +1,53 @@
+package findpanel
+
+import "github.com/gdamore/tcell/v2"
+
+// HandleMouse handles clicks on results and scrolling
+func (fp *FindPanel) HandleMouse(ev *tcell.EventMouse) {
+	x, y := ev.Position()
+
+	// ignore clicks outside the panel
+	if x < fp.X || x >= fp.X+fp.Width || y < fp.Y || y >= fp.Y+fp.Height {
+		return
+	}
+
+	// scroll wheel
+	switch ev.Buttons() {
+	case tcell.WheelUp:
+		if fp.scrollY > 0 {
+			fp.scrollY--
+		}
+		return
+	case tcell.WheelDown:
+		if fp.scrollY < len(fp.rows)-fp.listHeight() {
+			fp.scrollY++
+		}
+		return
+	}
+
+	if ev.Buttons()&tcell.Button1 == 0 {
+		return
+	}
+	if fp.focusCb != nil {
+		fp.focusCb()
+	}
+
+	// click into an input
+	if y == fp.Y || (fp.replacing && y == fp.Y+1) {
+		fp.selected = -1
+		fp.field = y - fp.Y
+		return
+	}
+	idx := y - fp.Y - fp.headerRows() + fp.scrollY
+	if y-fp.Y < fp.headerRows() || idx >= len(fp.rows) {
+		return
+	}
+	fp.selected = idx
+
+	// the checkbox column toggles instead of opening
+	if fp.replacing && x < fp.X+3 {
+		fp.toggleChecked(idx)
+		return
+	}
+	fp.activate(idx)
+}
@@ -0,0 +1,174 @@
+package fuzzy
+
+import (
+	"unicode"
+)
+
+// scoring, loosely after fzf: every matched rune scores, runes at word
+// boundaries score extra, runs of matches keep the bonus of their first
+// rune and gaps between matches cost a little
+const (
+	scoreMatch     = 16
+	scoreGapStart  = -3
+	scoreGapExtend = -1
+
+	bonusPathStart   = 10 // after / or at the start
+	bonusWordStart   = 8  // after _ - . or space
+	bonusCamel       = 7  // fooBar, foo1
+	bonusConsecutive = 6
+	bonusExactCase   = 1
+)
+
+// Matcher scores many texts against one pattern, reusing its scratch space
+// between calls. It is not safe for concurrent use.
+type Matcher struct {
+	pattern []rune
+
+	text            []rune
+	bonus           []int
+	best, from, run []int // n×m, row i for p[i]
+}
+
+// NewMatcher prepares pattern for matching
+func NewMatcher(pattern string) *Matcher {
+	return &Matcher{pattern: []rune(pattern)}
+}
+
+// Match scores how well pattern matches text as a subsequence, ignoring
+// case. ok is false when a rune of pattern is missing from text. positions
+// are the rune indexes of text that matched.
+func Match(pattern, text string) (score int, positions []int, ok bool) {
+	return NewMatcher(pattern).Match(text)
+}
+
+// Contains is the cheap part of Match: whether the pattern is a subsequence
+// of text at all
+func (mt *Matcher) Contains(text string) bool {
+	i := 0
+	for _, r := range text {
+		if i == len(mt.pattern) {
+			break
+		}
+		if equalFold(mt.pattern[i], r) {
+			i++
+		}
+	}
+	return i == len(mt.pattern)
+}
+
+// Match is like the package Match for the matcher's pattern
+func (mt *Matcher) Match(text string) (score int, positions []int, ok bool) {
+	p := mt.pattern
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+	if !mt.Contains(text) {
+		return 0, nil, false
+	}
+
+	mt.text = mt.text[:0]
+	for _, r := range text {
+		mt.text = append(mt.text, r)
+	}
+	t := mt.text
+	n, m := len(p), len(t)
+	mt.bonus = grow(mt.bonus, m)
+	bonus := mt.bonus
+	for j := range t {
+		bonus[j] = boundary(t, j)
+	}
+
+	// best[i*m+j] is the best score with p[i] matched at t[j], from where
+	// p[i-1] was matched and run the bonus carried by the run ending at t[j]
+	const none = -1 << 30
+	mt.best, mt.from, mt.run = grow(mt.best, n*m), grow(mt.from, n*m), grow(mt.run, n*m)
+	best, from, run := mt.best, mt.from, mt.run
+
+	for i := 0; i < n; i++ {
+		row, prev := i*m, (i-1)*m
+		gap, gapFrom := none, -1 // best score ending two or more runes back
+		for j := 0; j < m; j++ {
+			best[row+j] = none
+			if i > 0 && j >= 2 && best[prev+j-2] != none {
+				if open := best[prev+j-2] + scoreGapStart; open > gap+scoreGapExtend {
+					gap, gapFrom = open, j-2
+				} else {
+					gap += scoreGapExtend
+				}
+			} else if gap != none {
+				gap += scoreGapExtend
+			}
+			if !equalFold(p[i], t[j]) {
+				continue
+			}
+
+			s := scoreMatch + bonus[j]
+			if p[i] == t[j] {
+				s += bonusExactCase
+			}
+			if i == 0 {
+				best[row+j], from[row+j], run[row+j] = s, -1, bonus[j]
+				continue
+			}
+
+			if j > 0 && best[prev+j-1] != none {
+				carried := max(run[prev+j-1], bonusConsecutive, bonus[j])
+				best[row+j] = best[prev+j-1] + scoreMatch + carried
+				if p[i] == t[j] {
+					best[row+j] += bonusExactCase
+				}
+				from[row+j], run[row+j] = j-1, carried
+			}
+			if gap != none && gap+s > best[row+j] {
+				best[row+j], from[row+j], run[row+j] = gap+s, gapFrom, bonus[j]
+			}
+		}
+	}
+
+	last := (n - 1) * m
+	end := -1
+	for j := 0; j < m; j++ {
+		if best[last+j] != none && (end < 0 || best[last+j] > best[last+end]) {
+			end = j
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	positions = make([]int, n)
+	for i, j := n-1, end; i >= 0; i-- {
+		positions[i] = j
+		j = from[i*m+j]
+	}
+	return best[last+end], positions, true
+}
+
+// grow returns s resized to n, reallocating only when it is too small
+func grow(s []int, n int) []int {
+	if cap(s) < n {
+		return make([]int, n)
+	}
+	return s[:n]
+}
+
+func equalFold(a, b rune) bool {
+	return a == b || unicode.ToLower(a) == unicode.ToLower(b)
+}
+
+// boundary is the bonus for a match starting at t[j]
+func boundary(t []rune, j int) int {
+	if j == 0 {
+		return bonusPathStart
+	}
+	prev, cur := t[j-1], t[j]
+	switch {
+	case prev == '/' || prev == '\\':
+		return bonusPathStart
+	case prev == '_' || prev == '-' || prev == '.' || prev == ' ' || prev == ':':
+		return bonusWordStart
+	case unicode.IsLower(prev) && unicode.IsUpper(cur), !unicode.IsDigit(prev) && unicode.IsDigit(cur):
+		return bonusCamel
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package fuzzy
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMatchPositions(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		positions     []int
+		ok            bool
+	}{
+		{"", "anything", nil, true},
+		{"abc", "abc", []int{0, 1, 2}, true},
+		{"ABC", "abc", []int{0, 1, 2}, true},
+		{"abc", "ab", nil, false},
+		{"ba", "abc", nil, false},
+		// word starts beat the first occurrence
+		{"fb", "foo_bar", []int{0, 4}, true},
+		{"mg", "image/main.go", []int{6, 11}, true},
+		{"sm", "screenManager", []int{0, 6}, true},
+		// a consecutive run beats scattered runes
+		{"main", "m_a_i_n/main.go", []int{8, 9, 10, 11}, true},
+		{"ñd", "Ñandú", []int{0, 3}, true},
+		// no folding of accents
+		{"ndu", "Ñandú", nil, false},
+	}
+	for _, tt := range tests {
+		_, positions, ok := Match(tt.pattern, tt.text)
+		if ok != tt.ok || !reflect.DeepEqual(positions, tt.positions) {
+			t.Errorf("Match(%q, %q) = %v, %v, want %v, %v", tt.pattern, tt.text, positions, ok, tt.positions, tt.ok)
+		}
+	}
+}
+
+func TestMatchOrder(t *testing.T) {
+	// each text should score higher than the next one
+	tests := []struct {
+		pattern string
+		texts   []string
+	}{
+		// path starts, then a run inside a word, then scattered runes
+		{"main", []string{"main.go", "m/a/i/n.go", "domain.go", "mxaxixn"}},
+		{"fig", []string{"f/i/g.go", "config.go", "xfxixgx"}},
+		// word boundaries, then camel case
+		{"bm", []string{"buffer_manager", "bufferManager", "subsume"}},
+		{"ui", []string{"internal/ui/screen.go", "internal/buffer/builder.go"}},
+		// consecutive runes, then a boundary, then growing gaps
+		{"ab", []string{"ab", "a_b", "axb", "axxxxxb"}},
+		// exact case wins a tie
+		{"Save", []string{"Save", "save"}},
+	}
+	for _, tt := range tests {
+		prev := 0
+		for i, text := range tt.texts {
+			score, _, ok := Match(tt.pattern, text)
+			if !ok {
+				t.Errorf("Match(%q, %q) found nothing", tt.pattern, text)
+				break
+			}
+			if i > 0 && score >= prev {
+				t.Errorf("Match(%q): %q scores %d, not below %q with %d", tt.pattern, text, score, tt.texts[i-1], prev)
+			}
+			prev = score
+		}
+	}
+}
+
+func TestMatcherReuse(t *testing.T) {
+	// the scratch space of a long text must not leak into a shorter one
+	mt := NewMatcher("ab")
+	texts := []string{"a/b/c/d/e/f/g/h/ab", "ab", "xaxb", "b a", "a_b"}
+	for range 2 {
+		for _, text := range texts {
+			score, positions, ok := mt.Match(text)
+			wantScore, wantPositions, wantOK := Match("ab", text)
+			if score != wantScore || !reflect.DeepEqual(positions, wantPositions) || ok != wantOK {
+				t.Errorf("reused Match(%q) = %d, %v, %v, want %d, %v, %v", text, score, positions, ok, wantScore, wantPositions, wantOK)
+			}
+		}
+	}
+}
+
+func BenchmarkMatcher(b *testing.B) {
+	paths := make([]string, 1000)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("internal/pkg%d/sub/dir/file_%d_name.go", i%37, i)
+	}
+	mt := NewMatcher("pkfile")
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, p := range paths {
+			mt.Match(p)
+		}
+	}
+}
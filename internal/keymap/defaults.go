@@ -0,0 +1,83 @@
+package keymap
+
+// defaults are the built-in bindings; the [keys] tables in the config
+// add to and override them
+var defaults = []struct {
+	ctx     Context
+	keys    string
+	command string
+}{
+	{Global, "ctrl+n", "file.new"},
+	{Global, "ctrl+p", "file.quickOpen"},
+	{Global, "alt+r", "file.reopenWithEncoding"},
+	{Global, "alt+s", "file.saveWithEncoding"},
+	{Global, "ctrl+k s", "file.saveAll"},
+	{Global, "ctrl+shift+f", "search.findInFiles"},
+	{Global, "alt+f", "search.findInFiles"},
+	// plain Ctrl+H is Backspace in most terminals
+	{Global, "ctrl+shift+h", "search.replaceInFiles"},
+	{Global, "alt+h", "search.replaceInFiles"},
+	{Global, "alt+u", "search.undoReplace"},
+	{Global, "alt+t", "view.changeTheme"},
+	{Global, "ctrl+b", "view.toggleSidebar"},
+	{Global, "ctrl+shift+p", "palette.open"},
+	{Global, "f1", "palette.open"},
+	{Global, "ctrl+k ctrl+s", "keys.list"},
+	{Global, "ctrl+tab", "tabs.next"},
+	{Global, "ctrl+pgdn", "tabs.next"},
+	{Global, "ctrl+shift+tab", "tabs.previous"},
+	{Global, "ctrl+pgup", "tabs.previous"},
+	{Global, "ctrl+w", "tabs.close"},
+	{Global, "ctrl+k ctrl+w", "tabs.closeAll"},
+	{Global, "alt+\\", "panes.splitRight"},
+	{Global, "alt+-", "panes.splitDown"},
+	{Global, "ctrl+k x", "panes.close"},
+	{Global, "alt+o", "panes.focusNext"},
+	{Global, "alt+left", "panes.focusLeft"},
+	{Global, "alt+right", "panes.focusRight"},
+	{Global, "alt+up", "panes.focusUp"},
+	{Global, "alt+down", "panes.focusDown"},
+
+	{Editor, "ctrl+s", "editor.save"},
+	{Editor, "ctrl+f", "editor.find"},
+	{Editor, "ctrl+h", "editor.replace"},
+	{Editor, "ctrl+z", "editor.undo"},
+	{Editor, "ctrl+y", "editor.redo"},
+	{Editor, "ctrl+x", "editor.cut"},
+	{Editor, "ctrl+c", "editor.copy"},
+	{Editor, "ctrl+v", "editor.paste"},
+	{Editor, "ctrl+a", "editor.selectAll"},
+	{Editor, "ctrl+e", "editor.toggleLineEnding"},
+	{Editor, "alt+z", "editor.toggleWrap"},
+
+	{Find, "enter", "find.accept"},
+	{Find, "shift+enter", "find.previous"},
+	{Find, "down", "find.next"},
+	{Find, "up", "find.previous"},
+	{Find, "f3", "find.next"},
+	{Find, "shift+f3", "find.previous"},
+	{Find, "alt+c", "find.toggleCase"},
+	{Find, "alt+w", "find.toggleWord"},
+	{Find, "alt+r", "find.toggleRegexp"},
+	{Find, "alt+l", "find.toggleSelection"},
+	{Find, "alt+a", "find.replaceAll"},
+
+	{Sidebar, "delete", "sidebar.delete"},
+	{Sidebar, "f2", "sidebar.rename"},
+
+	{Search, "alt+c", "search.toggleCase"},
+	{Search, "alt+w", "search.toggleWord"},
+	{Search, "alt+r", "search.toggleRegexp"},
+	{Search, "alt+a", "search.replaceChecked"},
+}
+
+// Defaults returns a keymap with the built-in bindings
+func Defaults() *Keymap {
+	km := New()
+	for _, d := range defaults {
+		if err := km.Bind(d.ctx, d.keys, d.command); err != nil {
+			panic("keymap: bad default binding: " + err.Error())
+		}
+	}
+	return km
+}
@@ -0,0 +1,194 @@
+package keymap
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Key is a single key press with its modifiers. Printable keys are stored
+// as runes, everything else as a tcell key code.
+type Key struct {
+	Code tcell.Key
+	Rune rune
+	Mod  tcell.ModMask
+}
+
+var keyNames = map[string]tcell.Key{
+	"enter":     tcell.KeyEnter,
+	"tab":       tcell.KeyTab,
+	"backtab":   tcell.KeyBacktab,
+	"esc":       tcell.KeyEscape,
+	"backspace": tcell.KeyBackspace2,
+	"delete":    tcell.KeyDelete,
+	"insert":    tcell.KeyInsert,
+	"up":        tcell.KeyUp,
+	"down":      tcell.KeyDown,
+	"left":      tcell.KeyLeft,
+	"right":     tcell.KeyRight,
+	"home":      tcell.KeyHome,
+	"end":       tcell.KeyEnd,
+	"pgup":      tcell.KeyPgUp,
+	"pgdn":      tcell.KeyPgDn,
+}
+
+// other accepted spellings
+var keyAliases = map[string]string{
+	"escape":   "esc",
+	"del":      "delete",
+	"pageup":   "pgup",
+	"pagedown": "pgdn",
+	"return":   "enter",
+}
+
+func init() {
+	for i := 1; i <= 12; i++ {
+		keyNames[fmt.Sprintf("f%d", i)] = tcell.KeyF1 + tcell.Key(i-1)
+	}
+}
+
+var modNames = []struct {
+	name string
+	mod  tcell.ModMask
+}{
+	{"ctrl", tcell.ModCtrl},
+	{"alt", tcell.ModAlt},
+	{"shift", tcell.ModShift},
+	{"meta", tcell.ModMeta},
+}
+
+// ParseKey reads a key such as "ctrl+shift+p", "alt+f", "f3" or "enter"
+func ParseKey(s string) (Key, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), "+")
+	// "ctrl++" binds the plus key
+	if len(parts) > 1 && parts[len(parts)-1] == "" && parts[len(parts)-2] == "" {
+		parts = append(parts[:len(parts)-2], "+")
+	}
+
+	var k Key
+	for _, part := range parts[:len(parts)-1] {
+		found := false
+		for _, m := range modNames {
+			if part == m.name {
+				k.Mod |= m.mod
+				found = true
+			}
+		}
+		if !found {
+			return Key{}, fmt.Errorf("unknown modifier %q in %q", part, s)
+		}
+	}
+
+	name := parts[len(parts)-1]
+	if alias, ok := keyAliases[name]; ok {
+		name = alias
+	}
+	switch r := []rune(name); {
+	case name == "":
+		return Key{}, fmt.Errorf("missing key in %q", s)
+	case name == "space":
+		k.Rune = ' '
+	case keyNames[name] != 0:
+		k.Code = keyNames[name]
+	case len(r) == 1 && k.Mod&tcell.ModCtrl != 0 && r[0] >= 'a' && r[0] <= 'z':
+		// terminals send Ctrl+letter as a control code
+		k.Code = tcell.KeyCtrlA + tcell.Key(r[0]-'a')
+	case len(r) == 1 && unicode.IsPrint(r[0]):
+		k.Rune = r[0]
+	default:
+		return Key{}, fmt.Errorf("unknown key %q in %q", name, s)
+	}
+	return k.normalize(), nil
+}
+
+// ParseSequence reads space separated keys, e.g. "ctrl+k ctrl+c"
+func ParseSequence(s string) ([]Key, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty key sequence")
+	}
+	seq := make([]Key, len(fields))
+	for i, f := range fields {
+		k, err := ParseKey(f)
+		if err != nil {
+			return nil, err
+		}
+		seq[i] = k
+	}
+	return seq, nil
+}
+
+// FromEvent turns a key event into a Key comparable with parsed ones
+func FromEvent(ev *tcell.EventKey) Key {
+	k := Key{Code: ev.Key(), Mod: ev.Modifiers()}
+	switch {
+	case k.Code == tcell.KeyRune:
+		k.Code = 0
+		k.Rune = ev.Rune()
+	case k.Code == tcell.KeyBackspace && k.Mod&tcell.ModCtrl == 0:
+		// KeyBackspace is also Ctrl+H; without Ctrl it's the backspace key
+		k.Code = tcell.KeyBackspace2
+	}
+	return k.normalize()
+}
+
+// normalize keeps shift in the rune for plain typing and in the modifier
+// when combined with Ctrl or Alt
+func (k Key) normalize() Key {
+	if k.Code != 0 {
+		return k
+	}
+	if k.Mod&(tcell.ModCtrl|tcell.ModAlt|tcell.ModMeta) == 0 {
+		k.Mod &^= tcell.ModShift
+		return k
+	}
+	if unicode.IsUpper(k.Rune) {
+		k.Rune = unicode.ToLower(k.Rune)
+		k.Mod |= tcell.ModShift
+	}
+	if k.Mod&tcell.ModCtrl != 0 && k.Rune >= 'a' && k.Rune <= 'z' {
+		k.Code = tcell.KeyCtrlA + tcell.Key(k.Rune-'a')
+		k.Rune = 0
+	}
+	return k
+}
+
+func (k Key) String() string {
+	var sb strings.Builder
+	for _, m := range modNames {
+		if k.Mod&m.mod != 0 {
+			sb.WriteString(m.name + "+")
+		}
+	}
+	switch {
+	case k.Code >= tcell.KeyCtrlA && k.Code <= tcell.KeyCtrlZ && k.Mod&tcell.ModCtrl != 0:
+		sb.WriteRune(rune('a' + k.Code - tcell.KeyCtrlA))
+	case k.Code != 0:
+		name := ""
+		for n, c := range keyNames {
+			if c == k.Code {
+				name = n
+			}
+		}
+		if name == "" {
+			name = strings.ToLower(tcell.KeyNames[k.Code])
+		}
+		sb.WriteString(name)
+	case k.Rune == ' ':
+		sb.WriteString("space")
+	default:
+		sb.WriteRune(k.Rune)
+	}
+	return sb.String()
+}
+
+// FormatSequence writes keys the way ParseSequence reads them
+func FormatSequence(seq []Key) string {
+	names := make([]string, len(seq))
+	for i, k := range seq {
+		names[i] = k.String()
+	}
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,161 @@
+package keymap
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Key
+	}{
+		{"a", Key{Rune: 'a'}},
+		{"A", Key{Rune: 'a'}},
+		{"space", Key{Rune: ' '}},
+		{"enter", Key{Code: tcell.KeyEnter}},
+		{"Return", Key{Code: tcell.KeyEnter}},
+		{"shift+enter", Key{Code: tcell.KeyEnter, Mod: tcell.ModShift}},
+		{"f3", Key{Code: tcell.KeyF3}},
+		{"shift+f12", Key{Code: tcell.KeyF12, Mod: tcell.ModShift}},
+		{"pagedown", Key{Code: tcell.KeyPgDn}},
+		// Ctrl+letter is the control code terminals send
+		{"ctrl+a", Key{Code: tcell.KeyCtrlA, Mod: tcell.ModCtrl}},
+		{"ctrl+z", Key{Code: tcell.KeyCtrlZ, Mod: tcell.ModCtrl}},
+		{"Ctrl+Shift+P", Key{Code: tcell.KeyCtrlP, Mod: tcell.ModCtrl | tcell.ModShift}},
+		// Ctrl+H is a letter, the backspace key has its own name
+		{"ctrl+h", Key{Code: tcell.KeyCtrlH, Mod: tcell.ModCtrl}},
+		{"backspace", Key{Code: tcell.KeyBackspace2}},
+		// shift is dropped from plain runes and kept with Alt
+		{"shift+a", Key{Rune: 'a'}},
+		{"alt+f", Key{Rune: 'f', Mod: tcell.ModAlt}},
+		// names are case insensitive, shift has to be spelled out
+		{"alt+F", Key{Rune: 'f', Mod: tcell.ModAlt}},
+		{"alt+shift+f", Key{Rune: 'f', Mod: tcell.ModAlt | tcell.ModShift}},
+		{"ctrl++", Key{Rune: '+', Mod: tcell.ModCtrl}},
+		{"+", Key{Rune: '+'}},
+		{"alt+\\", Key{Rune: '\\', Mod: tcell.ModAlt}},
+		{" alt+- ", Key{Rune: '-', Mod: tcell.ModAlt}},
+	}
+	for _, tt := range tests {
+		got, err := ParseKey(tt.in)
+		if err != nil {
+			t.Errorf("ParseKey(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseKey(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseKeyErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", `missing key in ""`},
+		{"ctrl+", `missing key in "ctrl+"`},
+		{"hyper+a", `unknown modifier "hyper" in "hyper+a"`},
+		{"ctrl+f13", `unknown key "f13" in "ctrl+f13"`},
+		{"ab", `unknown key "ab" in "ab"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseKey(tt.in)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("ParseKey(%q) error = %v, want %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestFromEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   *tcell.EventKey
+		want string
+	}{
+		{"rune", tcell.NewEventKey(tcell.KeyRune, 'x', 0), "x"},
+		{"shifted rune", tcell.NewEventKey(tcell.KeyRune, '%', tcell.ModShift), "%"},
+		{"ctrl code", tcell.NewEventKey(tcell.KeyCtrlS, 0, tcell.ModCtrl), "ctrl+s"},
+		{"ctrl rune", tcell.NewEventKey(tcell.KeyRune, 's', tcell.ModCtrl), "ctrl+s"},
+		{"ctrl shift rune", tcell.NewEventKey(tcell.KeyRune, 'P', tcell.ModCtrl|tcell.ModShift), "ctrl+shift+p"},
+		{"ctrl shift code", tcell.NewEventKey(tcell.KeyCtrlP, 0, tcell.ModCtrl|tcell.ModShift), "ctrl+shift+p"},
+		{"alt upper", tcell.NewEventKey(tcell.KeyRune, 'F', tcell.ModAlt), "alt+shift+f"},
+		{"ctrl plus", tcell.NewEventKey(tcell.KeyRune, '+', tcell.ModCtrl), "ctrl++"},
+		{"backspace", tcell.NewEventKey(tcell.KeyBackspace, 0, 0), "backspace"},
+		{"backspace2", tcell.NewEventKey(tcell.KeyBackspace2, 0, 0), "backspace"},
+		{"ctrl+h", tcell.NewEventKey(tcell.KeyBackspace, 0, tcell.ModCtrl), "ctrl+h"},
+		{"shift enter", tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModShift), "shift+enter"},
+		{"shift f3", tcell.NewEventKey(tcell.KeyF3, 0, tcell.ModShift), "shift+f3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := ParseKey(tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := FromEvent(tt.ev); got != want {
+				t.Fatalf("FromEvent = %+v (%s), want %+v (%s)", got, got, want, want)
+			}
+		})
+	}
+}
+
+func TestKeyString(t *testing.T) {
+	// String writes what ParseKey reads back
+	for _, s := range []string{"a", "space", "enter", "shift+enter", "f3", "ctrl+a", "ctrl+shift+p", "ctrl+h", "backspace", "alt+shift+f", "ctrl++", "alt+\\"} {
+		k, err := ParseKey(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := k.String(); got != s {
+			t.Errorf("ParseKey(%q).String() = %q", s, got)
+		}
+	}
+}
+
+func TestFeed(t *testing.T) {
+	km := New()
+	for _, b := range []struct {
+		ctx  Context
+		keys string
+		cmd  string
+	}{
+		{Global, "alt+r", "file.reopen"},
+		{Find, "alt+r", "find.toggleRegexp"},
+		{Global, "ctrl+k ctrl+s", "keys.list"},
+		{Global, "alt+f", "search.find"},
+	} {
+		if err := km.Bind(b.ctx, b.keys, b.cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	key := func(k tcell.Key, r rune, mod tcell.ModMask) *tcell.EventKey { return tcell.NewEventKey(k, r, mod) }
+
+	tests := []struct {
+		name   string
+		ctxs   []Context
+		ev     *tcell.EventKey
+		cmd    string
+		result Result
+	}{
+		{"global", []Context{Editor, Global}, key(tcell.KeyRune, 'r', tcell.ModAlt), "file.reopen", Matched},
+		{"context first", []Context{Find, Global}, key(tcell.KeyRune, 'r', tcell.ModAlt), "find.toggleRegexp", Matched},
+		{"shift folds", []Context{Global}, key(tcell.KeyRune, 'F', tcell.ModAlt), "search.find", Matched},
+		{"unbound", []Context{Global}, key(tcell.KeyRune, 'x', 0), "", NotBound},
+		{"chord start", []Context{Global}, key(tcell.KeyCtrlK, 0, tcell.ModCtrl), "", Pending},
+		{"chord end", []Context{Global}, key(tcell.KeyCtrlS, 0, tcell.ModCtrl), "keys.list", Matched},
+		{"chord start again", []Context{Global}, key(tcell.KeyCtrlK, 0, tcell.ModCtrl), "", Pending},
+		{"chord miss", []Context{Global}, key(tcell.KeyRune, 'q', 0), "", Aborted},
+	}
+	for _, tt := range tests {
+		cmd, result := km.Feed(tt.ev, tt.ctxs...)
+		if cmd != tt.cmd || result != tt.result {
+			t.Errorf("%s: Feed = %q, %d, want %q, %d", tt.name, cmd, result, tt.cmd, tt.result)
+		}
+	}
+	if len(km.Pending()) != 0 {
+		t.Errorf("Pending() = %v after an aborted chord", km.Pending())
+	}
+}
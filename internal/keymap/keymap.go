@@ -0,0 +1,189 @@
+package keymap
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Context is where a binding applies. Global bindings work everywhere
+// unless the focused context binds the same keys.
+type Context string
+
+const (
+	Global  Context = "global"
+	Editor  Context = "editor"
+	Sidebar Context = "sidebar"
+	Search  Context = "search"
+	Dialog  Context = "dialog"
+	// Find is the editor's find bar, it replaces Editor while open
+	Find Context = "find"
+)
+
+var contexts = []Context{Global, Editor, Find, Sidebar, Search, Dialog}
+
+// ParseContext checks a context name from the config
+func ParseContext(name string) (Context, error) {
+	for _, c := range contexts {
+		if string(c) == name {
+			return c, nil
+		}
+	}
+	names := make([]string, len(contexts))
+	for i, c := range contexts {
+		names[i] = string(c)
+	}
+	return "", fmt.Errorf("unknown key context %q, have %s", name, strings.Join(names, ", "))
+}
+
+// Binding maps a key sequence to a command in a context
+type Binding struct {
+	Context Context
+	Keys    []Key
+	Command string
+}
+
+func (b Binding) String() string {
+	return fmt.Sprintf("%s: %s -> %s", b.Context, FormatSequence(b.Keys), b.Command)
+}
+
+// Result says what Feed did with a key
+type Result int
+
+const (
+	// NotBound means the key should go to the focused component
+	NotBound Result = iota
+	// Matched means a command was found
+	Matched
+	// Pending means the key started a chord, more keys are expected
+	Pending
+	// Aborted means a started chord didn't match anything
+	Aborted
+)
+
+// Keymap resolves key presses to command names
+type Keymap struct {
+	bindings []Binding
+	pending  []Key
+}
+
+func New() *Keymap {
+	return &Keymap{}
+}
+
+// Bind maps keys to command in ctx, replacing what ctx had bound to the
+// same keys. An empty command removes the binding.
+func (km *Keymap) Bind(ctx Context, keys string, command string) error {
+	seq, err := ParseSequence(keys)
+	if err != nil {
+		return err
+	}
+	km.bindings = slices.DeleteFunc(km.bindings, func(b Binding) bool {
+		return b.Context == ctx && slices.Equal(b.Keys, seq)
+	})
+	if command != "" {
+		km.bindings = append(km.bindings, Binding{Context: ctx, Keys: seq, Command: command})
+	}
+	return nil
+}
+
+// Bindings lists the bindings sorted by context, then command
+func (km *Keymap) Bindings() []Binding {
+	list := slices.Clone(km.bindings)
+	slices.SortStableFunc(list, func(a, b Binding) int {
+		if c := slices.Index(contexts, a.Context) - slices.Index(contexts, b.Context); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Command, b.Command)
+	})
+	return list
+}
+
+// Lookup returns the keys bound to command, for showing them next to it
+func (km *Keymap) Lookup(command string) [][]Key {
+	var seqs [][]Key
+	for _, b := range km.bindings {
+		if b.Command == command {
+			seqs = append(seqs, b.Keys)
+		}
+	}
+	return seqs
+}
+
+// Conflicts reports bindings that can never run: a chord whose first keys
+// are bound on their own in the same context, or in global from a context
+// the chord can't be typed in
+func (km *Keymap) Conflicts() []string {
+	var found []string
+	for _, long := range km.bindings {
+		for _, short := range km.bindings {
+			if len(short.Keys) >= len(long.Keys) || !slices.Equal(short.Keys, long.Keys[:len(short.Keys)]) {
+				continue
+			}
+			if short.Context == long.Context || short.Context != Global && long.Context == Global {
+				found = append(found, fmt.Sprintf("%s hides %s", short, long))
+			}
+		}
+	}
+	return found
+}
+
+// Pending returns the keys of a chord in progress
+func (km *Keymap) Pending() []Key {
+	return km.pending
+}
+
+// Reset drops a chord in progress
+func (km *Keymap) Reset() {
+	km.pending = nil
+}
+
+// Feed resolves a key press. ctxs lists the active contexts, most specific
+// first, usually ending with Global.
+func (km *Keymap) Feed(ev *tcell.EventKey, ctxs ...Context) (string, Result) {
+	seq := append(slices.Clone(km.pending), FromEvent(ev))
+	cmd, result := km.resolve(seq, ctxs)
+	if result == NotBound {
+		// Alt+F also matches Alt+Shift+F unless that is bound itself
+		last := seq[len(seq)-1]
+		if last.Code == 0 && last.Mod&tcell.ModShift != 0 {
+			last.Mod &^= tcell.ModShift
+			seq[len(seq)-1] = last
+			cmd, result = km.resolve(seq, ctxs)
+		}
+	}
+
+	switch result {
+	case Pending:
+		km.pending = seq
+	case NotBound:
+		if len(km.pending) > 0 {
+			result = Aborted
+		}
+		km.pending = nil
+	default:
+		km.pending = nil
+	}
+	return cmd, result
+}
+
+func (km *Keymap) resolve(seq []Key, ctxs []Context) (string, Result) {
+	for _, ctx := range ctxs {
+		prefix := false
+		for _, b := range km.bindings {
+			if b.Context != ctx || len(b.Keys) < len(seq) || !slices.Equal(b.Keys[:len(seq)], seq) {
+				continue
+			}
+			if len(b.Keys) == len(seq) {
+				return b.Command, Matched
+			}
+			prefix = true
+		}
+		if prefix {
+			return "", Pending
+		}
+	}
+	return "", NotBound
+}
@@ -13,10 +13,14 @@ type UILayout struct {
 	StatusBarHeight int
 	TopBarHeight    int
 	EditorPadding   EditorPadding
-	MinWidth        int
-	MinHeight       int
+	// below these sizes the layout gets compact, see ResponsiveLayout
+	MinWidth  int
+	MinHeight int
 }
 
+// narrower than this the sidebar is hidden
+const hideSidebarWidth = 50
+
 func NewUILayout() *UILayout {
 	return &UILayout{
 		SidebarWidth:    20,
@@ -29,24 +33,24 @@ func NewUILayout() *UILayout {
 			Left:   2,
 			Right:  2,
 		},
-		MinWidth:  100,
-		MinHeight: 30,
+		MinWidth:  80,
+		MinHeight: 20,
 	}
 }
 
+// CalculateDimensions returns the usable screen size
 func (l *UILayout) CalculateDimensions(screenWidth, screenHeight int) (int, int) {
-	width := screenWidth
-	height := screenHeight
-
-	if width < l.MinWidth {
-		width = l.MinWidth
-	}
+	return max(0, screenWidth), max(0, screenHeight)
+}
 
-	if height < l.MinHeight {
-		height = l.MinHeight
+// FitSidebar keeps the sidebar to at most half the screen and hides it on
+// very narrow screens
+func (l *UILayout) FitSidebar(screenWidth int) {
+	if screenWidth < hideSidebarWidth {
+		l.SidebarWidth = 0
+		return
 	}
-
-	return width, height
+	l.SidebarWidth = min(l.SidebarWidth, screenWidth/2)
 }
 
 func (l *UILayout) GetEditorArea(screenWidth, screenHeight int) (int, int, int, int) {
@@ -54,8 +58,8 @@ func (l *UILayout) GetEditorArea(screenWidth, screenHeight int) (int, int, int,
 
 	editorX := l.SidebarWidth + l.EditorPadding.Left
 	editorY := l.TopBarHeight + l.EditorPadding.Top
-	editorWidth := width - l.SidebarWidth - l.EditorPadding.Left - l.EditorPadding.Right
-	editorHeight := height - l.TopBarHeight - l.StatusBarHeight - l.EditorPadding.Top - l.EditorPadding.Bottom
+	editorWidth := max(0, width-l.SidebarWidth-l.EditorPadding.Left-l.EditorPadding.Right)
+	editorHeight := max(0, height-l.TopBarHeight-l.StatusBarHeight-l.EditorPadding.Top-l.EditorPadding.Bottom)
 
 	return editorX, editorY, editorWidth, editorHeight
 }
@@ -66,14 +70,14 @@ func (l *UILayout) GetSidebarArea(screenWidth, screenHeight int) (int, int, int,
 	sidebarX := 0
 	sidebarY := l.TopBarHeight
 	sidebarWidth := l.SidebarWidth
-	sidebarHeight := height - l.TopBarHeight - l.StatusBarHeight
+	sidebarHeight := max(0, height-l.TopBarHeight-l.StatusBarHeight)
 
 	return sidebarX, sidebarY, sidebarWidth, sidebarHeight
 }
 
 func (l *UILayout) GetStatusBarArea(screenWidth, screenHeight int) (int, int, int, int) {
 	width, height := l.CalculateDimensions(screenWidth, screenHeight)
-	return 0, height - l.StatusBarHeight, width, l.StatusBarHeight
+	return 0, max(l.TopBarHeight, height-l.StatusBarHeight), width, l.StatusBarHeight
 }
 
 func (l *UILayout) GetTopBarArea(screenWidth, screenHeight int) (int, int, int, int) {
@@ -85,13 +89,20 @@ func (l *UILayout) GetEditorPadding() EditorPadding {
 	return l.EditorPadding
 }
 
+// ResponsiveLayout fits the layout to the screen: small screens get less
+// padding and a narrower sidebar, very narrow ones none at all
 func ResponsiveLayout(screenWidth, screenHeight int) *UILayout {
 	layout := NewUILayout()
 	width, height := layout.CalculateDimensions(screenWidth, screenHeight)
 
-	if width < layout.MinWidth || height < layout.MinHeight {
-		return layout
+	if width < layout.MinWidth {
+		layout.SidebarWidth = max(12, width/5)
+		layout.EditorPadding.Left, layout.EditorPadding.Right = 1, 0
+	}
+	if height < layout.MinHeight {
+		layout.EditorPadding.Top, layout.EditorPadding.Bottom = 0, 0
 	}
+	layout.FitSidebar(width)
 
 	return layout
 }
@@ -1,7 +1,15 @@
 package layout
 
+import "cmp"
+
 type LayoutManager struct {
 	layout *UILayout
+
+	// configured sidebar width, 0 keeps the default
+	sidebarWidth int
+	// width the sidebar was dragged to, wins over the configured one
+	draggedWidth  int
+	sidebarHidden bool
 }
 
 func CreateLayoutManager() *LayoutManager {
@@ -12,6 +20,38 @@ func CreateLayoutManager() *LayoutManager {
 
 func (lm *LayoutManager) UpdateLayout(width, height int) {
 	lm.layout = ResponsiveLayout(width, height)
+	if w := cmp.Or(lm.draggedWidth, lm.sidebarWidth); w > 0 {
+		lm.layout.SidebarWidth = w
+		lm.layout.FitSidebar(width)
+	}
+	if lm.sidebarHidden {
+		lm.layout.SidebarWidth = 0
+	}
+}
+
+// SetSidebarVisible shows or hides the sidebar, the editor takes its room
+func (lm *LayoutManager) SetSidebarVisible(visible bool) {
+	lm.sidebarHidden = !visible
+}
+
+// SidebarVisible reports whether the sidebar is shown; it still takes no
+// room when the screen is too narrow for it
+func (lm *LayoutManager) SidebarVisible() bool {
+	return !lm.sidebarHidden
+}
+
+// SetSidebarWidth overrides the default sidebar width, 0 goes back to it.
+// A new width replaces one the sidebar was dragged to.
+func (lm *LayoutManager) SetSidebarWidth(width int) {
+	if width != lm.sidebarWidth {
+		lm.draggedWidth = 0
+	}
+	lm.sidebarWidth = width
+}
+
+// DragSidebarWidth sets the width the sidebar border was dragged to
+func (lm *LayoutManager) DragSidebarWidth(width int) {
+	lm.draggedWidth = width
 }
 
 // GetLayout returns the current layout
@@ -0,0 +1,308 @@
+package panes
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/uditrawat03/bitcode/internal/editor"
+	"github.com/uditrawat03/bitcode/internal/theme"
+)
+
+// Smallest pane a split or a drag may leave
+const (
+	minWidth  = 12
+	minHeight = 3
+)
+
+// Direction is how a split arranges its two sides
+type Direction int
+
+const (
+	// Vertical puts the panes side by side with a divider column between
+	Vertical Direction = iota
+	// Horizontal stacks them with a divider row between
+	Horizontal
+)
+
+// node is a pane when it has an editor, otherwise a split in two
+type node struct {
+	editor        *editor.Editor
+	dir           Direction
+	ratio         float64 // share of the first side
+	first, second *node
+	parent        *node
+
+	x, y, width, height int
+}
+
+// Panes splits the editor area into editors. One of them has focus; keys
+// go there and commands act on it.
+type Panes struct {
+	x, y, width, height int
+
+	root    *node
+	focused *node
+
+	// split whose divider is being dragged
+	dragging *node
+
+	onFocus func(*editor.Editor)
+}
+
+// CreatePanes starts with a single pane showing ed
+func CreatePanes(ed *editor.Editor) *Panes {
+	leaf := &node{editor: ed}
+	return &Panes{root: leaf, focused: leaf}
+}
+
+// SetOnFocus is called when another pane gets the focus
+func (p *Panes) SetOnFocus(cb func(*editor.Editor)) { p.onFocus = cb }
+
+// Focusable, the focused pane stands for all of them
+func (p *Panes) Focus()          { p.focused.editor.Focus() }
+func (p *Panes) Blur()           { p.focused.editor.Blur() }
+func (p *Panes) IsFocused() bool { return p.focused.editor.IsFocused() }
+
+// HandleKey sends keys to the focused pane
+func (p *Panes) HandleKey(ev *tcell.EventKey) {
+	p.focused.editor.HandleKey(ev)
+}
+
+// Focused is the editor in the focused pane
+func (p *Panes) Focused() *editor.Editor {
+	return p.focused.editor
+}
+
+// Editors lists the editor of every pane, left to right and top to bottom
+func (p *Panes) Editors() []*editor.Editor {
+	var eds []*editor.Editor
+	for _, leaf := range p.root.leaves(nil) {
+		eds = append(eds, leaf.editor)
+	}
+	return eds
+}
+
+func (n *node) leaves(list []*node) []*node {
+	if n.editor != nil {
+		return append(list, n)
+	}
+	return n.second.leaves(n.first.leaves(list))
+}
+
+// SetBounds moves and resizes the whole area, keeping the split ratios
+func (p *Panes) SetBounds(x, y, width, height int) {
+	p.x, p.y, p.width, p.height = x, y, width, height
+	p.layout()
+}
+
+func (p *Panes) layout() {
+	p.root.layout(p.x, p.y, p.width, p.height)
+}
+
+func (n *node) layout(x, y, width, height int) {
+	n.x, n.y, n.width, n.height = x, y, width, height
+	if n.editor != nil {
+		n.editor.SetBounds(x, y, width, height)
+		return
+	}
+	if n.dir == Vertical {
+		a := n.firstSize(width, minWidth)
+		n.first.layout(x, y, a, height)
+		n.second.layout(x+a+1, y, max(0, width-a-1), height)
+	} else {
+		a := n.firstSize(height, minHeight)
+		n.first.layout(x, y, width, a)
+		n.second.layout(x, y+a+1, width, max(0, height-a-1))
+	}
+}
+
+// firstSize is how many columns or rows the first side gets out of size,
+// one going to the divider. Both sides keep least where there is room.
+func (n *node) firstSize(size, least int) int {
+	room := max(0, size-1)
+	a := int(float64(room)*n.ratio + 0.5)
+	if room >= 2*least {
+		a = max(least, min(a, room-least))
+	}
+	return max(0, min(a, room))
+}
+
+// divider is the column or row of a split's divider
+func (n *node) divider() int {
+	if n.dir == Vertical {
+		return n.first.x + n.first.width
+	}
+	return n.first.y + n.first.height
+}
+
+// Split divides the focused pane in two, showing ed in the new half, which
+// gets the focus. It reports false when the pane is too small to split.
+func (p *Panes) Split(dir Direction, ed *editor.Editor) bool {
+	leaf := p.focused
+	if (dir == Vertical && leaf.width < 2*minWidth+1) || (dir == Horizontal && leaf.height < 2*minHeight+1) {
+		return false
+	}
+
+	// the leaf becomes the split, its editor moves to the first side
+	first := &node{editor: leaf.editor, parent: leaf}
+	second := &node{editor: ed, parent: leaf}
+	leaf.editor = nil
+	leaf.dir, leaf.ratio = dir, 0.5
+	leaf.first, leaf.second = first, second
+	p.focused = first
+	p.layout()
+	p.focus(second)
+	return true
+}
+
+// Close removes the focused pane, its neighbour takes the room. The last
+// pane can't be closed.
+func (p *Panes) Close() bool {
+	leaf := p.focused
+	split := leaf.parent
+	if split == nil {
+		return false
+	}
+	sibling := split.first
+	if sibling == leaf {
+		sibling = split.second
+	}
+
+	// the sibling takes the split's place in the tree
+	sibling.parent = split.parent
+	switch {
+	case split.parent == nil:
+		p.root = sibling
+	case split.parent.first == split:
+		split.parent.first = sibling
+	default:
+		split.parent.second = sibling
+	}
+	p.layout()
+
+	focused := leaf.editor.IsFocused()
+	leaf.editor.Blur()
+	p.focused = sibling.leaves(nil)[0]
+	if focused {
+		p.focused.editor.Focus()
+	}
+	if p.onFocus != nil {
+		p.onFocus(p.focused.editor)
+	}
+	return true
+}
+
+// Count is the number of panes
+func (p *Panes) Count() int {
+	return len(p.root.leaves(nil))
+}
+
+// focus moves the focus to another pane
+func (p *Panes) focus(leaf *node) {
+	if leaf == p.focused {
+		return
+	}
+	focused := p.IsFocused()
+	p.focused.editor.Blur()
+	p.focused = leaf
+	if focused {
+		leaf.editor.Focus()
+	}
+	if p.onFocus != nil {
+		p.onFocus(leaf.editor)
+	}
+}
+
+// FocusNext focuses the pane delta places after the focused one, wrapping
+// around
+func (p *Panes) FocusNext(delta int) {
+	leaves := p.root.leaves(nil)
+	i := 0
+	for j, leaf := range leaves {
+		if leaf == p.focused {
+			i = j
+		}
+	}
+	i = ((i+delta)%len(leaves) + len(leaves)) % len(leaves)
+	p.focus(leaves[i])
+}
+
+// FocusDirection focuses the nearest pane left, right, above or below the
+// focused one (dx or dy is -1 or 1). It reports false when there is none.
+func (p *Panes) FocusDirection(dx, dy int) bool {
+	from := p.focused
+	var best *node
+	bestGap, bestOff := 0, 0
+	for _, leaf := range p.root.leaves(nil) {
+		if leaf == from {
+			continue
+		}
+		// distance along the direction and how far off the line it is
+		var gap, off int
+		switch {
+		case dx != 0:
+			if leaf.y >= from.y+from.height || leaf.y+leaf.height <= from.y {
+				continue
+			}
+			gap = (leaf.x - from.x) * dx
+			off = abs(leaf.y - from.y)
+		default:
+			if leaf.x >= from.x+from.width || leaf.x+leaf.width <= from.x {
+				continue
+			}
+			gap = (leaf.y - from.y) * dy
+			off = abs(leaf.x - from.x)
+		}
+		if gap <= 0 {
+			continue
+		}
+		if best == nil || gap < bestGap || (gap == bestGap && off < bestOff) {
+			best, bestGap, bestOff = leaf, gap, off
+		}
+	}
+	if best == nil {
+		return false
+	}
+	p.focus(best)
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// leafAt returns the pane under a screen position, nil for none
+func (p *Panes) leafAt(x, y int) *node {
+	for _, leaf := range p.root.leaves(nil) {
+		if x >= leaf.x && x < leaf.x+leaf.width && y >= leaf.y && y < leaf.y+leaf.height {
+			return leaf
+		}
+	}
+	return nil
+}
+
+// Draw draws every pane and the dividers between them
+func (p *Panes) Draw(s tcell.Screen) {
+	p.root.draw(s, theme.Current().Style(theme.EditorDivider))
+}
+
+func (n *node) draw(s tcell.Screen, style tcell.Style) {
+	if n.editor != nil {
+		n.editor.Draw(s)
+		return
+	}
+	n.first.draw(s, style)
+	n.second.draw(s, style)
+
+	d := n.divider()
+	if n.dir == Vertical {
+		for row := n.y; row < n.y+n.height; row++ {
+			s.SetContent(d, row, '│', nil, style)
+		}
+	} else {
+		for col := n.x; col < n.x+n.width; col++ {
+			s.SetContent(col, d, '─', nil, style)
+		}
+	}
+}
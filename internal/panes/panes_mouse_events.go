@@ -0,0 +1,59 @@
+package panes
+
+import "github.com/gdamore/tcell/v2"
+
+// HandleMouse drags dividers, focuses the pane clicked on and passes the
+// event on to the editors
+func (p *Panes) HandleMouse(ev *tcell.EventMouse) {
+	x, y := ev.Position()
+
+	if p.dragging != nil {
+		if ev.Buttons() == 0 {
+			p.dragging = nil
+		} else {
+			p.drag(p.dragging, x, y)
+		}
+		return
+	}
+
+	if ev.Buttons()&tcell.Button1 != 0 {
+		if split := p.root.dividerAt(x, y); split != nil {
+			p.dragging = split
+			return
+		}
+		if leaf := p.leafAt(x, y); leaf != nil {
+			p.focus(leaf)
+		}
+	}
+
+	for _, leaf := range p.root.leaves(nil) {
+		leaf.editor.HandleMouse(ev)
+	}
+}
+
+// dividerAt returns the split whose divider is at a screen position
+func (n *node) dividerAt(x, y int) *node {
+	if n.editor != nil || x < n.x || x >= n.x+n.width || y < n.y || y >= n.y+n.height {
+		return nil
+	}
+	if (n.dir == Vertical && x == n.divider()) || (n.dir == Horizontal && y == n.divider()) {
+		return n
+	}
+	if split := n.first.dividerAt(x, y); split != nil {
+		return split
+	}
+	return n.second.dividerAt(x, y)
+}
+
+// drag moves a split's divider to the mouse
+func (p *Panes) drag(split *node, x, y int) {
+	pos, start, size := x, split.x, split.width
+	if split.dir == Horizontal {
+		pos, start, size = y, split.y, split.height
+	}
+	if size < 2 {
+		return
+	}
+	split.ratio = max(0, min(1, float64(pos-start)/float64(size-1)))
+	p.layout()
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/uditrawat03/bitcode/internal/theme"
 	"github.com/uditrawat03/bitcode/internal/treeview"
 )
 
@@ -18,6 +19,7 @@ type Sidebar struct {
 	Tree *treeview.TreeView
 
 	onFileOpen func(path string)
+	onResize   func(width int)
 	focusCb    func()
 }
 
@@ -45,6 +47,12 @@ func (sb *Sidebar) SetOnFileOpen(cb func(path string)) {
 	sb.onFileOpen = cb
 }
 
+// SetOnResize is called while the border is dragged, with the wanted width.
+// Without it the sidebar just resizes itself.
+func (sb *Sidebar) SetOnResize(cb func(width int)) {
+	sb.onResize = cb
+}
+
 // Focusable
 func (sb *Sidebar) Focus()          { sb.Focused = true }
 func (sb *Sidebar) Blur()           { sb.Focused = false }
@@ -52,10 +60,10 @@ func (sb *Sidebar) IsFocused() bool { return sb.Focused }
 
 // Draw the sidebar
 func (sb *Sidebar) Draw(s tcell.Screen) {
-	bg := tcell.NewRGBColor(30, 30, 30)
-	style := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(bg)
-	selectedStyle := tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.NewRGBColor(100, 100, 255))
-	hoverStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.NewRGBColor(60, 60, 60))
+	th := theme.Current()
+	style := th.Style(theme.SidebarText)
+	selectedStyle := th.Style(theme.SidebarSelected)
+	hoverStyle := th.Style(theme.SidebarHover)
 
 	// Fill background with right border
 	for row := 0; row < sb.Height; row++ {
@@ -123,8 +131,7 @@ func (sb *Sidebar) Draw(s tcell.Screen) {
 		scrollbarY := sb.ScrollY * sb.Height / len(sb.Tree.Nodes)
 
 		for i := 0; i < scrollbarHeight && scrollbarY+i < sb.Height; i++ {
-			s.SetContent(sb.X+sb.Width-1, sb.Y+scrollbarY+i, '█', nil,
-				tcell.StyleDefault.Foreground(tcell.ColorGray))
+			s.SetContent(sb.X+sb.Width-1, sb.Y+scrollbarY+i, '█', nil, th.Style(theme.SidebarScrollbar))
 		}
 	}
 }
@@ -12,15 +12,19 @@ func (sb *Sidebar) HandleMouse(ev *tcell.EventMouse) {
 			sb.resizing = false
 		} else {
 			newW := x - sb.X + 1
-			if newW > 10 {
-				sb.Width = newW
+			if newW > 10 && newW != sb.Width {
+				if sb.onResize != nil {
+					sb.onResize(newW)
+				} else {
+					sb.Width = newW
+				}
 			}
 		}
 		return
 	}
 
 	// drag start if on right border
-	if ev.Buttons()&tcell.Button1 != 0 && x == sb.X+sb.Width-1 {
+	if ev.Buttons()&tcell.Button1 != 0 && sb.Width > 0 && x == sb.X+sb.Width-1 && y >= sb.Y && y < sb.Y+sb.Height {
 		sb.resizing = true
 		return
 	}
@@ -1,10 +1,15 @@
 package statusbar
 
-import "github.com/gdamore/tcell/v2"
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/uditrawat03/bitcode/internal/theme"
+)
 
 type StatusBar struct {
 	x, y, width, height int
 	focused             bool
+	message             string
+	info                string
 }
 
 func CreateStatusBar(x, y, width, height int) *StatusBar {
@@ -18,13 +23,27 @@ func (sb *StatusBar) IsFocused() bool                  { return sb.focused }
 func (sb *StatusBar) HandleKey(ev *tcell.EventKey)     {}
 func (sb *StatusBar) HandleMouse(ev *tcell.EventMouse) {}
 
+// SetBounds moves and resizes the bar
+func (sb *StatusBar) SetBounds(x, y, width, height int) {
+	sb.x, sb.y, sb.width, sb.height = x, y, width, height
+}
+
+// SetMessage shows msg until it is replaced; an empty msg resets to "Ready"
+func (sb *StatusBar) SetMessage(msg string) { sb.message = msg }
+
+// SetInfo sets the right aligned file details (encoding, line ending, position)
+func (sb *StatusBar) SetInfo(info string) { sb.info = info }
+
 // Draw
 func (sb *StatusBar) Draw(s tcell.Screen) {
-	style := tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow)
+	style := theme.Current().Style(theme.StatusBarText)
 	if sb.focused {
 		style = style.Reverse(true)
 	}
 	content := " Status: Ready "
+	if sb.message != "" {
+		content = " " + sb.message + " "
+	}
 	for row := 0; row < sb.height; row++ {
 		for col := 0; col < sb.width; col++ {
 			s.SetContent(sb.x+col, sb.y+row, ' ', nil, style)
@@ -36,4 +55,13 @@ func (sb *StatusBar) Draw(s tcell.Screen) {
 		}
 		s.SetContent(sb.x+i, sb.y, r, nil, style)
 	}
+
+	info := []rune(" " + sb.info + " ")
+	start := sb.width - len(info)
+	if sb.info == "" || start <= len([]rune(content)) {
+		return
+	}
+	for i, r := range info {
+		s.SetContent(sb.x+start+i, sb.y, r, nil, style)
+	}
 }
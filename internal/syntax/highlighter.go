@@ -0,0 +1,153 @@
+package syntax
+
+import (
+	"slices"
+
+	"github.com/uditrawat03/bitcode/internal/buffer"
+)
+
+// At most this many lines are lexed per Prepare so jumping to the end of a
+// huge file draws right away; the rest is lexed by a single CatchUp
+const maxLexPerPrepare = 5000
+
+type lineInfo struct {
+	tokens []Token
+	start  State // state the line was lexed with
+	end    State
+	valid  bool
+}
+
+// Highlighter caches the tokens of a buffer's lines. After an edit only the
+// edited lines are lexed again, plus the lines after them until the lexer
+// state matches what it was before.
+type Highlighter struct {
+	lexer    Lexer
+	lines    []lineInfo
+	revision int
+	synced   bool
+
+	// lines before firstInvalid are lexed and consistent with each other,
+	// no line after lastInvalid needs lexing for its own sake
+	firstInvalid int
+	lastInvalid  int
+}
+
+// NewHighlighter returns nil for a nil lexer, which draws everything plain
+func NewHighlighter(lexer Lexer) *Highlighter {
+	if lexer == nil {
+		return nil
+	}
+	return &Highlighter{lexer: lexer}
+}
+
+// Lexer returns the language in use
+func (h *Highlighter) Lexer() Lexer {
+	return h.lexer
+}
+
+// Prepare catches up with edits to buf and lexes lines up to last, so the
+// following Tokens calls for that range are cheap. It reports false when
+// the budget ran out before last; CatchUp finishes the job.
+func (h *Highlighter) Prepare(buf *buffer.Buffer, last int) bool {
+	h.sync(buf)
+	last = min(last, len(h.lines)-1)
+	h.lexUpTo(buf, last, maxLexPerPrepare)
+	return h.firstInvalid > last
+}
+
+// CatchUp lexes every line up to last, however many that takes
+func (h *Highlighter) CatchUp(buf *buffer.Buffer, last int) {
+	h.sync(buf)
+	last = min(last, len(h.lines)-1)
+	h.lexUpTo(buf, last, len(h.lines))
+}
+
+// Tokens returns the tokens of line y. Lines that Prepare could not reach
+// yet are lexed on their own from the initial state.
+func (h *Highlighter) Tokens(buf *buffer.Buffer, y int) []Token {
+	if y < 0 || y >= len(h.lines) {
+		return nil
+	}
+	if y < h.firstInvalid {
+		return h.lines[y].tokens
+	}
+	tokens, _ := h.lexer.Lex([]rune(buf.Line(y)), 0)
+	return tokens
+}
+
+// sync applies the edits made since the last call, or starts over
+func (h *Highlighter) sync(buf *buffer.Buffer) {
+	if h.synced && buf.Revision() == h.revision {
+		return
+	}
+	revision := buf.Revision()
+	edits, ok := buf.EditsSince(h.revision)
+	if !h.synced || !ok {
+		h.reset(buf.LineCount())
+	} else {
+		for _, e := range edits {
+			h.splice(e)
+		}
+	}
+	h.revision = revision
+	h.synced = true
+
+	if len(h.lines) != buf.LineCount() {
+		// an edit was missed, better slow than wrong
+		h.reset(buf.LineCount())
+	}
+}
+
+func (h *Highlighter) reset(lines int) {
+	h.lines = make([]lineInfo, lines)
+	h.firstInvalid = 0
+	h.lastInvalid = lines - 1
+}
+
+// splice replaces the lines touched by an edit with unlexed ones
+func (h *Highlighter) splice(e buffer.LineEdit) {
+	if e.Line < 0 || e.Line+e.Old > len(h.lines) {
+		h.reset(len(h.lines) - e.Old + e.New)
+		return
+	}
+	switch {
+	case e.New < e.Old:
+		h.lines = slices.Delete(h.lines, e.Line+e.New, e.Line+e.Old)
+	case e.New > e.Old:
+		h.lines = slices.Insert(h.lines, e.Line+e.Old, make([]lineInfo, e.New-e.Old)...)
+	}
+	clear(h.lines[e.Line : e.Line+e.New])
+
+	if h.lastInvalid >= e.Line+e.Old {
+		h.lastInvalid += e.New - e.Old
+	}
+	h.lastInvalid = max(h.lastInvalid, e.Line+e.New-1)
+	h.firstInvalid = min(h.firstInvalid, e.Line)
+}
+
+// lexUpTo lexes from the first invalid line through line last, stopping
+// early once the remaining lines are known to be up to date or budget lines
+// were lexed
+func (h *Highlighter) lexUpTo(buf *buffer.Buffer, last, budget int) {
+	i := h.firstInvalid
+	for ; i <= last && budget > 0; i++ {
+		in := State(0)
+		if i > 0 {
+			in = h.lines[i-1].end
+		}
+		ln := &h.lines[i]
+		if ln.valid && ln.start == in {
+			if i > h.lastInvalid {
+				// everything below was lexed from the same state before
+				h.firstInvalid = len(h.lines)
+				return
+			}
+			continue
+		}
+		ln.tokens, ln.end = h.lexer.Lex([]rune(buf.Line(i)), in)
+		ln.start = in
+		ln.valid = true
+		budget--
+	}
+	h.firstInvalid = i
+}
@@ -0,0 +1,147 @@
+package syntax
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/uditrawat03/bitcode/internal/buffer"
+)
+
+// countingLexer counts the lines lexed through it
+type countingLexer struct {
+	Lexer
+	lexed int
+}
+
+func (c *countingLexer) Lex(line []rune, state State) ([]Token, State) {
+	c.lexed++
+	return c.Lexer.Lex(line, state)
+}
+
+// checkTokens compares every line with a highlighter starting from scratch
+func checkTokens(t *testing.T, h *Highlighter, buf *buffer.Buffer) {
+	t.Helper()
+	fresh := NewHighlighter(h.lexer)
+	fresh.CatchUp(buf, buf.LineCount()-1)
+	for y := 0; y < buf.LineCount(); y++ {
+		if got, want := h.Tokens(buf, y), fresh.Tokens(buf, y); !reflect.DeepEqual(got, want) {
+			t.Fatalf("line %d %q: tokens %v, want %v", y, buf.Line(y), got, want)
+		}
+	}
+}
+
+func TestHighlighterEdits(t *testing.T) {
+	text := "package main\n\n/* start\nstill a comment\n*/\nfunc main() {\n\tx := `raw\nstring`\n}\n"
+	tests := []struct {
+		name  string
+		edit  func(buf *buffer.Buffer)
+		lexed int // lines lexed again after the edit
+	}{
+		{"edit inside a line", func(buf *buffer.Buffer) { buf.SetLine(5, "func other() {") }, 1},
+		// the comment already open on the next line swallows it again
+		{"open a comment", func(buf *buffer.Buffer) { buf.SetLine(1, "/*") }, 2},
+		{"close a comment early", func(buf *buffer.Buffer) { buf.SetLine(2, "/* start */") }, 3},
+		{"split a line", func(buf *buffer.Buffer) {
+			buf.CursorY, buf.CursorX = 5, 4
+			buf.InsertLine()
+		}, 2},
+		{"join lines", func(buf *buffer.Buffer) {
+			buf.CursorY, buf.CursorX = 4, 0
+			buf.DeleteRune()
+		}, 1},
+		{"several edits", func(buf *buffer.Buffer) {
+			buf.SetLine(0, "package other")
+			buf.SetLine(8, "}}")
+			buf.CursorY, buf.CursorX = 7, 0
+			buf.DeleteRune()
+		}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := buffer.NewScratchBuffer("test.go", text)
+			lexer := &countingLexer{Lexer: golang}
+			h := NewHighlighter(lexer)
+			if !h.Prepare(buf, buf.LineCount()-1) {
+				t.Fatal("Prepare ran out of budget on a tiny file")
+			}
+
+			lexer.lexed = 0
+			tt.edit(buf)
+			h.Prepare(buf, buf.LineCount()-1)
+			if lexer.lexed != tt.lexed {
+				t.Errorf("lexed %d lines after the edit, want %d", lexer.lexed, tt.lexed)
+			}
+			checkTokens(t, h, buf)
+		})
+	}
+}
+
+func TestHighlighterSplice(t *testing.T) {
+	tests := []struct {
+		name        string
+		edit        buffer.LineEdit
+		lines       int
+		first, last int
+	}{
+		{"change one line", buffer.LineEdit{Line: 3, Old: 1, New: 1}, 10, 3, 3},
+		{"insert lines", buffer.LineEdit{Line: 3, Old: 1, New: 3}, 12, 3, 5},
+		{"delete lines", buffer.LineEdit{Line: 3, Old: 3, New: 1}, 8, 3, 3},
+		{"append at the end", buffer.LineEdit{Line: 9, Old: 1, New: 2}, 11, 9, 10},
+		// an edit the cache can't place starts over
+		{"out of range", buffer.LineEdit{Line: 9, Old: 3, New: 1}, 8, 0, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Highlighter{lexer: golang}
+			h.reset(10)
+			for i := range h.lines {
+				h.lines[i] = lineInfo{valid: true}
+			}
+			h.firstInvalid, h.lastInvalid = 10, -1
+
+			h.splice(tt.edit)
+			if len(h.lines) != tt.lines || h.firstInvalid != tt.first || h.lastInvalid != tt.last {
+				t.Fatalf("splice: %d lines, invalid %d-%d, want %d lines, invalid %d-%d",
+					len(h.lines), h.firstInvalid, h.lastInvalid, tt.lines, tt.first, tt.last)
+			}
+			for i, ln := range h.lines {
+				if want := i < tt.first || i > tt.last; ln.valid != want {
+					t.Fatalf("line %d valid = %v, want %v", i, ln.valid, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHighlighterBudget(t *testing.T) {
+	n := 3 * maxLexPerPrepare
+	buf := buffer.NewScratchBuffer("big.go", "/*\n"+strings.Repeat("x := 1\n", n)+"*/\nvar y = 2")
+	lexer := &countingLexer{Lexer: golang}
+	h := NewHighlighter(lexer)
+	last := buf.LineCount() - 1
+
+	if h.Prepare(buf, last) {
+		t.Fatal("Prepare lexed the whole file within its budget")
+	}
+	if lexer.lexed != maxLexPerPrepare {
+		t.Fatalf("Prepare lexed %d lines, want %d", lexer.lexed, maxLexPerPrepare)
+	}
+	// lines past the budget are lexed on their own meanwhile
+	if got := h.Tokens(buf, n); len(got) == 0 || got[0].Class == Comment {
+		t.Fatalf("unprepared line tokens = %v, want them lexed from the start state", got)
+	}
+
+	h.CatchUp(buf, last)
+	if !h.Prepare(buf, last) {
+		t.Fatal("Prepare still not done after CatchUp")
+	}
+	// plus the line Tokens lexed on its own
+	if lexer.lexed != buf.LineCount()+1 {
+		t.Fatalf("lexed %d lines in total, want each of the %d once", lexer.lexed-1, buf.LineCount())
+	}
+	checkTokens(t, h, buf)
+	if got := h.Tokens(buf, n); len(got) != 1 || got[0].Class != Comment {
+		t.Fatalf("line %d tokens = %v, want one comment", n, got)
+	}
+}
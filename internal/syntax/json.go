@@ -0,0 +1,73 @@
+package syntax
+
+import "unicode"
+
+// jsonLexer tells keys from string values by the colon that follows them.
+// Comments are accepted for JSONC files.
+type jsonLexer struct{}
+
+func (jsonLexer) Name() string { return "JSON" }
+
+func (jsonLexer) Lex(line []rune, state State) ([]Token, State) {
+	var tokens []Token
+	emit := func(start, end int, class Class) {
+		if end > start {
+			tokens = append(tokens, Token{Start: start, End: end, Class: class})
+		}
+	}
+
+	i, n := 0, len(line)
+	if state == stateBlockComment {
+		end := indexFrom(line, 0, "*/")
+		if end < 0 {
+			emit(0, n, Comment)
+			return tokens, state
+		}
+		emit(0, end+2, Comment)
+		i = end + 2
+	}
+
+	for i < n {
+		r := line[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case hasPrefixAt(line, i, "//"):
+			emit(i, n, Comment)
+			return tokens, 0
+		case hasPrefixAt(line, i, "/*"):
+			end := indexFrom(line, i+2, "*/")
+			if end < 0 {
+				emit(i, n, Comment)
+				return tokens, stateBlockComment
+			}
+			emit(i, end+2, Comment)
+			i = end + 2
+		case r == '"':
+			end, _ := scanString(line, i+1, delimiter{close: `"`, escapes: true})
+			class := String
+			if nextNonSpace(line, end) == ':' {
+				class = Key
+			}
+			emit(i, end, class)
+			i = end
+		case r == '-' || unicode.IsDigit(r):
+			end := scanNumber(line, i+1)
+			emit(i, end, Number)
+			i = end
+		case isIdentStart(r):
+			end := scanIdent(line, i)
+			switch string(line[i:end]) {
+			case "true", "false", "null":
+				emit(i, end, Constant)
+			}
+			i = end
+		case r == '{' || r == '}' || r == '[' || r == ']' || r == ',' || r == ':':
+			emit(i, i+1, Punctuation)
+			i++
+		default:
+			i++
+		}
+	}
+	return tokens, 0
+}
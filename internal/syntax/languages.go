@@ -0,0 +1,70 @@
+package syntax
+
+func init() {
+	Register(golang, ".go")
+	Register(python, ".py", ".pyw", ".pyi")
+	Register(shell, ".sh", ".bash", ".zsh", ".ksh", ".bashrc", ".zshrc", ".profile", ".bash_profile", "PKGBUILD")
+	Register(jsonLexer{}, ".json", ".jsonc", ".geojson", ".webmanifest")
+	Register(yamlLexer{}, ".yaml", ".yml")
+	Register(markdownLexer{}, ".md", ".markdown", ".mdown", ".mkd")
+}
+
+var golang = &language{
+	name: "Go",
+	keywords: words(`break case chan const continue default defer else fallthrough
+		for func go goto if import interface map package range return select
+		struct switch type var`),
+	types: words(`any bool byte comparable complex64 complex128 error float32
+		float64 int int8 int16 int32 int64 rune string uint uint8 uint16 uint32
+		uint64 uintptr`),
+	constants: words(`true false nil iota`),
+	builtins: words(`append cap clear close complex copy delete imag len make max
+		min new panic print println real recover`),
+	lineComment: "//",
+	blockStart:  "/*",
+	blockEnd:    "*/",
+	strings: []delimiter{
+		{open: "`", close: "`", multiline: true},
+		{open: `"`, close: `"`, escapes: true},
+		{open: "'", close: "'", escapes: true},
+	},
+}
+
+var python = &language{
+	name: "Python",
+	keywords: words(`and as assert async await break class continue def del elif
+		else except finally for from global if import in is lambda nonlocal not
+		or pass raise return try while with yield match case`),
+	types: words(`int float complex str bytes bytearray bool list tuple dict set
+		frozenset object type`),
+	constants: words(`True False None self cls NotImplemented Ellipsis`),
+	builtins: words(`abs all any print len range open super isinstance
+		issubclass getattr setattr hasattr enumerate zip map filter sorted
+		reversed min max sum iter next repr format input`),
+	lineComment: "#",
+	strings: []delimiter{
+		{open: `"""`, close: `"""`, multiline: true, escapes: true},
+		{open: `'''`, close: `'''`, multiline: true, escapes: true},
+		{open: `"`, close: `"`, escapes: true},
+		{open: "'", close: "'", escapes: true},
+	},
+	decorators: true,
+}
+
+var shell = &language{
+	name: "Shell",
+	keywords: words(`if then else elif fi case esac for select while until do
+		done in function time coproc return break continue exit local export
+		readonly declare typeset unset shift source`),
+	constants: words(`true false`),
+	builtins: words(`echo printf read cd pwd test eval exec set trap wait kill
+		alias unalias getopts`),
+	lineComment: "#",
+	strings: []delimiter{
+		{open: `"`, close: `"`, multiline: true, escapes: true},
+		{open: "'", close: "'", multiline: true},
+		{open: "`", close: "`", escapes: true},
+	},
+	variables:  true,
+	shellWords: true,
+}
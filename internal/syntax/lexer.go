@@ -0,0 +1,262 @@
+package syntax
+
+import (
+	"strings"
+	"unicode"
+)
+
+// delimiter is a kind of string literal
+type delimiter struct {
+	open, close string
+	multiline   bool // may span lines, like Go raw strings or Python """
+	escapes     bool // backslash escapes the next rune
+}
+
+// language describes a C-like language well enough for the generic lexer
+type language struct {
+	name        string
+	keywords    map[string]bool
+	types       map[string]bool
+	constants   map[string]bool
+	builtins    map[string]bool
+	lineComment string
+	blockStart  string
+	blockEnd    string
+	strings     []delimiter // longer openers first, e.g. """ before "
+
+	variables  bool // $name and ${name} as in shell
+	decorators bool // @name as in Python
+	shellWords bool // '#' only starts a comment at the start of a word
+}
+
+func words(s string) map[string]bool {
+	m := map[string]bool{}
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+// States of the generic lexer: 0 is code, 1 is inside a block comment and
+// 2+i inside the multiline string strings[i]
+const stateBlockComment State = 1
+
+func (l *language) Name() string { return l.name }
+
+func (l *language) Lex(line []rune, state State) ([]Token, State) {
+	var tokens []Token
+	emit := func(start, end int, class Class) {
+		if end > start {
+			tokens = append(tokens, Token{Start: start, End: end, Class: class})
+		}
+	}
+
+	i, n := 0, len(line)
+
+	// continue what the previous line left open
+	switch {
+	case state == stateBlockComment:
+		end := indexFrom(line, 0, l.blockEnd)
+		if end < 0 {
+			emit(0, n, Comment)
+			return tokens, state
+		}
+		i = end + len([]rune(l.blockEnd))
+		emit(0, i, Comment)
+	case state >= 2 && int(state)-2 < len(l.strings):
+		d := l.strings[state-2]
+		end, closed := scanString(line, 0, d)
+		emit(0, end, String)
+		if !closed {
+			return tokens, state
+		}
+		i = end
+	}
+
+	for i < n {
+		r := line[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case l.lineComment != "" && hasPrefixAt(line, i, l.lineComment) &&
+			(!l.shellWords || i == 0 || unicode.IsSpace(line[i-1])):
+			emit(i, n, Comment)
+			return tokens, 0
+
+		case l.blockStart != "" && hasPrefixAt(line, i, l.blockStart):
+			from := i + len([]rune(l.blockStart))
+			end := indexFrom(line, from, l.blockEnd)
+			if end < 0 {
+				emit(i, n, Comment)
+				return tokens, stateBlockComment
+			}
+			end += len([]rune(l.blockEnd))
+			emit(i, end, Comment)
+			i = end
+
+		case l.stringAt(line, i) >= 0:
+			k := l.stringAt(line, i)
+			d := l.strings[k]
+			end, closed := scanString(line, i+len([]rune(d.open)), d)
+			emit(i, end, String)
+			if !closed && d.multiline {
+				return tokens, State(2 + k)
+			}
+			i = end
+
+		case unicode.IsDigit(r) || (r == '.' && i+1 < n && unicode.IsDigit(line[i+1])):
+			end := scanNumber(line, i)
+			emit(i, end, Number)
+			i = end
+
+		case isIdentStart(r):
+			end := scanIdent(line, i)
+			word := string(line[i:end])
+			switch {
+			case l.keywords[word]:
+				emit(i, end, Keyword)
+			case l.types[word]:
+				emit(i, end, Type)
+			case l.constants[word]:
+				emit(i, end, Constant)
+			case l.builtins[word]:
+				emit(i, end, Function)
+			case nextNonSpace(line, end) == '(':
+				emit(i, end, Function)
+			}
+			i = end
+
+		case l.variables && r == '$' && i+1 < n:
+			end := scanVariable(line, i)
+			emit(i, end, Variable)
+			i = end
+
+		case l.decorators && r == '@' && i+1 < n && isIdentStart(line[i+1]):
+			end := scanIdent(line, i+1)
+			for end < n && line[end] == '.' && end+1 < n && isIdentStart(line[end+1]) {
+				end = scanIdent(line, end+1)
+			}
+			emit(i, end, Meta)
+			i = end
+
+		case strings.ContainsRune("+-*/%=<>!&|^~?:", r):
+			end := i + 1
+			for end < n && strings.ContainsRune("+-*/%=<>!&|^~?:", line[end]) &&
+				!hasPrefixAt(line, end, l.lineComment) && !hasPrefixAt(line, end, l.blockStart) {
+				end++
+			}
+			emit(i, end, Operator)
+			i = end
+
+		case strings.ContainsRune("()[]{},;.", r):
+			emit(i, i+1, Punctuation)
+			i++
+
+		default:
+			i++
+		}
+	}
+	return tokens, 0
+}
+
+// stringAt returns the index of the string delimiter opening at i, or -1
+func (l *language) stringAt(line []rune, i int) int {
+	for k, d := range l.strings {
+		if hasPrefixAt(line, i, d.open) {
+			return k
+		}
+	}
+	return -1
+}
+
+// scanString finds the end of a string whose content starts at i
+func scanString(line []rune, i int, d delimiter) (int, bool) {
+	for i < len(line) {
+		if d.escapes && line[i] == '\\' {
+			i += 2
+			continue
+		}
+		if hasPrefixAt(line, i, d.close) {
+			return i + len([]rune(d.close)), true
+		}
+		i++
+	}
+	return len(line), false
+}
+
+func scanNumber(line []rune, i int) int {
+	for i < len(line) {
+		r := line[i]
+		switch {
+		case unicode.IsDigit(r) || unicode.IsLetter(r) || r == '_' || r == '.':
+			i++
+		case (r == '+' || r == '-') && i > 0 && strings.ContainsRune("eEpP", line[i-1]):
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+func isIdentStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func scanIdent(line []rune, i int) int {
+	for i < len(line) && (isIdentStart(line[i]) || unicode.IsDigit(line[i])) {
+		i++
+	}
+	return i
+}
+
+// scanVariable reads $name, ${...}, $1, $@ and friends starting at the '$'
+func scanVariable(line []rune, i int) int {
+	switch next := line[i+1]; {
+	case next == '{':
+		for j := i + 2; j < len(line); j++ {
+			if line[j] == '}' {
+				return j + 1
+			}
+		}
+		return len(line)
+	case isIdentStart(next):
+		return scanIdent(line, i+1)
+	case unicode.IsDigit(next) || strings.ContainsRune("@*#?$!-", next):
+		return i + 2
+	}
+	return i + 1
+}
+
+func nextNonSpace(line []rune, i int) rune {
+	for ; i < len(line); i++ {
+		if !unicode.IsSpace(line[i]) {
+			return line[i]
+		}
+	}
+	return 0
+}
+
+func hasPrefixAt(line []rune, i int, prefix string) bool {
+	if prefix == "" {
+		return false
+	}
+	for _, r := range prefix {
+		if i >= len(line) || line[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// indexFrom finds sub in line at or after from, -1 when absent
+func indexFrom(line []rune, from int, sub string) int {
+	for i := from; i < len(line); i++ {
+		if hasPrefixAt(line, i, sub) {
+			return i
+		}
+	}
+	return -1
+}
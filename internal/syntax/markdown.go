@@ -0,0 +1,164 @@
+package syntax
+
+import (
+	"strings"
+	"unicode"
+)
+
+// States of the Markdown lexer inside fenced code blocks
+const (
+	stateFenceBackticks State = 1
+	stateFenceTildes    State = 2
+)
+
+type markdownLexer struct{}
+
+func (markdownLexer) Name() string { return "Markdown" }
+
+func (markdownLexer) Lex(line []rune, state State) ([]Token, State) {
+	var tokens []Token
+	emit := func(start, end int, class Class) {
+		if end > start {
+			tokens = append(tokens, Token{Start: start, End: end, Class: class})
+		}
+	}
+
+	n := len(line)
+	trimmed := strings.TrimLeft(string(line), " ")
+	indent := n - len([]rune(trimmed))
+
+	// fenced code blocks
+	fence := ""
+	switch {
+	case strings.HasPrefix(trimmed, "```"):
+		fence = "```"
+	case strings.HasPrefix(trimmed, "~~~"):
+		fence = "~~~"
+	}
+	switch state {
+	case stateFenceBackticks, stateFenceTildes:
+		emit(0, n, Code)
+		if (state == stateFenceBackticks && fence == "```") || (state == stateFenceTildes && fence == "~~~") {
+			return tokens, 0
+		}
+		return tokens, state
+	}
+	if fence != "" && indent < 4 {
+		emit(0, n, Code)
+		if fence == "```" {
+			return tokens, stateFenceBackticks
+		}
+		return tokens, stateFenceTildes
+	}
+
+	// headings and rules take the whole line
+	if indent < 4 && strings.HasPrefix(trimmed, "#") {
+		level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+		if level <= 6 && (len(trimmed) == level || trimmed[level] == ' ') {
+			emit(0, n, Heading)
+			return tokens, 0
+		}
+	}
+	if isRule(trimmed) {
+		emit(0, n, Meta)
+		return tokens, 0
+	}
+
+	// block quote and list markers
+	i := indent
+	for i < n && line[i] == '>' {
+		emit(i, i+1, Comment)
+		i++
+		for i < n && line[i] == ' ' {
+			i++
+		}
+	}
+	if i+1 < n && strings.ContainsRune("-*+", line[i]) && line[i+1] == ' ' {
+		emit(i, i+1, Operator)
+		i += 2
+	} else if j := i; j < n && unicode.IsDigit(line[j]) {
+		for j < n && unicode.IsDigit(line[j]) {
+			j++
+		}
+		if j+1 < n && (line[j] == '.' || line[j] == ')') && line[j+1] == ' ' {
+			emit(i, j+1, Operator)
+			i = j + 2
+		}
+	}
+
+	lexInline(line, i, emit)
+	return tokens, 0
+}
+
+// lexInline handles code spans, emphasis and links
+func lexInline(line []rune, i int, emit func(start, end int, class Class)) {
+	n := len(line)
+	for i < n {
+		r := line[i]
+		switch {
+		case r == '\\':
+			i += 2
+		case r == '`':
+			ticks := 1
+			for i+ticks < n && line[i+ticks] == '`' {
+				ticks++
+			}
+			end := indexFrom(line, i+ticks, strings.Repeat("`", ticks))
+			if end < 0 {
+				i += ticks
+				continue
+			}
+			emit(i, end+ticks, Code)
+			i = end + ticks
+		case (r == '*' || r == '_') && i+1 < n && !unicode.IsSpace(line[i+1]):
+			marker := string(r)
+			if line[i+1] == r {
+				marker += string(r)
+			}
+			end := indexFrom(line, i+len(marker), marker)
+			if end < 0 || end == i+len(marker) {
+				i += len(marker)
+				continue
+			}
+			emit(i, end+len(marker), Emphasis)
+			i = end + len(marker)
+		case r == '[' || (r == '!' && i+1 < n && line[i+1] == '['):
+			start := i
+			if r == '!' {
+				i++
+			}
+			close := indexFrom(line, i+1, "]")
+			if close < 0 {
+				i++
+				continue
+			}
+			end := close + 1
+			if end < n && line[end] == '(' {
+				if paren := indexFrom(line, end, ")"); paren >= 0 {
+					end = paren + 1
+				}
+			}
+			emit(start, end, Link)
+			i = end
+		case r == '<' && (hasPrefixAt(line, i+1, "http://") || hasPrefixAt(line, i+1, "https://")):
+			end := indexFrom(line, i, ">")
+			if end < 0 {
+				i++
+				continue
+			}
+			emit(i, end+1, Link)
+			i = end + 1
+		default:
+			i++
+		}
+	}
+}
+
+// isRule matches thematic breaks such as "---", "***" or "_ _ _"
+func isRule(s string) bool {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if len(s) < 3 {
+		return false
+	}
+	return strings.Count(s, s[:1]) == len(s) && strings.Contains("-*_", s[:1])
+}
@@ -0,0 +1,130 @@
+package syntax
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Class is the kind of a token, styles are picked per class
+type Class int
+
+const (
+	Plain Class = iota
+	Keyword
+	Type
+	Constant
+	Number
+	String
+	Comment
+	Function
+	Operator
+	Punctuation
+	Key      // object keys in JSON and YAML
+	Variable // shell variables
+	Meta     // decorators, anchors, directives
+	Heading
+	Emphasis
+	Link
+	Code // inline and fenced code in Markdown
+)
+
+// Token colors runes [Start, End) of a line
+type Token struct {
+	Start int
+	End   int
+	Class Class
+}
+
+// State is what a lexer carries from the end of one line to the next, for
+// example "inside a block comment". 0 is the state at the top of a file.
+type State int
+
+// Lexer tokenizes one line at a time
+type Lexer interface {
+	Name() string
+	Lex(line []rune, state State) ([]Token, State)
+}
+
+var (
+	lexers     = map[string]Lexer{}
+	extensions = map[string]Lexer{}
+	filenames  = map[string]Lexer{}
+)
+
+// Register makes a lexer available for files with the given extensions
+// (".go") or exact names ("Makefile")
+func Register(lexer Lexer, patterns ...string) {
+	lexers[strings.ToLower(lexer.Name())] = lexer
+	for _, p := range patterns {
+		if strings.HasPrefix(p, ".") {
+			extensions[strings.ToLower(p)] = lexer
+		} else {
+			filenames[p] = lexer
+		}
+	}
+}
+
+// ForFile picks a lexer by file name, nil when the language is unknown
+func ForFile(path string) Lexer {
+	base := filepath.Base(path)
+	if l, ok := filenames[base]; ok {
+		return l
+	}
+	return extensions[strings.ToLower(filepath.Ext(base))]
+}
+
+// ByName looks a lexer up by its name, e.g. "go" or "markdown"
+func ByName(name string) Lexer {
+	return lexers[strings.ToLower(name)]
+}
+
+// Styles maps token classes to how they are drawn
+type Styles map[Class]tcell.Style
+
+// classNames are how themes refer to the classes, as "syntax.<name>"
+var classNames = map[Class]string{
+	Plain:       "plain",
+	Keyword:     "keyword",
+	Type:        "type",
+	Constant:    "constant",
+	Number:      "number",
+	String:      "string",
+	Comment:     "comment",
+	Function:    "function",
+	Operator:    "operator",
+	Punctuation: "punctuation",
+	Key:         "key",
+	Variable:    "variable",
+	Meta:        "meta",
+	Heading:     "heading",
+	Emphasis:    "emphasis",
+	Link:        "link",
+	Code:        "code",
+}
+
+func (c Class) String() string {
+	return classNames[c]
+}
+
+// Classes lists every token class
+func Classes() []Class {
+	classes := make([]Class, 0, len(classNames))
+	for c := Plain; c <= Code; c++ {
+		classes = append(classes, c)
+	}
+	return classes
+}
+
+// Apply lays the style of class over base, keeping base's background so
+// line and selection highlights still show
+func (s Styles) Apply(base tcell.Style, class Class) tcell.Style {
+	st, ok := s[class]
+	if class == Plain || !ok {
+		return base
+	}
+	fg, _, attrs := st.Decompose()
+	_, bg, _ := base.Decompose()
+	return tcell.StyleDefault.Foreground(fg).Background(bg).Attributes(attrs)
+}
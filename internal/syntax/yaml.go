@@ -0,0 +1,160 @@
+package syntax
+
+import (
+	"strings"
+	"unicode"
+)
+
+// yamlLexer highlights keys, scalars and block scalars. Inside a block scalar
+// (after "key: |") the state holds 2 + the indentation of the key, lines
+// indented deeper belong to the scalar.
+type yamlLexer struct{}
+
+func (yamlLexer) Name() string { return "YAML" }
+
+func (yamlLexer) Lex(line []rune, state State) ([]Token, State) {
+	var tokens []Token
+	emit := func(start, end int, class Class) {
+		if end > start {
+			tokens = append(tokens, Token{Start: start, End: end, Class: class})
+		}
+	}
+
+	n := len(line)
+	indent := 0
+	for indent < n && line[indent] == ' ' {
+		indent++
+	}
+
+	if state >= 2 {
+		if indent == n || indent > int(state)-2 {
+			emit(indent, n, String)
+			return tokens, state
+		}
+		state = 0
+	}
+
+	// document markers and directives
+	text := string(line)
+	if strings.HasPrefix(text, "---") || strings.HasPrefix(text, "...") || strings.HasPrefix(text, "%") {
+		emit(0, n, Meta)
+		return tokens, 0
+	}
+
+	i := indent
+	// list item markers
+	for i+1 < n && line[i] == '-' && line[i+1] == ' ' || i+1 == n && line[i] == '-' {
+		emit(i, i+1, Punctuation)
+		i++
+		for i < n && line[i] == ' ' {
+			i++
+		}
+	}
+
+	// key
+	if end, ok := yamlKeyEnd(line, i); ok {
+		emit(i, end, Key)
+		emit(end, end+1, Punctuation)
+		i = end + 1
+	}
+
+	next := State(0)
+	for i < n {
+		r := line[i]
+		switch {
+		case r == ' ' || r == '\t':
+			i++
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			emit(i, n, Comment)
+			return tokens, next
+		case r == '"' || r == '\'':
+			end, _ := scanString(line, i+1, delimiter{close: string(r), escapes: r == '"'})
+			emit(i, end, String)
+			i = end
+		case r == '&' || r == '*' || r == '!':
+			end := i + 1
+			for end < n && !unicode.IsSpace(line[end]) && !strings.ContainsRune(",[]{}", line[end]) {
+				end++
+			}
+			emit(i, end, Meta)
+			i = end
+		case r == '|' || r == '>':
+			// block scalar indicator, optionally with chomping and indentation hints
+			end := i + 1
+			for end < n && strings.ContainsRune("+-0123456789", line[end]) {
+				end++
+			}
+			emit(i, end, Operator)
+			if nextNonSpace(line, end) == 0 || nextNonSpace(line, end) == '#' {
+				next = State(2 + indent)
+			}
+			i = end
+		case strings.ContainsRune("{}[],:", r):
+			emit(i, i+1, Punctuation)
+			i++
+		default:
+			// plain scalar up to a comment or flow punctuation
+			end := i
+			for end < n && !strings.ContainsRune(",[]{}", line[end]) &&
+				!(line[end] == '#' && end > 0 && line[end-1] == ' ') {
+				end++
+			}
+			word := strings.TrimRight(string(line[i:end]), " \t")
+			end = i + len([]rune(word))
+			switch {
+			case yamlConstants[strings.ToLower(word)]:
+				emit(i, end, Constant)
+			case isYAMLNumber(word):
+				emit(i, end, Number)
+			default:
+				emit(i, end, String)
+			}
+			i = max(end, i+1)
+		}
+	}
+	return tokens, next
+}
+
+var yamlConstants = words(`true false yes no on off null ~`)
+
+// yamlKeyEnd finds the colon ending a mapping key that starts at i
+func yamlKeyEnd(line []rune, i int) (int, bool) {
+	n := len(line)
+	if i < n && (line[i] == '"' || line[i] == '\'') {
+		end, closed := scanString(line, i+1, delimiter{close: string(line[i]), escapes: line[i] == '"'})
+		if closed && end < n && line[end] == ':' && (end+1 == n || line[end+1] == ' ') {
+			return end, true
+		}
+		return 0, false
+	}
+	for j := i; j < n; j++ {
+		switch {
+		case line[j] == '#' && j > i && line[j-1] == ' ':
+			return 0, false
+		case line[j] == ':' && j > i && (j+1 == n || line[j+1] == ' ' || line[j+1] == '\t'):
+			return j, true
+		case strings.ContainsRune("[]{},\"'", line[j]) && j == i:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+func isYAMLNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	s = strings.TrimLeft(s, "+-")
+	if s == ".inf" || s == ".nan" || s == ".Inf" || s == ".NaN" {
+		return true
+	}
+	if s == "" || !(unicode.IsDigit(rune(s[0])) || s[0] == '.' && len(s) > 1) {
+		return false
+	}
+	for _, r := range s {
+		if !(unicode.IsDigit(r) || strings.ContainsRune("._xXoOabcdefABCDEF+-eE", r)) {
+			return false
+		}
+	}
+	return true
+}
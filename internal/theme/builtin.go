@@ -0,0 +1,207 @@
+package theme
+
+import (
+	"sort"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// spec is the compact way built-in themes are written down
+type spec struct {
+	fg, bg string
+	attrs  tcell.AttrMask
+}
+
+func build(name string, isDark bool, specs map[string]spec) *Theme {
+	t := &Theme{Name: name, Dark: isDark, styles: map[string]tcell.Style{}}
+	for role, sp := range specs {
+		st := tcell.StyleDefault.Attributes(sp.attrs)
+		if sp.fg != "" {
+			st = st.Foreground(tcell.GetColor(sp.fg))
+		}
+		if sp.bg != "" {
+			st = st.Background(tcell.GetColor(sp.bg))
+		}
+		t.styles[role] = st
+	}
+	return t
+}
+
+// Dark is the default theme
+var Dark = build("dark", true, map[string]spec{
+	EditorText:         {fg: "white", bg: "black"},
+	EditorCurrentLine:  {fg: "white", bg: "#323250"},
+	EditorSelection:    {fg: "white", bg: "#323250"},
+	EditorLineNumber:   {fg: "white", bg: "black"},
+	EditorMatch:        {fg: "black", bg: "#b4963c"},
+	EditorCurrentMatch: {fg: "black", bg: "orange"},
+	EditorDivider:      {fg: "gray", bg: "black"},
+
+	FindBarText:      {fg: "white", bg: "#282828"},
+	FindBarOptionOn:  {fg: "yellow", bg: "#282828", attrs: tcell.AttrBold},
+	FindBarOptionOff: {fg: "gray", bg: "#282828"},
+
+	SidebarText:      {fg: "white", bg: "#1e1e1e"},
+	SidebarSelected:  {fg: "black", bg: "#6464ff"},
+	SidebarHover:     {fg: "white", bg: "#3c3c3c"},
+	SidebarScrollbar: {fg: "gray", bg: "#1e1e1e"},
+
+	PanelInput:         {fg: "white", bg: "#323232"},
+	PanelInputInactive: {fg: "white", bg: "#282828"},
+	PanelDim:           {fg: "gray", bg: "#1e1e1e"},
+	DiffAdded:          {fg: "white", bg: "#286428"},
+	DiffRemoved:        {fg: "white", bg: "#782828", attrs: tcell.AttrStrikeThrough},
+
+	StatusBarText: {fg: "black", bg: "yellow"},
+	TopBarText:    {fg: "black", bg: "green"},
+	TabActive:     {fg: "white", bg: "black", attrs: tcell.AttrBold},
+	TabInactive:   {fg: "black", bg: "green"},
+
+	DialogText:     {fg: "white", bg: "#1e1e1e"},
+	DialogBorder:   {fg: "#c8c8c8", bg: "#1e1e1e"},
+	DialogTitle:    {fg: "yellow", bg: "#1e1e1e"},
+	DialogSelected: {fg: "#1e1e1e", bg: "white"},
+
+	"syntax.keyword":     {fg: "#c678dd"},
+	"syntax.type":        {fg: "#e5c07b"},
+	"syntax.constant":    {fg: "#d19a66"},
+	"syntax.number":      {fg: "#d19a66"},
+	"syntax.string":      {fg: "#98c379"},
+	"syntax.comment":     {fg: "#6e7681", attrs: tcell.AttrItalic},
+	"syntax.function":    {fg: "#61afef"},
+	"syntax.operator":    {fg: "#56b6c2"},
+	"syntax.punctuation": {fg: "#abb2bf"},
+	"syntax.key":         {fg: "#e06c75"},
+	"syntax.variable":    {fg: "#e06c75"},
+	"syntax.meta":        {fg: "#56b6c2"},
+	"syntax.heading":     {fg: "#e06c75", attrs: tcell.AttrBold},
+	"syntax.emphasis":    {fg: "#e5c07b", attrs: tcell.AttrItalic},
+	"syntax.link":        {fg: "#61afef", attrs: tcell.AttrUnderline},
+	"syntax.code":        {fg: "#98c379"},
+})
+
+// Light suits terminals with a white background
+var Light = build("light", false, map[string]spec{
+	EditorText:         {fg: "#383a42", bg: "#fafafa"},
+	EditorCurrentLine:  {fg: "#383a42", bg: "#e8eaf0"},
+	EditorSelection:    {fg: "#383a42", bg: "#d0d8f0"},
+	EditorLineNumber:   {fg: "#9d9d9f", bg: "#fafafa"},
+	EditorMatch:        {fg: "black", bg: "#f0d878"},
+	EditorCurrentMatch: {fg: "black", bg: "#ffa040"},
+	EditorDivider:      {fg: "#d4d4d4", bg: "#fafafa"},
+
+	FindBarText:      {fg: "#383a42", bg: "#e5e5e6"},
+	FindBarOptionOn:  {fg: "#0184bc", bg: "#e5e5e6", attrs: tcell.AttrBold},
+	FindBarOptionOff: {fg: "#a0a1a7", bg: "#e5e5e6"},
+
+	SidebarText:      {fg: "#383a42", bg: "#f0f0f0"},
+	SidebarSelected:  {fg: "white", bg: "#4078f2"},
+	SidebarHover:     {fg: "#383a42", bg: "#dcdcdc"},
+	SidebarScrollbar: {fg: "#a0a1a7", bg: "#f0f0f0"},
+
+	PanelInput:         {fg: "#383a42", bg: "white"},
+	PanelInputInactive: {fg: "#383a42", bg: "#e5e5e6"},
+	PanelDim:           {fg: "#a0a1a7", bg: "#f0f0f0"},
+	DiffAdded:          {fg: "black", bg: "#c8f0c8"},
+	DiffRemoved:        {fg: "black", bg: "#f8c8c8", attrs: tcell.AttrStrikeThrough},
+
+	StatusBarText: {fg: "white", bg: "#4078f2"},
+	TopBarText:    {fg: "#383a42", bg: "#d4d4d4"},
+	TabActive:     {fg: "#383a42", bg: "#fafafa", attrs: tcell.AttrBold},
+	TabInactive:   {fg: "#696c77", bg: "#d4d4d4"},
+
+	DialogText:     {fg: "#383a42", bg: "#f0f0f0"},
+	DialogBorder:   {fg: "#696c77", bg: "#f0f0f0"},
+	DialogTitle:    {fg: "#4078f2", bg: "#f0f0f0", attrs: tcell.AttrBold},
+	DialogSelected: {fg: "white", bg: "#4078f2"},
+
+	"syntax.keyword":     {fg: "#a626a4"},
+	"syntax.type":        {fg: "#c18401"},
+	"syntax.constant":    {fg: "#986801"},
+	"syntax.number":      {fg: "#986801"},
+	"syntax.string":      {fg: "#50a14f"},
+	"syntax.comment":     {fg: "#a0a1a7", attrs: tcell.AttrItalic},
+	"syntax.function":    {fg: "#4078f2"},
+	"syntax.operator":    {fg: "#0184bc"},
+	"syntax.punctuation": {fg: "#383a42"},
+	"syntax.key":         {fg: "#e45649"},
+	"syntax.variable":    {fg: "#e45649"},
+	"syntax.meta":        {fg: "#0184bc"},
+	"syntax.heading":     {fg: "#e45649", attrs: tcell.AttrBold},
+	"syntax.emphasis":    {fg: "#c18401", attrs: tcell.AttrItalic},
+	"syntax.link":        {fg: "#4078f2", attrs: tcell.AttrUnderline},
+	"syntax.code":        {fg: "#50a14f"},
+})
+
+// SolarizedDark is Ethan Schoonover's Solarized palette
+var SolarizedDark = build("solarized-dark", true, map[string]spec{
+	EditorText:         {fg: "#839496", bg: "#002b36"},
+	EditorCurrentLine:  {fg: "#93a1a1", bg: "#073642"},
+	EditorSelection:    {fg: "#93a1a1", bg: "#114654"},
+	EditorLineNumber:   {fg: "#586e75", bg: "#002b36"},
+	EditorMatch:        {fg: "#002b36", bg: "#b58900"},
+	EditorCurrentMatch: {fg: "#002b36", bg: "#cb4b16"},
+	EditorDivider:      {fg: "#586e75", bg: "#002b36"},
+
+	FindBarText:      {fg: "#93a1a1", bg: "#073642"},
+	FindBarOptionOn:  {fg: "#b58900", bg: "#073642", attrs: tcell.AttrBold},
+	FindBarOptionOff: {fg: "#586e75", bg: "#073642"},
+
+	SidebarText:      {fg: "#839496", bg: "#00212b"},
+	SidebarSelected:  {fg: "#fdf6e3", bg: "#268bd2"},
+	SidebarHover:     {fg: "#93a1a1", bg: "#073642"},
+	SidebarScrollbar: {fg: "#586e75", bg: "#00212b"},
+
+	PanelInput:         {fg: "#93a1a1", bg: "#073642"},
+	PanelInputInactive: {fg: "#839496", bg: "#002b36"},
+	PanelDim:           {fg: "#586e75", bg: "#00212b"},
+	DiffAdded:          {fg: "#fdf6e3", bg: "#4d6b00"},
+	DiffRemoved:        {fg: "#fdf6e3", bg: "#8a2523", attrs: tcell.AttrStrikeThrough},
+
+	StatusBarText: {fg: "#002b36", bg: "#859900"},
+	TopBarText:    {fg: "#002b36", bg: "#2aa198"},
+	TabActive:     {fg: "#93a1a1", bg: "#002b36", attrs: tcell.AttrBold},
+	TabInactive:   {fg: "#002b36", bg: "#2aa198"},
+
+	DialogText:     {fg: "#93a1a1", bg: "#073642"},
+	DialogBorder:   {fg: "#586e75", bg: "#073642"},
+	DialogTitle:    {fg: "#b58900", bg: "#073642"},
+	DialogSelected: {fg: "#073642", bg: "#93a1a1"},
+
+	"syntax.keyword":     {fg: "#859900"},
+	"syntax.type":        {fg: "#b58900"},
+	"syntax.constant":    {fg: "#cb4b16"},
+	"syntax.number":      {fg: "#d33682"},
+	"syntax.string":      {fg: "#2aa198"},
+	"syntax.comment":     {fg: "#586e75", attrs: tcell.AttrItalic},
+	"syntax.function":    {fg: "#268bd2"},
+	"syntax.operator":    {fg: "#93a1a1"},
+	"syntax.punctuation": {fg: "#839496"},
+	"syntax.key":         {fg: "#268bd2"},
+	"syntax.variable":    {fg: "#b58900"},
+	"syntax.meta":        {fg: "#6c71c4"},
+	"syntax.heading":     {fg: "#cb4b16", attrs: tcell.AttrBold},
+	"syntax.emphasis":    {fg: "#b58900", attrs: tcell.AttrItalic},
+	"syntax.link":        {fg: "#268bd2", attrs: tcell.AttrUnderline},
+	"syntax.code":        {fg: "#2aa198"},
+})
+
+var builtins = map[string]*Theme{
+	Dark.Name:          Dark,
+	Light.Name:         Light,
+	SolarizedDark.Name: SolarizedDark,
+}
+
+// Builtin returns a theme shipped with the editor, nil if there is none by that name
+func Builtin(name string) *Theme {
+	return builtins[name]
+}
+
+func builtinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
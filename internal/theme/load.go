@@ -0,0 +1,192 @@
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/uditrawat03/bitcode/internal/syntax"
+)
+
+// file is the JSON layout of a theme:
+//
+//	{
+//	  "name": "midnight",
+//	  "extends": "dark",
+//	  "styles": {
+//	    "editor.text": {"fg": "#c0c0c0", "bg": "#101018"},
+//	    "syntax.keyword": "#ff79c6",
+//	    "syntax.comment": {"fg": "#6272a4", "italic": true}
+//	  }
+//	}
+//
+// A plain string sets the foreground only. Roles left out, and fields left
+// out of a role, come from the theme named by extends.
+type file struct {
+	Name    string                     `json:"name"`
+	Extends string                     `json:"extends"`
+	Dark    *bool                      `json:"dark"`
+	Styles  map[string]json.RawMessage `json:"styles"`
+}
+
+type styleSpec struct {
+	FG            string `json:"fg"`
+	BG            string `json:"bg"`
+	Bold          *bool  `json:"bold"`
+	Italic        *bool  `json:"italic"`
+	Underline     *bool  `json:"underline"`
+	Reverse       *bool  `json:"reverse"`
+	StrikeThrough *bool  `json:"strikethrough"`
+}
+
+// Dir is where user themes live, ~/.config/bitcode/themes on Linux
+func Dir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "bitcode", "themes")
+}
+
+// Names lists the built-in themes followed by the ones in Dir
+func Names() []string {
+	names := builtinNames()
+	entries, _ := os.ReadDir(Dir())
+	var user []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if ok && !entry.IsDir() && builtins[name] == nil {
+			user = append(user, name)
+		}
+	}
+	sort.Strings(user)
+	return append(names, user...)
+}
+
+// Find resolves a theme by built-in name, name of a file in Dir, or path
+// to a JSON file
+func Find(name string) (*Theme, error) {
+	if t := Builtin(name); t != nil {
+		return t, nil
+	}
+	path := name
+	if !strings.ContainsAny(name, `/\`) {
+		path = filepath.Join(Dir(), name+".json")
+	}
+	t, err := Load(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("unknown theme %q", name)
+	}
+	return t, err
+}
+
+// Load reads a theme from a JSON file
+func Load(path string) (*Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("theme %s: %w", filepath.Base(path), err)
+	}
+	if f.Name == "" {
+		f.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	base := Dark
+	if f.Extends != "" {
+		if base = Builtin(f.Extends); base == nil {
+			return nil, fmt.Errorf("theme %s: unknown base theme %q", f.Name, f.Extends)
+		}
+	}
+
+	t := base.clone()
+	t.Name = f.Name
+	if f.Dark != nil {
+		t.Dark = *f.Dark
+	}
+	for role, raw := range f.Styles {
+		if !isRole(role) {
+			return nil, fmt.Errorf("theme %s: unknown role %q", f.Name, role)
+		}
+		st, err := parseStyle(raw, t.styles[role])
+		if err != nil {
+			return nil, fmt.Errorf("theme %s: %s: %w", f.Name, role, err)
+		}
+		t.styles[role] = st
+	}
+	return t, nil
+}
+
+// parseStyle applies a JSON style, a color string or an object, over base
+func parseStyle(raw json.RawMessage, base tcell.Style) (tcell.Style, error) {
+	var spec styleSpec
+	if err := json.Unmarshal(raw, &spec.FG); err != nil {
+		spec = styleSpec{}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return base, fmt.Errorf("want a color or an object: %w", err)
+		}
+	}
+
+	fg, bg, attrs := base.Decompose()
+	var err error
+	if spec.FG != "" {
+		if fg, err = parseColor(spec.FG); err != nil {
+			return base, err
+		}
+	}
+	if spec.BG != "" {
+		if bg, err = parseColor(spec.BG); err != nil {
+			return base, err
+		}
+	}
+	for _, a := range []struct {
+		on   *bool
+		mask tcell.AttrMask
+	}{
+		{spec.Bold, tcell.AttrBold},
+		{spec.Italic, tcell.AttrItalic},
+		{spec.Underline, tcell.AttrUnderline},
+		{spec.Reverse, tcell.AttrReverse},
+		{spec.StrikeThrough, tcell.AttrStrikeThrough},
+	} {
+		switch {
+		case a.on == nil:
+		case *a.on:
+			attrs |= a.mask
+		default:
+			attrs &^= a.mask
+		}
+	}
+	return tcell.StyleDefault.Foreground(fg).Background(bg).Attributes(attrs), nil
+}
+
+// parseColor accepts "#rrggbb", color names such as "navy" and "default"
+func parseColor(s string) (tcell.Color, error) {
+	if strings.EqualFold(s, "default") {
+		return tcell.ColorDefault, nil
+	}
+	c := tcell.GetColor(s)
+	if c == tcell.ColorDefault {
+		return c, fmt.Errorf("unknown color %q", s)
+	}
+	return c, nil
+}
+
+// isRole reports whether role is one themes can set
+func isRole(role string) bool {
+	if _, ok := Dark.styles[role]; ok {
+		return true
+	}
+	for _, c := range syntax.Classes() {
+		if role == SyntaxRole(c) {
+			return true
+		}
+	}
+	return false
+}
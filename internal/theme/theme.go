@@ -0,0 +1,149 @@
+package theme
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/uditrawat03/bitcode/internal/syntax"
+)
+
+// Style roles, themes map each of them to a style
+const (
+	EditorText         = "editor.text"
+	EditorCurrentLine  = "editor.currentLine"
+	EditorSelection    = "editor.selection"
+	EditorLineNumber   = "editor.lineNumber"
+	EditorMatch        = "editor.match"
+	EditorCurrentMatch = "editor.currentMatch"
+	EditorDivider      = "editor.divider"
+
+	FindBarText      = "findbar.text"
+	FindBarOptionOn  = "findbar.optionOn"
+	FindBarOptionOff = "findbar.optionOff"
+
+	SidebarText      = "sidebar.text"
+	SidebarSelected  = "sidebar.selected"
+	SidebarHover     = "sidebar.hover"
+	SidebarScrollbar = "sidebar.scrollbar"
+
+	PanelInput         = "panel.input"
+	PanelInputInactive = "panel.inputInactive"
+	PanelDim           = "panel.dim"
+	DiffAdded          = "diff.added"
+	DiffRemoved        = "diff.removed"
+
+	StatusBarText = "statusbar.text"
+	TopBarText    = "topbar.text"
+	TabActive     = "tabs.active"
+	TabInactive   = "tabs.inactive"
+
+	DialogText     = "dialog.text"
+	DialogBorder   = "dialog.border"
+	DialogTitle    = "dialog.title"
+	DialogSelected = "dialog.selected"
+)
+
+// SyntaxRole is the role used for a token class, e.g. "syntax.keyword"
+func SyntaxRole(c syntax.Class) string {
+	return "syntax." + c.String()
+}
+
+// Theme is a named set of styles
+type Theme struct {
+	Name string
+	Dark bool
+
+	styles map[string]tcell.Style
+	syntax syntax.Styles
+}
+
+// Style returns the style for a role; unknown roles get the editor text style
+func (t *Theme) Style(role string) tcell.Style {
+	if st, ok := t.styles[role]; ok {
+		return st
+	}
+	return t.styles[EditorText]
+}
+
+// Foreground returns just the text color of a role, for drawing it on
+// another role's background
+func (t *Theme) Foreground(role string) tcell.Color {
+	fg, _, _ := t.Style(role).Decompose()
+	return fg
+}
+
+// Syntax returns the styles for highlighted tokens
+func (t *Theme) Syntax() syntax.Styles {
+	if t.syntax == nil {
+		t.syntax = syntax.Styles{}
+		for _, c := range syntax.Classes() {
+			if st, ok := t.styles[SyntaxRole(c)]; ok {
+				t.syntax[c] = st
+			}
+		}
+	}
+	return t.syntax
+}
+
+// clone copies t so it can be changed without touching the original
+func (t *Theme) clone() *Theme {
+	c := &Theme{Name: t.Name, Dark: t.Dark, styles: make(map[string]tcell.Style, len(t.styles))}
+	for role, st := range t.styles {
+		c.styles[role] = st
+	}
+	return c
+}
+
+// ForColors maps the theme onto a terminal with fewer colors; tcell would
+// pick the nearest colors too, doing it here keeps every role consistent
+func (t *Theme) ForColors(colors int) *Theme {
+	if colors >= 1<<24 || colors <= 0 {
+		return t
+	}
+	palette := make([]tcell.Color, min(colors, 256))
+	for i := range palette {
+		palette[i] = tcell.PaletteColor(i)
+	}
+
+	c := t.clone()
+	for role, st := range c.styles {
+		fg, bg, attrs := st.Decompose()
+		c.styles[role] = tcell.StyleDefault.
+			Foreground(nearest(fg, palette)).
+			Background(nearest(bg, palette)).
+			Attributes(attrs)
+	}
+	return c
+}
+
+func nearest(c tcell.Color, palette []tcell.Color) tcell.Color {
+	if c == tcell.ColorDefault || !c.Valid() {
+		return c
+	}
+	if c&tcell.ColorIsRGB == 0 && int(c-tcell.ColorValid) < len(palette) {
+		return c
+	}
+	return tcell.FindColor(c, palette)
+}
+
+var (
+	chosen  = Dark
+	current = Dark
+	colors  = 1 << 24
+)
+
+// Current returns the theme in use
+func Current() *Theme {
+	return current
+}
+
+// Set switches to t, adjusted to the terminal's colors
+func Set(t *Theme) {
+	chosen = t
+	current = t.ForColors(colors)
+}
+
+// SetColors tells the theme how many colors the terminal supports,
+// see tcell.Screen.Colors
+func SetColors(n int) {
+	colors = n
+	current = chosen.ForColors(n)
+}
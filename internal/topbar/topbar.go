@@ -1,41 +1,178 @@
 package topbar
 
-import "github.com/gdamore/tcell/v2"
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/uditrawat03/bitcode/internal/theme"
+)
+
+// Tab is an open buffer shown in the bar
+type Tab struct {
+	Title    string
+	Modified bool
+}
 
 type TopBar struct {
 	x, y, width, height int
 	focused             bool
+
+	tabs   []Tab
+	active int
+	// first tab drawn, so the active one stays in view
+	scroll int
+
+	// mouse state: a press on a tab may turn into a drag
+	pressed  bool
+	dragging int
+
+	onSelect func(i int)
+	onClose  func(i int)
+	onMove   func(from, to int)
 }
 
 func CreateTopBar(x, y, width, height int) *TopBar {
-	return &TopBar{x: x, y: y, width: width, height: height}
+	return &TopBar{x: x, y: y, width: width, height: height, dragging: -1}
 }
 
 // Focusable
-func (tb *TopBar) Focus()                           { tb.focused = true }
-func (tb *TopBar) Blur()                            { tb.focused = false }
-func (tb *TopBar) IsFocused() bool                  { return tb.focused }
-func (tb *TopBar) HandleKey(ev *tcell.EventKey)     {}
-func (tb *TopBar) HandleMouse(ev *tcell.EventMouse) {}
+func (tb *TopBar) Focus()          { tb.focused = true }
+func (tb *TopBar) Blur()           { tb.focused = false }
+func (tb *TopBar) IsFocused() bool { return tb.focused }
+
+// SetBounds moves and resizes the bar
+func (tb *TopBar) SetBounds(x, y, width, height int) {
+	tb.x, tb.y, tb.width, tb.height = x, y, width, height
+}
+
+// SetTabs replaces the tabs; active is the index of the shown buffer, -1
+// for none
+func (tb *TopBar) SetTabs(tabs []Tab, active int) {
+	tb.tabs = tabs
+	tb.active = active
+	tb.scrollToActive()
+}
+
+// SetOnSelect is called with the index of a tab that was clicked
+func (tb *TopBar) SetOnSelect(cb func(i int)) { tb.onSelect = cb }
+
+// SetOnClose is called when a tab's close button is clicked or the tab is
+// middle-clicked
+func (tb *TopBar) SetOnClose(cb func(i int)) { tb.onClose = cb }
+
+// SetOnMove is called while a tab is dragged over another one
+func (tb *TopBar) SetOnMove(cb func(from, to int)) { tb.onMove = cb }
+
+// label is how a tab reads: name, a dot when modified and the close button
+func (t Tab) label() string {
+	label := " " + t.Title
+	if t.Modified {
+		label += " ●"
+	}
+	return label + " × "
+}
+
+// span is where a tab is drawn
+type span struct {
+	start, end int // columns relative to the bar
+}
+
+// closeCol is the column of the close button
+func (s span) closeCol() int {
+	return s.end - 2
+}
+
+// spans lays out the tabs from tb.scroll, leaving out those past the edge
+func (tb *TopBar) spans() []span {
+	spans := make([]span, len(tb.tabs))
+	col := 0
+	for i := range tb.tabs {
+		if i < tb.scroll {
+			spans[i] = span{-1, -1}
+			continue
+		}
+		w := runewidth.StringWidth(tb.tabs[i].label())
+		if col+w > tb.width {
+			spans[i] = span{-1, -1}
+			col = tb.width
+			continue
+		}
+		spans[i] = span{col, col + w}
+		col += w + 1 // separator
+	}
+	return spans
+}
+
+// scrollToActive moves tb.scroll so the active tab fits
+func (tb *TopBar) scrollToActive() {
+	tb.scroll = min(tb.scroll, max(0, len(tb.tabs)-1))
+	if tb.active < 0 || tb.active >= len(tb.tabs) {
+		return
+	}
+	if tb.active < tb.scroll {
+		tb.scroll = tb.active
+		return
+	}
+	for tb.scroll < tb.active && tb.spans()[tb.active].start < 0 {
+		tb.scroll++
+	}
+}
+
+// tabAt returns the tab under column x of the screen, -1 for none
+func (tb *TopBar) tabAt(x int) (int, span) {
+	for i, s := range tb.spans() {
+		if s.start >= 0 && x >= tb.x+s.start && x < tb.x+s.end {
+			return i, s
+		}
+	}
+	return -1, span{}
+}
 
 // Draw
 func (tb *TopBar) Draw(s tcell.Screen) {
-	style := tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorGreen)
+	th := theme.Current()
+	style := th.Style(theme.TopBarText)
 	if tb.focused {
 		style = style.Reverse(true)
 	}
-
-	content := " Top Bar Content "
 	for row := 0; row < tb.height; row++ {
 		for col := 0; col < tb.width; col++ {
 			s.SetContent(tb.x+col, tb.y+row, ' ', nil, style)
 		}
 	}
 
-	for i, r := range content {
-		if i >= tb.width {
-			break
+	if len(tb.tabs) == 0 {
+		for i, r := range " bitcode " {
+			if i >= tb.width {
+				break
+			}
+			s.SetContent(tb.x+i, tb.y, r, nil, style)
+		}
+		return
+	}
+
+	for i, sp := range tb.spans() {
+		if sp.start < 0 {
+			continue
+		}
+		tabStyle := th.Style(theme.TabInactive)
+		if i == tb.active {
+			tabStyle = th.Style(theme.TabActive)
 		}
-		s.SetContent(tb.x+i, tb.y, r, nil, style)
+		col := tb.x + sp.start
+		for _, r := range tb.tabs[i].label() {
+			s.SetContent(col, tb.y, r, nil, tabStyle)
+			col += runewidth.RuneWidth(r)
+		}
+		if sp.end < tb.width {
+			s.SetContent(tb.x+sp.end, tb.y, '│', nil, style)
+		}
+	}
+
+	// more tabs than fit
+	if tb.scroll > 0 {
+		s.SetContent(tb.x, tb.y, '‹', nil, style)
+	}
+	if spans := tb.spans(); spans[len(spans)-1].start < 0 {
+		s.SetContent(tb.x+tb.width-1, tb.y, '›', nil, style)
 	}
 }
@@ -0,0 +1,16 @@
+package topbar
+
+import "github.com/gdamore/tcell/v2"
+
+// HandleKey moves between tabs with Left/Right while the bar has focus
+func (tb *TopBar) HandleKey(ev *tcell.EventKey) {
+	if !tb.focused || len(tb.tabs) == 0 || tb.onSelect == nil {
+		return
+	}
+	switch ev.Key() {
+	case tcell.KeyLeft:
+		tb.onSelect((tb.active + len(tb.tabs) - 1) % len(tb.tabs))
+	case tcell.KeyRight:
+		tb.onSelect((tb.active + 1) % len(tb.tabs))
+	}
+}
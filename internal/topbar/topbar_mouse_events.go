@@ -0,0 +1,52 @@
+package topbar
+
+import "github.com/gdamore/tcell/v2"
+
+// HandleMouse switches tabs on click, closes them with the close button or
+// a middle click, and reorders them by dragging
+func (tb *TopBar) HandleMouse(ev *tcell.EventMouse) {
+	x, y := ev.Position()
+
+	if ev.Buttons()&tcell.Button1 == 0 {
+		tb.pressed = false
+		tb.dragging = -1
+	}
+
+	// dragging a tab over its neighbours moves it
+	if tb.pressed && ev.Buttons()&tcell.Button1 != 0 {
+		if i, _ := tb.tabAt(x); i >= 0 && tb.dragging >= 0 && i != tb.dragging {
+			if tb.onMove != nil {
+				tb.onMove(tb.dragging, i)
+			}
+			tb.dragging = i
+		}
+		return
+	}
+
+	if y != tb.y {
+		return
+	}
+	i, sp := tb.tabAt(x)
+	if i < 0 {
+		return
+	}
+
+	switch {
+	case ev.Buttons()&tcell.Button3 != 0:
+		if tb.onClose != nil {
+			tb.onClose(i)
+		}
+	case ev.Buttons()&tcell.Button1 != 0:
+		tb.pressed = true
+		if x == tb.x+sp.closeCol() {
+			if tb.onClose != nil {
+				tb.onClose(i)
+			}
+			return
+		}
+		tb.dragging = i
+		if tb.onSelect != nil {
+			tb.onSelect(i)
+		}
+	}
+}
@@ -1,14 +1,24 @@
 package ui
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/gdamore/tcell/v2"
 	dialog "github.com/uditrawat03/bitcode/internal/Dialog"
 	"github.com/uditrawat03/bitcode/internal/buffer"
+	"github.com/uditrawat03/bitcode/internal/config"
 	"github.com/uditrawat03/bitcode/internal/editor"
+	"github.com/uditrawat03/bitcode/internal/findpanel"
+	"github.com/uditrawat03/bitcode/internal/keymap"
 	"github.com/uditrawat03/bitcode/internal/layout"
+	"github.com/uditrawat03/bitcode/internal/panes"
 	"github.com/uditrawat03/bitcode/internal/sidebar"
 	"github.com/uditrawat03/bitcode/internal/statusbar"
 	"github.com/uditrawat03/bitcode/internal/topbar"
+	"github.com/uditrawat03/bitcode/internal/watcher"
+	"github.com/uditrawat03/bitcode/internal/workspace"
 )
 
 type Focusable interface {
@@ -25,12 +35,39 @@ type ScreenManager struct {
 	bufferManager *buffer.BufferManager
 	screen        tcell.Screen
 
+	// editor is the one in the focused pane
 	editor    *editor.Editor
+	panes     *panes.Panes
 	sidebar   *sidebar.Sidebar
 	topBar    *topbar.TopBar
 	statusBar *statusbar.StatusBar
+	findPanel *findpanel.FindPanel
+
+	// Find in Files takes the sidebar's place while open
+	findPanelOpen bool
+	searchCancel  context.CancelFunc
+	// the last Replace in Files, until it is undone or replaced
+	lastReplace *projectReplace
 
 	dialog *dialog.Dialog
+	// buffers changed on disk while another dialog was open
+	pendingChanges []*buffer.Buffer
+
+	// open buffers in tab order
+	tabs []*buffer.Buffer
+
+	// key bindings and the commands they run
+	keymap   *keymap.Keymap
+	commands map[string]*Command
+
+	// settings from config.toml, reloaded when the files change
+	config        *config.Config
+	configPaths   []string
+	configWatcher *watcher.Watcher
+
+	// files for Go to File, most recently opened first
+	fileIndex   *workspace.Index
+	recentFiles []string
 
 	focusOrder []Focusable
 	focusedIdx int
@@ -40,10 +77,37 @@ func CreateScreenManager() *ScreenManager {
 	sm := &ScreenManager{
 		layoutManager: layout.CreateLayoutManager(),
 		bufferManager: buffer.NewBufferManager(),
+		keymap:        keymap.Defaults(),
 	}
+	sm.registerCommands()
 	return sm
 }
 
+// SetScreen gives the manager the screen early so background work can post
+// events to the main loop before the first Draw
+func (sm *ScreenManager) SetScreen(screen tcell.Screen) {
+	sm.screen = screen
+}
+
+// How long to wait before posting again to a full event queue
+const postRetryInterval = 20 * time.Millisecond
+
+// post runs fn on the main loop; safe to call from any goroutine. It fails
+// when the event queue is full.
+func (sm *ScreenManager) post(fn func()) error {
+	if sm.screen == nil {
+		return nil
+	}
+	return sm.screen.PostEvent(tcell.NewEventInterrupt(fn))
+}
+
+// HandleInterrupt runs work queued with post
+func (sm *ScreenManager) HandleInterrupt(ev *tcell.EventInterrupt) {
+	if fn, ok := ev.Data().(func()); ok {
+		fn()
+	}
+}
+
 // Initialize components and focus order
 func (sm *ScreenManager) InitComponents(screenWidth, screenHeight int) {
 	// Update layout
@@ -53,14 +117,15 @@ func (sm *ScreenManager) InitComponents(screenWidth, screenHeight int) {
 	// TopBar
 	tbX, tbY, tbW, tbH := l.GetTopBarArea(screenWidth, screenHeight)
 	sm.topBar = topbar.CreateTopBar(tbX, tbY, tbW, tbH)
+	sm.initTabs()
 
 	// Sidebar
 	sbX, sbY, sbW, sbH := l.GetSidebarArea(screenWidth, screenHeight)
 	sm.sidebar = sidebar.CreateSidebar(sbX, sbY, sbW, sbH)
 	sm.sidebar.SetOnFileOpen(func(path string) {
-		buf := sm.bufferManager.Open(path)
-		sm.editor.SetBuffer(buf)
+		sm.openFile(path)
 	})
+	sm.sidebar.SetOnResize(sm.resizeSidebar)
 
 	sm.sidebar.SetFocusCallback(func() {
 		sm.focusOrder[sm.focusedIdx].Blur()
@@ -68,23 +133,47 @@ func (sm *ScreenManager) InitComponents(screenWidth, screenHeight int) {
 		sm.focusOrder[sm.focusedIdx].Focus()
 	})
 
-	// Editor
-	edX, edY, edW, edH := l.GetEditorArea(screenWidth, screenHeight)
-	sm.editor = editor.CreateEditor(edX, edY, edW, edH)
-	sm.editor.SetFocusCallback(func() {
+	// Find in Files, drawn over the sidebar
+	sm.findPanel = findpanel.CreateFindPanel(sbX, sbY, sbW, sbH, sm.sidebar.Tree.Root.Path)
+	sm.findPanel.SetOnSearch(sm.runProjectSearch)
+	sm.findPanel.SetOnOpen(sm.openSearchMatch)
+	sm.findPanel.SetOnReplace(sm.confirmProjectReplace)
+	sm.findPanel.SetOnClose(sm.closeFindPanel)
+	sm.findPanel.SetFocusCallback(func() {
 		sm.focusOrder[sm.focusedIdx].Blur()
-		sm.focusedIdx = 1 // editor index in focusOrder
+		sm.focusedIdx = 0 // sidebar slot
 		sm.focusOrder[sm.focusedIdx].Focus()
 	})
 
+	// index the workspace in the background for Go to File
+	sm.fileIndex = workspace.NewIndex(sm.sidebar.Tree.Root.Path)
+	sm.fileIndex.Rebuild(nil)
+
+	// Editor
+	edX, edY, edW, edH := l.GetEditorArea(screenWidth, screenHeight)
 	// StatusBar
 	stX, stY, stW, stH := l.GetStatusBarArea(screenWidth, screenHeight)
 	sm.statusBar = statusbar.CreateStatusBar(stX, stY, stW, stH)
 
+	// Editor panes, starting with one
+	sm.editor = sm.newEditor()
+	sm.panes = panes.CreatePanes(sm.editor)
+	sm.panes.SetOnFocus(func(ed *editor.Editor) { sm.editor = ed })
+	sm.panes.SetBounds(edX, edY, edW, edH)
+
+	sm.bufferManager.SetOnExternalChange(func(buf *buffer.Buffer) {
+		// a missed change would leave the buffer stale, wait for room
+		for sm.post(func() { sm.handleExternalChange(buf) }) != nil {
+			time.Sleep(postRetryInterval)
+		}
+	})
+	sm.bufferManager.SetOnClosed(sm.removeTab)
+	sm.bufferManager.SetOnRenamed(sm.handleRenamed)
+
 	// Set focus order
 	sm.focusOrder = []Focusable{
 		sm.sidebar,
-		sm.editor,
+		sm.panes,
 		sm.topBar,
 		sm.statusBar,
 	}
@@ -93,6 +182,46 @@ func (sm *ScreenManager) InitComponents(screenWidth, screenHeight int) {
 	sm.focusOrder[sm.focusedIdx].Focus()
 }
 
+// openFile opens path in the editor, reporting load errors in the status bar
+func (sm *ScreenManager) openFile(path string) bool {
+	buf, err := sm.bufferManager.Open(path)
+	if err != nil {
+		sm.statusBar.SetMessage("Open failed: " + err.Error())
+		return false
+	}
+	sm.showBuffer(buf)
+	sm.addRecentFile(buf.File)
+	sm.statusBar.SetMessage("")
+	return true
+}
+
+// updateStatusInfo shows details about the active buffer in the status bar
+func (sm *ScreenManager) updateStatusInfo() {
+	sm.updateTabs()
+
+	buf := sm.editor.GetBuffer()
+	if buf == nil {
+		sm.statusBar.SetInfo("")
+		return
+	}
+
+	modified := ""
+	if buf.Modified() {
+		modified = "[+]  "
+	}
+
+	lineEnding := "LF"
+	if buf.LineEnding == buffer.CRLF {
+		lineEnding = "CRLF"
+	}
+	encoding := buf.Encoding
+	if buf.BOM {
+		encoding += " BOM"
+	}
+	line, col := sm.editor.Cursor()
+	sm.statusBar.SetInfo(fmt.Sprintf("%sLn %d, Col %d  %s  %s", modified, line+1, col+1, encoding, lineEnding))
+}
+
 // Switch focus to next component
 func (sm *ScreenManager) FocusNext() {
 	if sm.dialog != nil {
@@ -101,19 +230,28 @@ func (sm *ScreenManager) FocusNext() {
 	}
 	sm.focusOrder[sm.focusedIdx].Blur()
 	sm.focusedIdx = (sm.focusedIdx + 1) % len(sm.focusOrder)
+	if sm.focusedIdx == 0 && !sm.sidebarShown() {
+		sm.focusedIdx = 1 // skip the sidebar while it has no room
+	}
 	sm.focusOrder[sm.focusedIdx].Focus()
 }
 
 // Draw all components
 func (sm *ScreenManager) Draw(screen tcell.Screen) {
 	sm.screen = screen
-	screenWidth, screenHeight := sm.screen.Size()
-	sm.layoutManager.UpdateLayout(screenWidth, screenHeight)
+
+	sm.updateStatusInfo()
 
 	// Redraw components
 	sm.topBar.Draw(screen)
-	sm.sidebar.Draw(screen)
-	sm.editor.Draw(screen)
+	if sm.sidebarShown() {
+		if sm.findPanelOpen {
+			sm.findPanel.Draw(screen)
+		} else {
+			sm.sidebar.Draw(screen)
+		}
+	}
+	sm.panes.Draw(screen)
 	sm.statusBar.Draw(screen)
 
 	// Draw dialog on top
@@ -0,0 +1,254 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/uditrawat03/bitcode/internal/buffer"
+	"github.com/uditrawat03/bitcode/internal/config"
+	"github.com/uditrawat03/bitcode/internal/keymap"
+	"github.com/uditrawat03/bitcode/internal/panes"
+)
+
+// Command is an action the keymap and the command palette can run
+type Command struct {
+	ID string
+	// Title is shown in the palette; commands without one aren't listed
+	Title string
+	Run   func()
+	// Enabled reports whether the command applies right now, nil means
+	// always. The palette hides disabled commands.
+	Enabled func() bool
+}
+
+func (sm *ScreenManager) register(cmd *Command) {
+	sm.commands[cmd.ID] = cmd
+}
+
+// registerCommands fills the registry keymap command names run from
+func (sm *ScreenManager) registerCommands() {
+	sm.commands = map[string]*Command{}
+	hasBuffer := func() bool { return sm.editor.GetBuffer() != nil }
+	panelOpen := func() bool { return sm.findPanelOpen }
+
+	// File
+	sm.register(&Command{ID: "file.new", Title: "New File", Run: sm.openNewFileDialog})
+	sm.register(&Command{ID: "file.quickOpen", Title: "Go to File", Run: sm.openQuickOpen})
+	sm.register(&Command{ID: "file.reopenWithEncoding", Title: "Reopen with Encoding", Enabled: hasBuffer,
+		Run: func() { sm.openEncodingDialog(true) }})
+	sm.register(&Command{ID: "file.saveWithEncoding", Title: "Save with Encoding", Enabled: hasBuffer,
+		Run: func() { sm.openEncodingDialog(false) }})
+	sm.register(&Command{ID: "file.saveAll", Title: "Save All", Run: sm.saveAll})
+
+	// Editor
+	for _, c := range []struct{ id, title string }{
+		{"editor.save", "Save"},
+		{"editor.find", "Find"},
+		{"editor.replace", "Replace"},
+		{"editor.undo", "Undo"},
+		{"editor.redo", "Redo"},
+		{"editor.cut", "Cut"},
+		{"editor.copy", "Copy"},
+		{"editor.paste", "Paste"},
+		{"editor.selectAll", "Select All"},
+		{"editor.toggleLineEnding", "Toggle Line Endings (LF/CRLF)"},
+		{"editor.toggleWrap", "Toggle Word Wrap"},
+	} {
+		sm.register(&Command{ID: c.id, Title: c.title, Enabled: hasBuffer, Run: func() {
+			sm.restoreEditorFocus()
+			sm.editor.Execute(c.id)
+		}})
+	}
+
+	// Find bar
+	findOpen := func() bool { return sm.editor.IsFindOpen() }
+	for _, c := range []struct{ id, title string }{
+		{"find.accept", "Find: Next Match or Replace"},
+		{"find.next", "Find: Next Match"},
+		{"find.previous", "Find: Previous Match"},
+		{"find.replaceAll", "Find: Replace All"},
+		{"find.toggleCase", "Find: Toggle Match Case"},
+		{"find.toggleWord", "Find: Toggle Whole Word"},
+		{"find.toggleRegexp", "Find: Toggle Regular Expression"},
+		{"find.toggleSelection", "Find: Toggle In Selection"},
+	} {
+		sm.register(&Command{ID: c.id, Title: c.title, Enabled: findOpen, Run: func() {
+			sm.restoreEditorFocus()
+			sm.editor.Execute(c.id)
+		}})
+	}
+
+	// Ctrl+S on a file that changed on disk asks before overwriting it
+	sm.commands["editor.save"].Run = func() {
+		if buf := sm.editor.GetBuffer(); buf != nil && buf.DiskChanged() {
+			sm.promptExternalChange(buf)
+			return
+		}
+		sm.editor.Execute("editor.save")
+	}
+
+	// Sidebar
+	sm.register(&Command{ID: "sidebar.delete", Title: "Delete Selected File", Run: func() {
+		if node := sm.sidebar.GetSelectedNode(); node != nil {
+			sm.confirmDeleteNode(node)
+		}
+	}})
+	sm.register(&Command{ID: "sidebar.rename", Title: "Rename Selected File", Run: func() {
+		sm.openRenameDialog(sm.sidebar.GetSelectedNode())
+	}})
+
+	// Search
+	sm.register(&Command{ID: "search.findInFiles", Title: "Find in Files", Run: func() { sm.openFindPanel(false) }})
+	sm.register(&Command{ID: "search.replaceInFiles", Title: "Replace in Files", Run: func() { sm.openFindPanel(true) }})
+	for _, c := range []struct{ id, title string }{
+		{"search.toggleCase", "Find in Files: Toggle Match Case"},
+		{"search.toggleWord", "Find in Files: Toggle Whole Word"},
+		{"search.toggleRegexp", "Find in Files: Toggle Regular Expression"},
+		{"search.replaceChecked", "Find in Files: Replace Checked"},
+	} {
+		sm.register(&Command{ID: c.id, Title: c.title, Enabled: panelOpen, Run: func() { sm.findPanel.Execute(c.id) }})
+	}
+	sm.register(&Command{ID: "search.undoReplace", Title: "Undo Replace in Files",
+		Enabled: func() bool { return sm.lastReplace != nil }, Run: sm.undoReplace})
+
+	// Tabs
+	hasTabs := func() bool { return len(sm.tabs) > 0 }
+	sm.register(&Command{ID: "tabs.next", Title: "Next Tab", Enabled: hasTabs, Run: func() { sm.switchTab(1) }})
+	sm.register(&Command{ID: "tabs.previous", Title: "Previous Tab", Enabled: hasTabs, Run: func() { sm.switchTab(-1) }})
+	sm.register(&Command{ID: "tabs.close", Title: "Close Tab", Enabled: hasTabs, Run: sm.closeActiveTab})
+	sm.register(&Command{ID: "tabs.closeAll", Title: "Close All Tabs", Enabled: hasTabs, Run: sm.closeAllTabs})
+
+	// Panes
+	manyPanes := func() bool { return sm.panes.Count() > 1 }
+	sm.register(&Command{ID: "panes.splitRight", Title: "Split Editor Right", Run: func() { sm.splitPane(panes.Vertical) }})
+	sm.register(&Command{ID: "panes.splitDown", Title: "Split Editor Down", Run: func() { sm.splitPane(panes.Horizontal) }})
+	sm.register(&Command{ID: "panes.close", Title: "Close Editor Pane", Enabled: manyPanes, Run: sm.closePane})
+	sm.register(&Command{ID: "panes.focusNext", Title: "Focus Next Editor Pane", Enabled: manyPanes, Run: func() {
+		sm.panes.FocusNext(1)
+		sm.restoreEditorFocus()
+	}})
+	for _, c := range []struct {
+		id, title string
+		dx, dy    int
+	}{
+		{"panes.focusLeft", "Focus Editor Pane Left", -1, 0},
+		{"panes.focusRight", "Focus Editor Pane Right", 1, 0},
+		{"panes.focusUp", "Focus Editor Pane Above", 0, -1},
+		{"panes.focusDown", "Focus Editor Pane Below", 0, 1},
+	} {
+		sm.register(&Command{ID: c.id, Title: c.title, Enabled: manyPanes, Run: func() { sm.focusPane(c.dx, c.dy) }})
+	}
+
+	// View
+	sm.register(&Command{ID: "view.toggleSidebar", Title: "Toggle Sidebar", Run: sm.toggleSidebar})
+	sm.register(&Command{ID: "view.changeTheme", Title: "Change Theme", Run: sm.openThemeDialog})
+	sm.register(&Command{ID: "palette.open", Title: "Show All Commands", Run: sm.openCommandPalette})
+	sm.register(&Command{ID: "keys.list", Title: "Keyboard Shortcuts", Run: sm.showKeyBindings})
+
+	// Dialogs, only reachable through keys
+	sm.register(&Command{ID: "dialog.submit", Run: func() {
+		if sm.dialog != nil {
+			sm.dialog.Submit()
+		}
+	}})
+	sm.register(&Command{ID: "dialog.cancel", Run: func() {
+		if sm.dialog != nil {
+			sm.dialog.Cancel()
+		}
+	}})
+}
+
+func (sm *ScreenManager) runCommand(id string) {
+	cmd, ok := sm.commands[id]
+	if !ok {
+		sm.statusBar.SetMessage("Unknown command: " + id)
+		return
+	}
+	cmd.Run()
+}
+
+// keyContexts are the keymap contexts for the focused component, most
+// specific first
+func (sm *ScreenManager) keyContexts() []keymap.Context {
+	switch {
+	case sm.dialog != nil:
+		// dialogs are modal, global keys don't reach past them
+		return []keymap.Context{keymap.Dialog}
+	case sm.editor.IsFocused() && sm.editor.IsFindOpen():
+		// the find bar takes the typing keys, editor bindings would edit
+		// the buffer behind it
+		return []keymap.Context{keymap.Find, keymap.Global}
+	case sm.editor.IsFocused():
+		return []keymap.Context{keymap.Editor, keymap.Global}
+	case sm.findPanelOpen && sm.findPanel.IsFocused():
+		return []keymap.Context{keymap.Search, keymap.Global}
+	case sm.sidebar.IsFocused():
+		return []keymap.Context{keymap.Sidebar, keymap.Global}
+	}
+	return []keymap.Context{keymap.Global}
+}
+
+// buildKeymap applies the config's [keys] tables over the defaults
+func (sm *ScreenManager) buildKeymap(bindings []config.KeyBinding) []string {
+	km := keymap.Defaults()
+	var problems []string
+	for _, kb := range bindings {
+		if kb.Command != "" && sm.commands[kb.Command] == nil {
+			problems = append(problems, fmt.Sprintf("%s:%d: unknown command %q", kb.Path, kb.Line, kb.Command))
+			continue
+		}
+		if err := km.Bind(kb.Context, kb.Keys, kb.Command); err != nil {
+			problems = append(problems, fmt.Sprintf("%s:%d: %v", kb.Path, kb.Line, err))
+		}
+	}
+	for _, c := range km.Conflicts() {
+		problems = append(problems, "key conflict: "+c)
+	}
+	sm.keymap = km
+	return problems
+}
+
+// showKeyBindings lists every binding in a scratch buffer
+func (sm *ScreenManager) showKeyBindings() {
+	var sb strings.Builder
+	sb.WriteString("Keyboard shortcuts, change them in the [keys] tables of " + config.UserPath() + "\n")
+	var ctx keymap.Context
+	for _, b := range sm.keymap.Bindings() {
+		if b.Context != ctx {
+			ctx = b.Context
+			fmt.Fprintf(&sb, "\n[%s]\n", ctx)
+		}
+		title := ""
+		if cmd := sm.commands[b.Command]; cmd != nil {
+			title = cmd.Title
+		}
+		fmt.Fprintf(&sb, "  %-24s %-28s %s\n", keymap.FormatSequence(b.Keys), b.Command, title)
+	}
+	if conflicts := sm.keymap.Conflicts(); len(conflicts) > 0 {
+		sb.WriteString("\nConflicts\n")
+		for _, c := range conflicts {
+			sb.WriteString("  " + c + "\n")
+		}
+	}
+	sm.showBuffer(buffer.NewScratchBuffer("Keyboard Shortcuts", sb.String()))
+	sm.restoreEditorFocus()
+}
+
+// toggleSidebar hides the sidebar (or Find in Files) so the editor gets the
+// whole width, or brings it back
+func (sm *ScreenManager) toggleSidebar() {
+	sm.setSidebarVisible(!sm.layoutManager.SidebarVisible())
+}
+
+func (sm *ScreenManager) setSidebarVisible(visible bool) {
+	if visible == sm.layoutManager.SidebarVisible() {
+		return
+	}
+	sm.layoutManager.SetSidebarVisible(visible)
+	sm.applyLayout()
+	if !visible && sm.focusedIdx == 0 {
+		sm.focusOrder[0].Blur()
+		sm.focusedIdx = 1 // editor index
+		sm.editor.Focus()
+	}
+}
@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/uditrawat03/bitcode/internal/config"
+	"github.com/uditrawat03/bitcode/internal/editor"
+	"github.com/uditrawat03/bitcode/internal/theme"
+	"github.com/uditrawat03/bitcode/internal/watcher"
+)
+
+// LoadConfig applies the user and project config files and reloads them
+// whenever one of them is saved
+func (sm *ScreenManager) LoadConfig() {
+	sm.configPaths = []string{config.UserPath(), config.ProjectPath(sm.sidebar.Tree.Root.Path)}
+	sm.reloadConfig()
+
+	w, err := watcher.New()
+	if err != nil {
+		log.Printf("Config reload disabled: %v", err)
+		return
+	}
+	for _, path := range sm.configPaths {
+		// the folder is what gets watched, create it so a config file
+		// written there later is picked up; a relative path means there
+		// was no config folder to put it in
+		if filepath.IsAbs(path) {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				log.Printf("Unable to watch %s: %v", path, err)
+				continue
+			}
+		}
+		if err := w.Add(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Unable to watch %s: %v", path, err)
+		}
+	}
+	sm.configWatcher = w
+	go func() {
+		for range w.Events() {
+			// a dropped event would leave the old settings, wait for room
+			for sm.post(sm.reloadConfig) != nil {
+				time.Sleep(postRetryInterval)
+			}
+		}
+	}()
+}
+
+// reloadConfig reads the config files again and applies what changed
+func (sm *ScreenManager) reloadConfig() {
+	cfg, err := config.Load(sm.configPaths...)
+	first := sm.config == nil
+	var problems []string
+	if err != nil {
+		problems = strings.Split(err.Error(), "\n")
+	}
+	problems = append(problems, sm.applyConfig(cfg)...)
+
+	switch {
+	case len(problems) > 0:
+		sm.statusBar.SetMessage("Config: " + strings.Join(problems, "; "))
+	case !first:
+		sm.statusBar.SetMessage("Config reloaded")
+	}
+}
+
+// applyConfig switches to cfg and returns problems found on the way
+func (sm *ScreenManager) applyConfig(cfg *config.Config) []string {
+	prev := sm.config
+	sm.config = cfg
+
+	// like the theme, keep a wrap toggled with Alt+Z unless the setting changed
+	mode, ok := editor.ParseWrapMode(cfg.Editor.Wrap)
+	wrapChanged := ok && (prev == nil || prev.Editor.Wrap != cfg.Editor.Wrap)
+	for _, ed := range sm.panes.Editors() {
+		ed.SetTabSize(cfg.Editor.TabSize)
+		if wrapChanged {
+			ed.SetWrapMode(mode)
+		}
+	}
+	problems := sm.buildKeymap(cfg.Keys)
+
+	sm.layoutManager.SetSidebarWidth(cfg.Layout.SidebarWidth)
+	sm.applyLayout()
+
+	// only when the setting changed, so a theme picked with Alt+T survives
+	// unrelated edits to the file
+	if prev == nil || prev.Theme != cfg.Theme {
+		if t, err := theme.Find(cfg.Theme); err == nil {
+			theme.Set(t)
+		}
+	}
+
+	if sm.screen != nil {
+		if cfg.Terminal.Mouse {
+			sm.screen.EnableMouse()
+		} else {
+			sm.screen.DisableMouse()
+		}
+		if cfg.Terminal.Paste {
+			sm.screen.EnablePaste()
+		} else {
+			sm.screen.DisablePaste()
+		}
+	}
+	return problems
+}
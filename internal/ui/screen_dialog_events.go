@@ -1,12 +1,15 @@
 package ui
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
 	dialog "github.com/uditrawat03/bitcode/internal/Dialog"
+	"github.com/uditrawat03/bitcode/internal/buffer"
+	"github.com/uditrawat03/bitcode/internal/theme"
 	"github.com/uditrawat03/bitcode/internal/treeview"
 )
 
@@ -23,6 +26,7 @@ func (sm *ScreenManager) CloseDialog() {
 		sm.dialog = nil
 		sm.screen.HideCursor()
 	}
+	sm.nextPendingChange()
 }
 
 func (sm *ScreenManager) IsDialogOpen() bool {
@@ -92,8 +96,7 @@ func (sm *ScreenManager) openNewFileDialog() {
 
 			// open in editor
 			if sm.editor != nil {
-				buf := sm.bufferManager.Open(fullPath)
-				sm.editor.SetBuffer(buf)
+				sm.openFile(fullPath)
 			}
 
 			sm.CloseDialog()
@@ -117,6 +120,19 @@ func (sm *ScreenManager) confirmDeleteNode(node *treeview.Node) {
 	title := "Delete " + node.Name + "?"
 	message := "Press Enter to confirm, Esc to cancel."
 
+	// deleting closes their buffers too, say what would be lost
+	if modified := sm.bufferManager.ModifiedIn(node.Path); len(modified) > 0 {
+		var names []string
+		for i, buf := range modified {
+			if i == 8 {
+				names = append(names, fmt.Sprintf("  and %d more", len(modified)-i))
+				break
+			}
+			names = append(names, "  "+buf.DisplayName())
+		}
+		message = "Unsaved changes will be lost in:\n" + strings.Join(names, "\n") + "\n\n" + message
+	}
+
 	dialogWidth := lenLongestLine(message) + 4
 	if dialogWidth < 40 {
 		dialogWidth = 40
@@ -136,12 +152,8 @@ func (sm *ScreenManager) confirmDeleteNode(node *treeview.Node) {
 			if err != nil {
 				log.Println("Delete failed:", err)
 			} else {
-				// 1. Close editor if active file is deleted
-				if sm.editor != nil && sm.editor.GetBuffer() != nil {
-					if sm.editor.GetBuffer().File == fullPath {
-						sm.editor.SetBuffer(nil)
-					}
-				}
+				// 1. Close the buffers of the deleted files
+				sm.bufferManager.ClosePath(fullPath)
 
 				// 2. Refresh parent folder in TreeView
 				if node.Parent != nil && sm.sidebar != nil {
@@ -171,6 +183,164 @@ func (sm *ScreenManager) confirmDeleteNode(node *treeview.Node) {
 	sm.OpenDialog(dialogDelete)
 }
 
+// openRenameDialog asks for a new name for a sidebar node and renames the
+// file or folder, open buffers included
+func (sm *ScreenManager) openRenameDialog(node *treeview.Node) {
+	if node == nil || node.Parent == nil {
+		return
+	}
+
+	dialogRename := dialog.NewDialog(
+		"Rename "+node.Name, "", 40, 7,
+		func(name string) {
+			sm.CloseDialog()
+			name = strings.TrimSpace(name)
+			if name == "" || name == node.Name {
+				return
+			}
+			newPath := filepath.Join(filepath.Dir(node.Path), name)
+			if err := sm.bufferManager.Rename(node.Path, newPath); err != nil {
+				sm.statusBar.SetMessage("Rename failed: " + err.Error())
+				return
+			}
+			sm.sidebar.Tree.ReloadChildren(node.Parent)
+			sm.fileIndex.Rebuild(nil)
+			sm.statusBar.SetMessage("Renamed to " + name)
+		},
+		func(_ string) {
+			sm.CloseDialog()
+		},
+		func() {
+			sm.restoreEditorFocus()
+		},
+	)
+	dialogRename.SetInput(node.Name)
+
+	sm.OpenDialog(dialogRename)
+}
+
+// openEncodingDialog asks for an encoding and either re-reads the active
+// buffer with it (reopen) or saves the buffer converted to it
+func (sm *ScreenManager) openEncodingDialog(reopen bool) {
+	buf := sm.editor.GetBuffer()
+	title := "Save with encoding"
+	if reopen {
+		title = "Reopen with encoding"
+	}
+	description := "Current: " + buf.Encoding + " (e.g. utf-8, utf-16le, latin1, shift_jis)"
+
+	dialogEncoding := dialog.NewDialog(
+		title, description, max(lenLongestLine(description)+4, 40), 7,
+		func(name string) {
+			sm.CloseDialog()
+			report := func(err error) {
+				if err != nil {
+					sm.statusBar.SetMessage("Encoding failed: " + err.Error())
+					return
+				}
+				sm.statusBar.SetMessage("Encoding: " + buf.Encoding)
+			}
+			if !reopen {
+				report(buf.SaveWithEncoding(name))
+				return
+			}
+			// re-reading drops unsaved changes
+			sm.confirmDiscard(buf, func() {
+				err := buf.ReloadWithEncoding(name)
+				sm.showBuffer(buf)
+				report(err)
+			})
+		},
+		func(_ string) {
+			sm.CloseDialog()
+		},
+		func() {
+			sm.restoreEditorFocus()
+		},
+	)
+
+	sm.OpenDialog(dialogEncoding)
+}
+
+// openThemeDialog switches the color theme by name, built-in or from the
+// themes folder
+func (sm *ScreenManager) openThemeDialog() {
+	description := "Current: " + theme.Current().Name + " (" + strings.Join(theme.Names(), ", ") + ")"
+
+	dialogTheme := dialog.NewDialog(
+		"Change Theme", description, max(lenLongestLine(description)+4, 40), 7,
+		func(name string) {
+			sm.CloseDialog()
+			t, err := theme.Find(strings.TrimSpace(name))
+			if err != nil {
+				sm.statusBar.SetMessage("Theme failed: " + err.Error())
+				return
+			}
+			theme.Set(t)
+			sm.statusBar.SetMessage("Theme: " + t.Name)
+		},
+		func(_ string) {
+			sm.CloseDialog()
+		},
+		func() {
+			sm.restoreEditorFocus()
+		},
+	)
+
+	sm.OpenDialog(dialogTheme)
+}
+
+// RequestQuit runs quit once every modified buffer was saved or discarded
+func (sm *ScreenManager) RequestQuit(quit func()) {
+	sm.confirmBuffers(sm.bufferManager.Modified(), quit)
+}
+
+// confirmBuffers asks about each modified buffer in turn and calls proceed
+// when all of them were handled. Cancel stops the chain.
+func (sm *ScreenManager) confirmBuffers(bufs []*buffer.Buffer, proceed func()) {
+	if len(bufs) == 0 {
+		proceed()
+		return
+	}
+	sm.confirmDiscard(bufs[0], func() {
+		sm.confirmBuffers(bufs[1:], proceed)
+	})
+}
+
+// confirmDiscard asks whether to save a modified buffer before it goes away
+func (sm *ScreenManager) confirmDiscard(buf *buffer.Buffer, proceed func()) {
+	if !buf.Modified() {
+		proceed()
+		return
+	}
+
+	name := buf.DisplayName()
+
+	dialogConfirm := dialog.NewChoiceDialog(
+		"Unsaved changes",
+		name+" has unsaved changes.",
+		[]string{"Save", "Discard", "Cancel"},
+		func(choice int) {
+			sm.CloseDialog()
+			switch choice {
+			case 0:
+				if err := buf.Save(); err != nil {
+					sm.statusBar.SetMessage("Save failed: " + err.Error())
+					return
+				}
+				proceed()
+			case 1:
+				proceed()
+			}
+		},
+		func() {
+			sm.restoreEditorFocus()
+		},
+	)
+
+	sm.OpenDialog(dialogConfirm)
+}
+
 // Helpers
 func lenLongestLine(s string) int {
 	max := 0
@@ -0,0 +1,196 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	dialog "github.com/uditrawat03/bitcode/internal/Dialog"
+	"github.com/uditrawat03/bitcode/internal/buffer"
+)
+
+// handleExternalChange reacts to an open file being changed by another
+// program: clean buffers are reloaded, dirty ones ask what to do
+func (sm *ScreenManager) handleExternalChange(buf *buffer.Buffer) {
+	if !buf.DiskChanged() {
+		return
+	}
+
+	if !buf.Modified() {
+		if sm.reload(buf) {
+			sm.statusBar.SetMessage("Reloaded " + buf.DisplayName() + " (changed on disk)")
+		}
+		return
+	}
+
+	sm.promptExternalChange(buf)
+}
+
+// promptExternalChange asks whether to take the disk version of a modified buffer
+func (sm *ScreenManager) promptExternalChange(buf *buffer.Buffer) {
+	if sm.dialog != nil {
+		// don't stack dialogs, ask once the open one is closed
+		if !slices.Contains(sm.pendingChanges, buf) {
+			sm.pendingChanges = append(sm.pendingChanges, buf)
+		}
+		return
+	}
+
+	dialogChanged := dialog.NewChoiceDialog(
+		"File changed on disk",
+		buf.DisplayName()+" was changed by another program.",
+		[]string{"Reload", "Show diff", "Keep mine"},
+		func(choice int) {
+			sm.CloseDialog()
+			switch choice {
+			case 0:
+				if sm.reload(buf) {
+					sm.statusBar.SetMessage("Reloaded " + buf.DisplayName())
+				}
+			case 1:
+				sm.showDiskDiff(buf)
+			case 2:
+				buf.KeepLocal()
+				sm.statusBar.SetMessage("Kept your version of " + buf.DisplayName())
+			}
+		},
+		func() {
+			sm.restoreEditorFocus()
+		},
+	)
+
+	sm.OpenDialog(dialogChanged)
+}
+
+// reload takes the disk version of buf, reporting why when it can't
+func (sm *ScreenManager) reload(buf *buffer.Buffer) bool {
+	err := buf.Reload()
+	switch {
+	case errors.Is(err, buffer.ErrDeleted):
+		sm.statusBar.SetMessage(buf.DisplayName() + " was deleted on disk, save to keep it")
+		return false
+	case err != nil:
+		sm.statusBar.SetMessage("Reload failed: " + err.Error())
+		return false
+	}
+	return true
+}
+
+// nextPendingChange asks about the next buffer that changed on disk while
+// a dialog was open, skipping ones that were closed or settled since
+func (sm *ScreenManager) nextPendingChange() {
+	for sm.dialog == nil && len(sm.pendingChanges) > 0 {
+		buf := sm.pendingChanges[0]
+		sm.pendingChanges = sm.pendingChanges[1:]
+		if slices.Contains(sm.tabs, buf) {
+			sm.handleExternalChange(buf)
+		}
+	}
+}
+
+// showDiskDiff opens a scratch buffer with the diff from disk to the buffer.
+// The decision is asked again when the buffer is saved.
+func (sm *ScreenManager) showDiskDiff(buf *buffer.Buffer) {
+	diff, err := buf.DiffWithDisk()
+	if err != nil {
+		sm.statusBar.SetMessage("Diff failed: " + err.Error())
+		return
+	}
+	if diff == "" {
+		diff = "No differences.\n"
+	}
+	sm.showBuffer(buffer.NewScratchBuffer("diff: "+buf.DisplayName(), diff))
+	sm.statusBar.SetMessage("Showing changes on disk → buffer")
+}
+
+// saveAll saves every modified file, reporting the ones that failed
+func (sm *ScreenManager) saveAll() {
+	count := len(sm.bufferManager.Modified())
+	if count == 0 {
+		sm.statusBar.SetMessage("No unsaved changes")
+		return
+	}
+	if err := sm.bufferManager.SaveAll(); err != nil {
+		sm.statusBar.SetMessage("Save failed: " + strings.ReplaceAll(err.Error(), "\n", "; "))
+		return
+	}
+	sm.statusBar.SetMessage(fmt.Sprintf("Saved %d files", count))
+}
+
+// handleRenamed follows a buffer whose file was renamed: its editors pick
+// up the new name's highlighting and recent files point at the new path
+func (sm *ScreenManager) handleRenamed(buf *buffer.Buffer, oldPath string) {
+	for i, p := range sm.recentFiles {
+		if buffer.CanonicalPath(p) == oldPath {
+			sm.recentFiles[i] = buf.File
+		}
+	}
+	for _, ed := range sm.panes.Editors() {
+		if ed.GetBuffer() == buf {
+			line, col := ed.Cursor()
+			ed.SetBuffer(buf)
+			ed.GotoPosition(line, col)
+		}
+	}
+}
+
+// StartRecovery offers to restore buffers left behind by a crashed session
+// and then starts snapshotting modified buffers in the background
+func (sm *ScreenManager) StartRecovery() {
+	dir := buffer.RecoveryDir()
+	leftovers := buffer.ListRecovery(dir)
+	sm.bufferManager.StartRecovery(dir)
+	sm.offerRecovery(leftovers)
+}
+
+// offerRecovery asks about each recovery file in turn
+func (sm *ScreenManager) offerRecovery(files []buffer.RecoveryFile) {
+	if len(files) == 0 {
+		return
+	}
+	rf := files[0]
+	next := func() { sm.offerRecovery(files[1:]) }
+
+	description := filepath.Base(rf.Path) + " has unsaved changes from " + rf.SavedAt.Format("Jan 2 15:04") + "."
+	dialogRecover := dialog.NewChoiceDialog(
+		"Recover unsaved changes?",
+		description,
+		[]string{"Restore", "Discard", "Skip"},
+		func(choice int) {
+			sm.CloseDialog()
+			switch choice {
+			case 0:
+				buf, err := sm.bufferManager.Open(rf.Path)
+				if err != nil {
+					sm.statusBar.SetMessage("Restore failed: " + err.Error())
+					break
+				}
+				buf.Restore(rf)
+				sm.showBuffer(buf)
+				rf.Remove()
+				sm.statusBar.SetMessage("Restored " + buf.DisplayName() + ", save to keep it")
+			case 1:
+				rf.Remove()
+			}
+			next()
+		},
+		func() {
+			sm.restoreEditorFocus()
+		},
+	)
+
+	sm.OpenDialog(dialogRecover)
+}
+
+func (sm *ScreenManager) Close() {
+	sm.cancelProjectSearch()
+	if sm.fileIndex != nil {
+		sm.fileIndex.Close()
+	}
+	if sm.configWatcher != nil {
+		sm.configWatcher.Close()
+	}
+	sm.bufferManager.Shutdown()
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"github.com/gdamore/tcell/v2"
+	"github.com/uditrawat03/bitcode/internal/keymap"
 )
 
 // func (sm *ScreenManager) HandleKey(ev *tcell.EventKey) {
@@ -15,24 +16,35 @@ import (
 // 	sm.focusOrder[sm.focusedIdx].HandleKey(ev)
 // }
 
+// CapturesEscape reports whether Escape closes something instead of quitting
+func (sm *ScreenManager) CapturesEscape() bool {
+	return sm.dialog != nil || sm.editor.IsFindOpen() || (sm.findPanelOpen && sm.findPanel.IsFocused()) ||
+		len(sm.keymap.Pending()) > 0
+}
+
 func (sm *ScreenManager) HandleKey(ev *tcell.EventKey) {
-	// Dialog active
-	if sm.dialog != nil {
-		sm.dialog.HandleKey(ev)
+	// Bound keys and chords run commands
+	pending := sm.keymap.Pending()
+	cmd, result := sm.keymap.Feed(ev, sm.keyContexts()...)
+	switch result {
+	case keymap.Matched:
+		if len(pending) > 0 {
+			sm.statusBar.SetMessage("")
+		}
+		sm.runCommand(cmd)
 		return
-	}
-
-	// Ctrl+N → New File Dialog
-	if ev.Key() == tcell.KeyCtrlN {
-		sm.openNewFileDialog()
+	case keymap.Pending:
+		sm.statusBar.SetMessage("(" + keymap.FormatSequence(sm.keymap.Pending()) + ") was pressed, waiting for the next key...")
+		return
+	case keymap.Aborted:
+		seq := append(pending, keymap.FromEvent(ev))
+		sm.statusBar.SetMessage("(" + keymap.FormatSequence(seq) + ") is not bound")
 		return
 	}
 
-	if sm.sidebar.IsFocused() && ev.Key() == tcell.KeyDelete {
-		node := sm.sidebar.GetSelectedNode()
-		if node != nil {
-			sm.confirmDeleteNode(node)
-		}
+	// Dialog active
+	if sm.dialog != nil {
+		sm.dialog.HandleKey(ev)
 		return
 	}
 
@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+
+	dialog "github.com/uditrawat03/bitcode/internal/Dialog"
+	"github.com/uditrawat03/bitcode/internal/fuzzy"
+	"github.com/uditrawat03/bitcode/internal/keymap"
+)
+
+// openCommandPalette lists the commands, fuzzy filtered by what is typed,
+// and runs the picked one
+func (sm *ScreenManager) openCommandPalette() {
+	var shown []*Command
+	filter := func(query string) []dialog.Item {
+		shown = sm.paletteCommands(query)
+		items := make([]dialog.Item, len(shown))
+		for i, cmd := range shown {
+			_, positions, _ := fuzzy.Match(query, cmd.Title)
+			items[i] = dialog.Item{Label: cmd.Title, Detail: sm.bindingLabel(cmd.ID), Matches: positions}
+		}
+		return items
+	}
+
+	w, h := 80, 24
+	if sm.screen != nil {
+		sw, sh := sm.screen.Size()
+		w, h = min(w, sw-4), min(h, sh-2)
+	}
+	palette := dialog.NewListDialog("Commands", w, h, filter,
+		func(i int) {
+			cmd := shown[i]
+			sm.CloseDialog()
+			cmd.Run()
+		},
+		func(_ string) {
+			sm.CloseDialog()
+		},
+		nil,
+	)
+	sm.OpenDialog(palette)
+}
+
+// paletteCommands returns the enabled commands matching query, best first
+func (sm *ScreenManager) paletteCommands(query string) []*Command {
+	type scored struct {
+		cmd   *Command
+		score int
+	}
+	var list []scored
+	for _, cmd := range sm.commands {
+		if cmd.Title == "" || (cmd.Enabled != nil && !cmd.Enabled()) {
+			continue
+		}
+		score, _, ok := fuzzy.Match(query, cmd.Title)
+		if !ok {
+			continue
+		}
+		list = append(list, scored{cmd, score})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].score != list[j].score {
+			return list[i].score > list[j].score
+		}
+		return list[i].cmd.Title < list[j].cmd.Title
+	})
+
+	cmds := make([]*Command, len(list))
+	for i, s := range list {
+		cmds[i] = s.cmd
+	}
+	return cmds
+}
+
+// bindingLabel shows the keys bound to a command, e.g. "ctrl+s"
+func (sm *ScreenManager) bindingLabel(id string) string {
+	var labels []string
+	for _, seq := range sm.keymap.Lookup(id) {
+		labels = append(labels, keymap.FormatSequence(seq))
+	}
+	return strings.Join(labels, ", ")
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+// paletteTitles lists the titles paletteCommands returns for query
+func paletteTitles(sm *ScreenManager, query string) []string {
+	var titles []string
+	for _, cmd := range sm.paletteCommands(query) {
+		titles = append(titles, cmd.Title)
+	}
+	return titles
+}
+
+func TestPaletteCommandsOrder(t *testing.T) {
+	off := func() bool { return false }
+	sm := &ScreenManager{commands: map[string]*Command{}}
+	for _, cmd := range []*Command{
+		{ID: "editor.save", Title: "Save"},
+		{ID: "file.saveAll", Title: "Save All"},
+		{ID: "file.saveWithEncoding", Title: "Save with Encoding"},
+		{ID: "file.reopenWithEncoding", Title: "Reopen with Encoding"},
+		{ID: "tabs.close", Title: "Close Tab"},
+		{ID: "tabs.closeAll", Title: "Close All Tabs"},
+		{ID: "panes.close", Title: "Close Editor Pane", Enabled: off},
+		{ID: "panes.splitRight", Title: "Split Editor Right"},
+		{ID: "sidebar.toggle", Title: "Toggle Sidebar"},
+		{ID: "keys.hidden"},
+	} {
+		sm.register(cmd)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// no query lists every titled, enabled command by title
+		{"", []string{"Close All Tabs", "Close Tab", "Reopen with Encoding", "Save", "Save All",
+			"Save with Encoding", "Split Editor Right", "Toggle Sidebar"}},
+		// equal scores fall back to the title
+		{"save", []string{"Save", "Save All", "Save with Encoding"}},
+		// word starts beat runes inside words
+		{"sa", []string{"Save", "Save All", "Save with Encoding", "Close All Tabs", "Toggle Sidebar", "Close Tab"}},
+		{"ct", []string{"Close Tab", "Close All Tabs"}},
+		// disabled commands are left out
+		{"close ed", nil},
+		{"enc", []string{"Reopen with Encoding", "Save with Encoding"}},
+		{"xyz", nil},
+	}
+	for _, tt := range tests {
+		if got := paletteTitles(sm, tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("paletteCommands(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"github.com/uditrawat03/bitcode/internal/editor"
+	"github.com/uditrawat03/bitcode/internal/panes"
+)
+
+// newEditor creates an editor for a pane, hooked up like the others
+func (sm *ScreenManager) newEditor() *editor.Editor {
+	ed := editor.CreateEditor(0, 0, 0, 0)
+	ed.SetFocusCallback(func() {
+		sm.focusOrder[sm.focusedIdx].Blur()
+		sm.focusedIdx = 1 // panes index in focusOrder
+		sm.focusOrder[sm.focusedIdx].Focus()
+	})
+	ed.SetStatusCallback(sm.statusBar.SetMessage)
+	if sm.config != nil {
+		ed.SetTabSize(sm.config.Editor.TabSize)
+		if mode, ok := editor.ParseWrapMode(sm.config.Editor.Wrap); ok {
+			ed.SetWrapMode(mode)
+		}
+	}
+	return ed
+}
+
+// splitPane splits the focused pane; the new one shows the same buffer at
+// the same place and gets the focus
+func (sm *ScreenManager) splitPane(dir panes.Direction) {
+	from := sm.editor
+	ed := sm.newEditor()
+	if !sm.panes.Split(dir, ed) {
+		sm.statusBar.SetMessage("Not enough room to split the editor")
+		return
+	}
+	if buf := from.GetBuffer(); buf != nil {
+		line, col := from.Cursor()
+		ed.SetBuffer(buf)
+		ed.GotoPosition(line, col)
+	}
+	sm.restoreEditorFocus()
+}
+
+// closePane closes the focused pane; its buffers stay open in their tabs
+func (sm *ScreenManager) closePane() {
+	if !sm.panes.Close() {
+		sm.statusBar.SetMessage("The last editor pane can't be closed")
+		return
+	}
+	sm.restoreEditorFocus()
+}
+
+// focusPane moves the focus to the pane next to the focused one
+func (sm *ScreenManager) focusPane(dx, dy int) {
+	sm.panes.FocusDirection(dx, dy)
+	sm.restoreEditorFocus()
+}
@@ -0,0 +1,217 @@
+package ui
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	dialog "github.com/uditrawat03/bitcode/internal/Dialog"
+	"github.com/uditrawat03/bitcode/internal/buffer"
+	"github.com/uditrawat03/bitcode/internal/fuzzy"
+	"github.com/uditrawat03/bitcode/internal/syntax"
+	"github.com/uditrawat03/bitcode/internal/workspace"
+)
+
+const (
+	// most files listed in Go to File
+	quickOpenLimit = 200
+	// most files scored for one query, see quickOpenFiles
+	quickOpenCandidates = 5000
+	// remembered for the recently-opened boost
+	recentFilesLimit = 50
+	// lines read for the preview
+	previewLines = 100
+)
+
+// addRecentFile moves path to the front of the recently opened files
+func (sm *ScreenManager) addRecentFile(path string) {
+	path = filepath.Clean(path)
+	sm.recentFiles = slices.DeleteFunc(sm.recentFiles, func(p string) bool { return p == path })
+	sm.recentFiles = slices.Insert(sm.recentFiles, 0, path)
+	if len(sm.recentFiles) > recentFilesLimit {
+		sm.recentFiles = sm.recentFiles[:recentFilesLimit]
+	}
+}
+
+// openQuickOpen fuzzy finds a file in the workspace and opens it
+func (sm *ScreenManager) openQuickOpen() {
+	if sm.fileIndex == nil {
+		return
+	}
+	var shown []string
+	filter := func(query string) []dialog.Item {
+		files, ready := sm.fileIndex.Files()
+		query = strings.TrimSpace(query)
+		shown = sm.quickOpenFiles(files, query)
+		mt := fuzzy.NewMatcher(query)
+		items := make([]dialog.Item, len(shown))
+		for i, rel := range shown {
+			_, positions, _ := mt.Match(rel)
+			items[i] = dialog.Item{Label: rel, Matches: positions}
+		}
+		if !ready && len(items) < quickOpenLimit {
+			items = append(items, dialog.Item{Label: "Indexing..."})
+		}
+		return items
+	}
+
+	w, h := 100, 24
+	if sm.screen != nil {
+		sw, sh := sm.screen.Size()
+		w, h = min(w, sw-4), min(h, sh-2)
+	}
+	var finder *dialog.Dialog
+	finder = dialog.NewListDialog("Go to File", w, h, filter,
+		func(i int) {
+			if i >= len(shown) {
+				return // the "Indexing..." row
+			}
+			file := filepath.Join(sm.fileIndex.Root(), filepath.FromSlash(shown[i]))
+			sm.CloseDialog()
+			if sm.openFile(file) {
+				sm.restoreEditorFocus()
+			}
+		},
+		func(_ string) {
+			sm.CloseDialog()
+		},
+		func() {
+			sm.restoreEditorFocus()
+		},
+	)
+	finder.SetPreview(func(i int) *dialog.Preview {
+		if i >= len(shown) {
+			return nil
+		}
+		return filePreview(filepath.Join(sm.fileIndex.Root(), filepath.FromSlash(shown[i])))
+	})
+	sm.OpenDialog(finder)
+
+	// pick up files added since the last build
+	sm.fileIndex.Rebuild(func() {
+		sm.post(func() {
+			if sm.dialog == finder {
+				finder.Refresh()
+			}
+		})
+	})
+}
+
+// quickOpenFiles ranks the indexed files against query. A match in the
+// base name and a recently opened file rank higher; with no query the
+// recent files come first.
+func (sm *ScreenManager) quickOpenFiles(files []string, query string) []string {
+	recent := make(map[string]int, len(sm.recentFiles))
+	for i, p := range sm.recentFiles {
+		if rel, err := filepath.Rel(sm.fileIndex.Root(), p); err == nil {
+			recent[filepath.ToSlash(rel)] = len(sm.recentFiles) - i
+		}
+	}
+
+	// the subsequence check is cheap, scoring isn't. In a big tree a short
+	// query matches most paths, score the likeliest: recent files, then
+	// ones matching in their base name, then the shortest.
+	type candidate struct {
+		rel       string
+		recent    int
+		baseMatch bool
+	}
+	mt := fuzzy.NewMatcher(query)
+	var candidates []candidate
+	for _, rel := range files {
+		if mt.Contains(rel) {
+			candidates = append(candidates, candidate{rel, recent[rel], mt.Contains(path.Base(rel))})
+		}
+	}
+	if len(candidates) > quickOpenCandidates {
+		slices.SortStableFunc(candidates, func(a, b candidate) int {
+			switch {
+			case a.recent != b.recent:
+				return b.recent - a.recent
+			case a.baseMatch != b.baseMatch:
+				if a.baseMatch {
+					return -1
+				}
+				return 1
+			}
+			return len(a.rel) - len(b.rel)
+		})
+		candidates = candidates[:quickOpenCandidates]
+	}
+
+	type scored struct {
+		rel   string
+		score int
+	}
+	list := make([]scored, 0, len(candidates))
+	for _, c := range candidates {
+		score, _, ok := mt.Match(c.rel)
+		if !ok {
+			continue
+		}
+		if query != "" && c.baseMatch {
+			base, _, _ := mt.Match(path.Base(c.rel))
+			score += base / 2
+		}
+		if c.recent > 0 {
+			score += 20 + c.recent
+		}
+		list = append(list, scored{c.rel, score})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].score != list[j].score {
+			return list[i].score > list[j].score
+		}
+		if len(list[i].rel) != len(list[j].rel) {
+			return len(list[i].rel) < len(list[j].rel)
+		}
+		return list[i].rel < list[j].rel
+	})
+
+	if len(list) > quickOpenLimit {
+		list = list[:quickOpenLimit]
+	}
+	rels := make([]string, len(list))
+	for i, s := range list {
+		rels[i] = s.rel
+	}
+	return rels
+}
+
+// filePreview reads the start of a file for the Go to File preview
+func filePreview(file string) *dialog.Preview {
+	f, err := os.Open(file)
+	if err != nil {
+		return &dialog.Preview{Lines: []string{err.Error()}}
+	}
+	defer f.Close()
+
+	data := make([]byte, 16*1024)
+	n, _ := f.Read(data)
+	data = data[:n]
+	// decode like search does, UTF-16 is full of NULs without being binary
+	enc := buffer.DetectEncoding(data)
+	if workspace.IsBinary(data) && !buffer.IsUTF16(enc) {
+		return &dialog.Preview{Lines: []string{"(binary file)"}}
+	}
+	if buffer.IsUTF16(enc) {
+		// the read may have split the last code unit
+		data = data[:len(data)&^1]
+	}
+	text, err := buffer.DecodeText(data, enc)
+	if err != nil {
+		return &dialog.Preview{Lines: []string{err.Error()}}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if len(lines) > previewLines {
+		lines = lines[:previewLines]
+	}
+	for i, line := range lines {
+		lines[i] = strings.ReplaceAll(line, "\t", "    ")
+	}
+	return &dialog.Preview{Lines: lines, Lexer: syntax.ForFile(file)}
+}
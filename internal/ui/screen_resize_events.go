@@ -0,0 +1,40 @@
+package ui
+
+// Resize lays the components out again for the new terminal size
+func (sm *ScreenManager) Resize() {
+	sm.applyLayout()
+}
+
+// applyLayout moves every component to the area the layout gives it
+func (sm *ScreenManager) applyLayout() {
+	if sm.screen == nil {
+		return
+	}
+	w, h := sm.screen.Size()
+	sm.layoutManager.UpdateLayout(w, h)
+	l := sm.layoutManager.GetLayout()
+	sm.topBar.SetBounds(l.GetTopBarArea(w, h))
+	sm.sidebar.X, sm.sidebar.Y, sm.sidebar.Width, sm.sidebar.Height = l.GetSidebarArea(w, h)
+	sm.findPanel.SetBounds(l.GetSidebarArea(w, h))
+	sm.panes.SetBounds(l.GetEditorArea(w, h))
+	sm.statusBar.SetBounds(l.GetStatusBarArea(w, h))
+
+	// the sidebar can't keep focus while there is no room for it
+	if !sm.sidebarShown() && sm.focusedIdx == 0 {
+		sm.focusOrder[0].Blur()
+		sm.focusedIdx = 1 // editor index
+		sm.editor.Focus()
+	}
+}
+
+// sidebarShown reports whether the sidebar (or Find in Files) has room on
+// screen; it is gone when hidden or when the terminal is too narrow
+func (sm *ScreenManager) sidebarShown() bool {
+	return sm.layoutManager.GetLayout().SidebarWidth > 0
+}
+
+// resizeSidebar is called while the sidebar border is dragged
+func (sm *ScreenManager) resizeSidebar(width int) {
+	sm.layoutManager.DragSidebarWidth(width)
+	sm.applyLayout()
+}
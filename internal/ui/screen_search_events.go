@@ -0,0 +1,245 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	dialog "github.com/uditrawat03/bitcode/internal/Dialog"
+	"github.com/uditrawat03/bitcode/internal/buffer"
+	"github.com/uditrawat03/bitcode/internal/workspace"
+)
+
+// How often streamed search results are handed to the main loop
+const searchFlushInterval = 50 * time.Millisecond
+
+// openFindPanel shows Find in Files in place of the sidebar and focuses it,
+// with replacing set it also offers to replace the matches
+func (sm *ScreenManager) openFindPanel(replacing bool) {
+	sm.setSidebarVisible(true)
+	if !sm.sidebarShown() {
+		sm.statusBar.SetMessage("Window too narrow for Find in Files")
+		return
+	}
+	if !sm.findPanelOpen {
+		sm.findPanelOpen = true
+		sm.focusOrder[0] = sm.findPanel
+	}
+	if replacing != sm.findPanel.IsReplacing() {
+		sm.findPanel.SetReplacing(replacing)
+	}
+	sm.focusOrder[sm.focusedIdx].Blur()
+	sm.sidebar.Blur()
+	sm.focusedIdx = 0
+	sm.findPanel.Focus()
+}
+
+// closeFindPanel stops any running search and brings the sidebar back
+func (sm *ScreenManager) closeFindPanel() {
+	sm.cancelProjectSearch()
+	if !sm.findPanelOpen {
+		return
+	}
+	sm.findPanelOpen = false
+	sm.findPanel.Blur()
+	sm.focusOrder[0] = sm.sidebar
+	if sm.focusedIdx == 0 {
+		sm.sidebar.Focus()
+	}
+}
+
+func (sm *ScreenManager) cancelProjectSearch() {
+	if sm.searchCancel != nil {
+		sm.searchCancel()
+		sm.searchCancel = nil
+	}
+}
+
+// runProjectSearch searches the workspace in the background and streams the
+// results into the find panel in batches
+func (sm *ScreenManager) runProjectSearch(query string, opts buffer.SearchOptions) {
+	sm.cancelProjectSearch()
+	ctx, cancel := context.WithCancel(context.Background())
+	sm.searchCancel = cancel
+	sm.findPanel.Start()
+
+	var mu sync.Mutex
+	var pending []workspace.FileResult
+
+	// deliver hands the pending results to the panel; results of a cancelled
+	// search are dropped
+	deliver := func(done bool, err error) bool {
+		mu.Lock()
+		batch := pending
+		pending = nil
+		mu.Unlock()
+		posted := sm.post(func() {
+			if ctx.Err() != nil {
+				return
+			}
+			sm.findPanel.AddResults(batch)
+			if done {
+				sm.findPanel.Finish(err)
+			}
+		})
+		if posted != nil {
+			// queue full, keep the batch for the next try
+			mu.Lock()
+			pending = append(batch, pending...)
+			mu.Unlock()
+			return false
+		}
+		return true
+	}
+
+	root := sm.sidebar.Tree.Root.Path
+	go func() {
+		lastFlush := time.Now()
+		err := workspace.Search(ctx, root, query, opts, sm.unsavedContent, func(result workspace.FileResult) {
+			mu.Lock()
+			pending = append(pending, result)
+			mu.Unlock()
+			if time.Since(lastFlush) >= searchFlushInterval {
+				deliver(false, nil)
+				lastFlush = time.Now()
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		for !deliver(true, err) && ctx.Err() == nil {
+			time.Sleep(searchFlushInterval)
+		}
+	}()
+}
+
+// openSearchMatch opens a file picked in the find panel at the match
+func (sm *ScreenManager) openSearchMatch(path string, line, col int) {
+	if !sm.openFile(path) {
+		return
+	}
+	sm.editor.GotoPosition(line, col)
+
+	sm.focusOrder[sm.focusedIdx].Blur()
+	sm.focusedIdx = 1 // editor index
+	sm.focusOrder[sm.focusedIdx].Focus()
+}
+
+// unsavedContent lets project search see edits that aren't saved yet
+func (sm *ScreenManager) unsavedContent(path string) (string, bool) {
+	buf, ok := sm.bufferManager.Lookup(path)
+	if !ok || !buf.Modified() {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+// confirmProjectReplace asks before replacing the checked matches in the
+// find panel, then applies them through the buffer manager
+func (sm *ScreenManager) confirmProjectReplace(edits []buffer.FileEdit, query, replacement string, opts buffer.SearchOptions) {
+	matches, files := sm.findPanel.CheckedCount()
+	dialogReplace := dialog.NewChoiceDialog(
+		"Replace in files",
+		fmt.Sprintf("Replace %d occurrences in %d files?", matches, files),
+		[]string{"Replace", "Cancel"},
+		func(choice int) {
+			sm.CloseDialog()
+			if choice == 0 {
+				sm.replaceInFiles(edits, query, replacement, opts)
+			}
+		},
+		func() {
+			if sm.findPanelOpen {
+				sm.focusOrder[sm.focusedIdx].Blur()
+				sm.focusedIdx = 0
+				sm.findPanel.Focus()
+			}
+		},
+	)
+	sm.OpenDialog(dialogReplace)
+}
+
+// replaceInFiles applies a project replace and searches again so the panel
+// shows what is left
+func (sm *ScreenManager) replaceInFiles(edits []buffer.FileEdit, query, replacement string, opts buffer.SearchOptions) {
+	sm.forgetReplace()
+	results := sm.bufferManager.ReplaceInFiles(edits, query, replacement, opts)
+	sm.lastReplace = &projectReplace{results: results, query: query, opts: opts}
+
+	total, files := 0, 0
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", sm.findPanel.RelPath(r.Path), r.Err))
+			continue
+		}
+		if r.Count > 0 {
+			total += r.Count
+			files++
+		}
+		if r.Missed > 0 {
+			failed = append(failed, fmt.Sprintf("%s: %d changed since the search", sm.findPanel.RelPath(r.Path), r.Missed))
+		}
+	}
+
+	sm.runProjectSearch(query, opts)
+
+	msg := fmt.Sprintf("Replaced %d occurrences in %d files", total, files)
+	if len(failed) > 0 {
+		msg += fmt.Sprintf(", %d failed (%s)", len(failed), failed[0])
+	}
+	sm.statusBar.SetMessage(msg)
+}
+
+// projectReplace is a Replace in Files that can still be undone
+type projectReplace struct {
+	results []buffer.ReplaceResult
+	query   string
+	opts    buffer.SearchOptions
+}
+
+// undoReplace reverts the last Replace in Files
+func (sm *ScreenManager) undoReplace() {
+	if sm.lastReplace == nil {
+		sm.statusBar.SetMessage("Nothing to undo")
+		return
+	}
+	last := sm.lastReplace
+	results := sm.bufferManager.UndoReplace(last.results)
+	sm.forgetReplace()
+
+	files := 0
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", sm.findPanel.RelPath(r.Path), r.Err))
+			continue
+		}
+		files++
+	}
+	if sm.findPanelOpen {
+		sm.runProjectSearch(last.query, last.opts)
+	}
+
+	msg := fmt.Sprintf("Undid replace in %d files", files)
+	if len(failed) > 0 {
+		msg += fmt.Sprintf(", %d failed (%s)", len(failed), failed[0])
+	}
+	sm.statusBar.SetMessage(msg)
+}
+
+// forgetReplace drops the last Replace in Files, closing the files it
+// loaded that weren't opened since
+func (sm *ScreenManager) forgetReplace() {
+	if sm.lastReplace == nil {
+		return
+	}
+	for _, r := range sm.lastReplace.results {
+		if r.Loaded && !slices.Contains(sm.tabs, r.Buf) && !r.Buf.Modified() {
+			sm.bufferManager.Close(r.Buf)
+		}
+	}
+	sm.lastReplace = nil
+}
@@ -0,0 +1,181 @@
+package ui
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/uditrawat03/bitcode/internal/buffer"
+	"github.com/uditrawat03/bitcode/internal/topbar"
+)
+
+// initTabs hooks the tabs in the top bar up to the open buffers
+func (sm *ScreenManager) initTabs() {
+	sm.topBar.SetOnSelect(func(i int) {
+		sm.showBuffer(sm.tabs[i])
+		sm.restoreEditorFocus()
+	})
+	sm.topBar.SetOnClose(sm.closeTab)
+	sm.topBar.SetOnMove(sm.moveTab)
+}
+
+// showBuffer shows buf in the focused pane, adding a tab for it when it
+// has none
+func (sm *ScreenManager) showBuffer(buf *buffer.Buffer) {
+	if !slices.Contains(sm.tabs, buf) {
+		// new tabs open right of the current one
+		at := len(sm.tabs)
+		if i := slices.Index(sm.tabs, sm.editor.GetBuffer()); i >= 0 {
+			at = i + 1
+		}
+		sm.tabs = slices.Insert(sm.tabs, at, buf)
+	}
+	if buf.File != "" {
+		sm.bufferManager.Activate(buf)
+	}
+	sm.editor.SetBuffer(buf)
+}
+
+// switchTab shows the tab delta places from the current one, wrapping
+// around
+func (sm *ScreenManager) switchTab(delta int) {
+	if len(sm.tabs) == 0 {
+		return
+	}
+	i := max(0, slices.Index(sm.tabs, sm.editor.GetBuffer()))
+	i = ((i+delta)%len(sm.tabs) + len(sm.tabs)) % len(sm.tabs)
+	sm.showBuffer(sm.tabs[i])
+	sm.restoreEditorFocus()
+}
+
+// closeActiveTab closes the tab of the buffer in the editor
+func (sm *ScreenManager) closeActiveTab() {
+	if i := slices.Index(sm.tabs, sm.editor.GetBuffer()); i >= 0 {
+		sm.closeTab(i)
+	}
+}
+
+// closeTab closes a tab, asking first when its buffer has unsaved changes
+func (sm *ScreenManager) closeTab(i int) {
+	if i < 0 || i >= len(sm.tabs) {
+		return
+	}
+	buf := sm.tabs[i]
+	sm.confirmDiscard(buf, func() {
+		sm.closeBuffer(buf)
+	})
+}
+
+// closeAllTabs closes every tab once each modified buffer was saved or
+// discarded
+func (sm *ScreenManager) closeAllTabs() {
+	modified := sm.bufferManager.Modified()
+	for _, buf := range sm.tabs {
+		if buf.File == "" && buf.Modified() {
+			modified = append(modified, buf)
+		}
+	}
+	sm.confirmBuffers(modified, func() {
+		sm.bufferManager.CloseAll()
+		for _, buf := range slices.Clone(sm.tabs) {
+			sm.removeTab(buf)
+		}
+	})
+}
+
+// closeBuffer closes buf for good; file buffers go through the buffer
+// manager, which removes the tab when it reports the close
+func (sm *ScreenManager) closeBuffer(buf *buffer.Buffer) {
+	if buf.File != "" {
+		sm.bufferManager.Close(buf)
+		return
+	}
+	sm.removeTab(buf)
+}
+
+// removeTab removes buf's tab; panes showing it move to the neighbouring
+// tab
+func (sm *ScreenManager) removeTab(buf *buffer.Buffer) {
+	i := slices.Index(sm.tabs, buf)
+	if i >= 0 {
+		sm.tabs = slices.Delete(sm.tabs, i, i+1)
+	}
+
+	var next *buffer.Buffer
+	if len(sm.tabs) > 0 {
+		next = sm.tabs[min(max(i, 0), len(sm.tabs)-1)]
+	}
+	for _, ed := range sm.panes.Editors() {
+		if ed.GetBuffer() == buf {
+			ed.SetBuffer(next)
+		}
+	}
+}
+
+// moveTab moves a tab to another place, e.g. while it is dragged
+func (sm *ScreenManager) moveTab(from, to int) {
+	if from < 0 || from >= len(sm.tabs) || to < 0 || to >= len(sm.tabs) {
+		return
+	}
+	buf := sm.tabs[from]
+	sm.tabs = slices.Delete(sm.tabs, from, from+1)
+	sm.tabs = slices.Insert(sm.tabs, to, buf)
+}
+
+// updateTabs gives the top bar the current tabs
+func (sm *ScreenManager) updateTabs() {
+	titles := tabTitles(sm.tabs)
+	tabs := make([]topbar.Tab, len(sm.tabs))
+	for i, buf := range sm.tabs {
+		tabs[i] = topbar.Tab{Title: titles[i], Modified: buf.Modified()}
+	}
+	sm.topBar.SetTabs(tabs, slices.Index(sm.tabs, sm.editor.GetBuffer()))
+}
+
+// tabTitles names each buffer by its file name, adding as many parent
+// folders as it takes to tell apart files with the same name, e.g.
+// "main.go — cmd/a" and "main.go — cmd/b"
+func tabTitles(bufs []*buffer.Buffer) []string {
+	titles := make([]string, len(bufs))
+	byName := map[string][]int{}
+	for i, buf := range bufs {
+		titles[i] = buf.DisplayName()
+		if buf.File != "" {
+			byName[titles[i]] = append(byName[titles[i]], i)
+		}
+	}
+
+	for name, group := range byName {
+		if len(group) < 2 {
+			continue
+		}
+		dirs := make([][]string, len(group))
+		longest := 0
+		for j, i := range group {
+			dir := filepath.Dir(buffer.CanonicalPath(bufs[i].File))
+			dirs[j] = strings.Split(filepath.ToSlash(dir), "/")
+			longest = max(longest, len(dirs[j]))
+		}
+
+		// the fewest trailing folders that make every title unique
+		for depth := 1; depth <= longest; depth++ {
+			seen := map[string]bool{}
+			unique := true
+			suffixes := make([]string, len(group))
+			for j, parts := range dirs {
+				suffixes[j] = strings.Join(parts[max(0, len(parts)-depth):], "/")
+				if seen[suffixes[j]] {
+					unique = false
+				}
+				seen[suffixes[j]] = true
+			}
+			if unique || depth == longest {
+				for j, i := range group {
+					titles[i] = name + " — " + suffixes[j]
+				}
+				break
+			}
+		}
+	}
+	return titles
+}
@@ -0,0 +1,94 @@
+package watcher
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+)
+
+// Watcher reports paths of watched files that changed on disk. Events are
+// delivered on Events() from a background goroutine.
+type Watcher struct {
+	events chan string
+	done   chan struct{}
+	once   sync.Once
+
+	mu     sync.Mutex
+	files  map[string]bool
+	closed bool
+
+	backend
+}
+
+// New creates a watcher, using inotify where available and polling elsewhere
+func New() (*Watcher, error) {
+	w := &Watcher{
+		events: make(chan string, 16),
+		done:   make(chan struct{}),
+		files:  map[string]bool{},
+	}
+	if err := w.start(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Events delivers the changed paths; it is closed once the watcher stops
+func (w *Watcher) Events() <-chan string { return w.events }
+
+// Add starts watching path
+func (w *Watcher) Add(path string) error {
+	path = filepath.Clean(path)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return errors.New("watcher is closed")
+	}
+	if w.files[path] {
+		return nil
+	}
+	if err := w.add(path); err != nil {
+		return err
+	}
+	w.files[path] = true
+	return nil
+}
+
+// Remove stops watching path
+func (w *Watcher) Remove(path string) {
+	path = filepath.Clean(path)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed || !w.files[path] {
+		return
+	}
+	delete(w.files, path)
+	w.remove(path)
+}
+
+// Close stops watching; Events is closed when the background goroutine
+// has exited. The goroutine releases the backend itself, so nothing it is
+// still reading from goes away under it.
+func (w *Watcher) Close() {
+	w.once.Do(func() {
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+		close(w.done)
+	})
+}
+
+// watched reports whether path is one of the watched files
+func (w *Watcher) watched(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.files[path]
+}
+
+// emit delivers an event unless the watcher was closed
+func (w *Watcher) emit(path string) {
+	select {
+	case w.events <- path:
+	case <-w.done:
+	}
+}
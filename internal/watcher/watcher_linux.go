@@ -0,0 +1,133 @@
+//go:build linux
+
+package watcher
+
+import (
+	"bytes"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Editors, formatters and git replace files by renaming over them, which
+// drops an inotify watch on the file itself, so the parent directory is
+// watched instead and events are filtered by name.
+const dirMask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_ATTRIB
+
+type backend struct {
+	fd   int
+	dirs map[string]int // dir -> watch descriptor
+	wds  map[int]string // watch descriptor -> dir
+	refs map[string]int // number of watched files per dir
+}
+
+func (w *Watcher) start() error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	w.fd = fd
+	w.dirs = map[string]int{}
+	w.wds = map[int]string{}
+	w.refs = map[string]int{}
+	go w.loop()
+	return nil
+}
+
+func (w *Watcher) add(path string) error {
+	dir := filepath.Dir(path)
+	if w.refs[dir] == 0 {
+		wd, err := unix.InotifyAddWatch(w.fd, dir, dirMask)
+		if err != nil {
+			return err
+		}
+		w.dirs[dir] = wd
+		w.wds[wd] = dir
+	}
+	w.refs[dir]++
+	return nil
+}
+
+func (w *Watcher) remove(path string) {
+	dir := filepath.Dir(path)
+	w.refs[dir]--
+	if w.refs[dir] > 0 {
+		return
+	}
+	delete(w.refs, dir)
+	if wd, ok := w.dirs[dir]; ok {
+		unix.InotifyRmWatch(w.fd, uint32(wd))
+		delete(w.dirs, dir)
+		delete(w.wds, wd)
+	}
+}
+
+// close releases the inotify descriptor once loop is done with it
+func (w *Watcher) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// loop may also stop on an error, Add must not use the descriptor after
+	w.closed = true
+	unix.Close(w.fd)
+}
+
+func (w *Watcher) loop() {
+	defer close(w.events)
+	defer w.close()
+	buf := make([]byte, 64*1024)
+	fds := []unix.PollFd{{Fd: int32(w.fd), Events: unix.POLLIN}}
+	for {
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+
+		// poll with a timeout so Close is noticed
+		n, err := unix.Poll(fds, 500)
+		if err != nil && err != unix.EINTR {
+			return
+		}
+		if n <= 0 {
+			continue
+		}
+
+		n, err = unix.Read(w.fd, buf)
+		if err != nil || n < unix.SizeofInotifyEvent {
+			continue
+		}
+		for _, path := range w.parse(buf[:n]) {
+			w.emit(path)
+		}
+	}
+}
+
+// parse turns raw inotify records into watched file paths
+func (w *Watcher) parse(data []byte) []string {
+	var paths []string
+	seen := map[string]bool{}
+	for off := 0; off+unix.SizeofInotifyEvent <= len(data); {
+		ev := (*unix.InotifyEvent)(unsafe.Pointer(&data[off]))
+		nameStart := off + unix.SizeofInotifyEvent
+		nameEnd := nameStart + int(ev.Len)
+		off = nameEnd
+		if nameEnd > len(data) || ev.Len == 0 {
+			continue
+		}
+		name := string(bytes.TrimRight(data[nameStart:nameEnd], "\x00"))
+
+		w.mu.Lock()
+		dir := w.wds[int(ev.Wd)]
+		w.mu.Unlock()
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if !seen[path] && w.watched(path) {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
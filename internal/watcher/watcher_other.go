@@ -0,0 +1,74 @@
+//go:build !linux
+
+package watcher
+
+import (
+	"os"
+	"time"
+)
+
+// Without inotify the watched files are polled
+const pollInterval = 2 * time.Second
+
+type stamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+type backend struct {
+	stamps map[string]stamp
+}
+
+func statStamp(path string) stamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return stamp{}
+	}
+	return stamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+func (w *Watcher) start() error {
+	w.stamps = map[string]stamp{}
+	go w.loop()
+	return nil
+}
+
+func (w *Watcher) add(path string) error {
+	w.stamps[path] = statStamp(path)
+	return nil
+}
+
+func (w *Watcher) remove(path string) {
+	delete(w.stamps, path)
+}
+
+func (w *Watcher) close() {}
+
+func (w *Watcher) loop() {
+	defer close(w.events)
+	defer w.close()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		var changed []string
+		w.mu.Lock()
+		for path, old := range w.stamps {
+			if now := statStamp(path); now != old {
+				w.stamps[path] = now
+				changed = append(changed, path)
+			}
+		}
+		w.mu.Unlock()
+
+		for _, path := range changed {
+			w.emit(path)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package workspace
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob, want string
+	}{
+		{"*.go", `[^/]*\.go`},
+		{"a?c", `a[^/]c`},
+		{"**/build", `(?:.*/)?build`},
+		{"logs/**", `logs/.*`},
+		{"a/**/b", `a/(?:.*/)?b`},
+		{"a**b", `a.*b`},
+		{"[abc].txt", `[abc]\.txt`},
+		{"[!abc]", `[^abc]`},
+		{"[a", `\[a`},
+		{`\*.md`, `\*\.md`},
+	}
+	for _, tt := range tests {
+		if got := globToRegexp(tt.glob); got != tt.want {
+			t.Errorf("globToRegexp(%q) = %q, want %q", tt.glob, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		path  string
+		isDir bool
+		// ignored, and whether any rule decided at all
+		ignored, decided bool
+	}{
+		{"name at any depth", []string{"*.log"}, "a/b/debug.log", false, true, true},
+		{"star stops at slashes", []string{"a*b"}, "a/b", false, false, false},
+		{"no match", []string{"*.log"}, "main.go", false, false, false},
+		{"comment", []string{"# main.go"}, "main.go", false, false, false},
+		// a slash anchors the pattern to the .gitignore dir
+		{"leading slash", []string{"/build"}, "build", true, true, true},
+		{"leading slash is anchored", []string{"/build"}, "src/build", true, false, false},
+		{"inner slash is anchored", []string{"doc/out"}, "src/doc/out", true, false, false},
+		{"inner slash", []string{"doc/out"}, "doc/out", true, true, true},
+		// a trailing slash only matches directories
+		{"trailing slash on a dir", []string{"tmp/"}, "a/tmp", true, true, true},
+		{"trailing slash on a file", []string{"tmp/"}, "a/tmp", false, false, false},
+		{"double star prefix", []string{"**/gen"}, "a/b/gen", true, true, true},
+		{"double star prefix at the top", []string{"**/gen"}, "gen", false, true, true},
+		{"double star suffix", []string{"logs/**"}, "logs/a/b.txt", false, true, true},
+		{"double star suffix needs something inside", []string{"logs/**"}, "logs", true, false, false},
+		{"double star middle", []string{"a/**/z"}, "a/b/c/z", false, true, true},
+		{"double star middle with none between", []string{"a/**/z"}, "a/z", false, true, true},
+		// the last matching rule wins
+		{"negation", []string{"*.log", "!keep.log"}, "keep.log", false, false, true},
+		{"negation overridden", []string{"!keep.log", "*.log"}, "keep.log", false, true, true},
+		{"escaped bang", []string{`\!bang`}, "!bang", false, true, true},
+		{"escaped hash", []string{`\#hash`}, "#hash", false, true, true},
+		{"trailing spaces", []string{"out   "}, "out", false, true, true},
+		{"escaped trailing space", []string{`out\ `}, "out ", false, true, true},
+		{"crlf", []string{"out\r"}, "out", false, true, true},
+	}
+	dir := filepath.FromSlash("/repo")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig := &ignoreFile{dir: dir}
+			for _, line := range tt.lines {
+				if rule, ok := parseIgnoreLine(line); ok {
+					ig.rules = append(ig.rules, rule)
+				}
+			}
+			ignored, decided := ig.match(filepath.Join(dir, filepath.FromSlash(tt.path)), tt.isDir)
+			if ignored != tt.ignored || decided != tt.decided {
+				t.Fatalf("match(%q) = %v, %v, want %v, %v", tt.path, ignored, decided, tt.ignored, tt.decided)
+			}
+		})
+	}
+}
+
+func TestIgnoreStack(t *testing.T) {
+	parse := func(dir string, lines ...string) *ignoreFile {
+		ig := &ignoreFile{dir: filepath.FromSlash(dir)}
+		for _, line := range lines {
+			rule, _ := parseIgnoreLine(line)
+			ig.rules = append(ig.rules, rule)
+		}
+		return ig
+	}
+	stack := ignoreStack{parse("/repo", "*.gen", "vendor/"), parse("/repo/sub", "!keep.gen")}
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"/repo/a.gen", false, true},
+		{"/repo/sub/a.gen", false, true},
+		// the deeper .gitignore decides first
+		{"/repo/sub/keep.gen", false, false},
+		{"/repo/keep.gen", false, true},
+		{"/repo/sub/vendor", true, true},
+		{"/repo/main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := stack.Ignored(filepath.FromSlash(tt.path), tt.isDir); got != tt.ignored {
+			t.Errorf("Ignored(%q) = %v, want %v", tt.path, got, tt.ignored)
+		}
+	}
+}
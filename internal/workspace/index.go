@@ -0,0 +1,94 @@
+package workspace
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// How often a first build publishes what it found so far
+const indexPublishInterval = 100 * time.Millisecond
+
+// Index lists the files under a root for quick open. It is built in the
+// background; Files returns what was found so far.
+type Index struct {
+	root string
+
+	mu     sync.Mutex
+	files  []string // relative to root, slash separated
+	ready  bool
+	cancel context.CancelFunc
+}
+
+func NewIndex(root string) *Index {
+	return &Index{root: root}
+}
+
+// Root is the folder the index lists
+func (ix *Index) Root() string {
+	return ix.root
+}
+
+// Files returns the indexed paths relative to the root, and whether the last
+// build finished. The slice must not be modified.
+func (ix *Index) Files() ([]string, bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.files, ix.ready
+}
+
+// Rebuild walks the root again in the background, stopping a build still
+// running. The first build publishes files as they are found; later ones
+// keep the old list until they are done so results don't flicker. onUpdate
+// is called from the walking goroutine whenever Files changed.
+func (ix *Index) Rebuild(onUpdate func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ix.mu.Lock()
+	if ix.cancel != nil {
+		ix.cancel()
+	}
+	ix.cancel = cancel
+	first := !ix.ready && len(ix.files) == 0
+	ix.mu.Unlock()
+
+	go func() {
+		var files []string
+		last := time.Now()
+		publish := func(ready bool) {
+			ix.mu.Lock()
+			if ctx.Err() == nil {
+				ix.files = files[:len(files):len(files)]
+				ix.ready = ready
+			}
+			ix.mu.Unlock()
+			if ctx.Err() == nil && onUpdate != nil {
+				onUpdate()
+			}
+		}
+
+		err := Walk(ctx, ix.root, func(path string) error {
+			if rel, err := filepath.Rel(ix.root, path); err == nil {
+				files = append(files, filepath.ToSlash(rel))
+			}
+			if first && time.Since(last) >= indexPublishInterval {
+				last = time.Now()
+				publish(false)
+			}
+			return nil
+		})
+		if err == nil {
+			publish(true)
+		}
+	}()
+}
+
+// Close stops a build in progress
+func (ix *Index) Close() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.cancel != nil {
+		ix.cancel()
+		ix.cancel = nil
+	}
+}
@@ -0,0 +1,158 @@
+package workspace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/uditrawat03/bitcode/internal/buffer"
+)
+
+const (
+	// Files bigger than this are skipped, they are rarely source code
+	maxSearchFileSize = 16 << 20
+	// The search stops once this many matches were found
+	maxSearchMatches = 10000
+	// Bytes inspected when looking for binary content
+	binarySniffLen = 8000
+)
+
+// ErrTooManyMatches is returned when a search was cut short at maxSearchMatches
+var ErrTooManyMatches = errors.New("too many matches")
+
+// LineMatch is a match in a file; Line and Start/End (rune columns) are 0-based
+type LineMatch struct {
+	Line  int
+	Start int
+	End   int
+	Text  string // the whole line
+}
+
+// FileResult groups the matches found in one file
+type FileResult struct {
+	Path    string
+	Matches []LineMatch
+}
+
+// Overlay returns the content to search instead of the file on disk, e.g.
+// for files with unsaved changes in the editor
+type Overlay func(path string) (string, bool)
+
+// IsBinary guesses whether data is binary by looking for a NUL byte near the start
+func IsBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), binarySniffLen)], 0) >= 0
+}
+
+// Search looks for query in every file under root, skipping ignored and
+// binary files. Files are searched concurrently; found is called once per
+// file with matches, always from the goroutine that called Search. overlay
+// may be nil, it is called from several goroutines at once.
+func Search(ctx context.Context, root, query string, opts buffer.SearchOptions, overlay Overlay, found func(FileResult)) error {
+	re, err := buffer.CompileSearch(query, opts)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	paths := make(chan string, 64)
+	results := make(chan FileResult, 64)
+
+	var walkErr error
+	go func() {
+		defer close(paths)
+		walkErr = Walk(ctx, root, func(path string) error {
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				result, ok := searchFile(path, re, overlay)
+				if !ok {
+					continue
+				}
+				select {
+				case results <- result:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	total := 0
+	for result := range results {
+		if total >= maxSearchMatches {
+			continue // drain so the workers can finish
+		}
+		if total+len(result.Matches) > maxSearchMatches {
+			result.Matches = result.Matches[:maxSearchMatches-total]
+		}
+		total += len(result.Matches)
+		found(result)
+		if total >= maxSearchMatches {
+			cancel()
+		}
+	}
+
+	switch {
+	case total >= maxSearchMatches:
+		return ErrTooManyMatches
+	case walkErr != nil && !errors.Is(walkErr, context.Canceled):
+		return walkErr
+	}
+	return ctx.Err()
+}
+
+// searchFile reads and scans one file, ok is false when there is nothing to report
+func searchFile(path string, re *regexp.Regexp, overlay Overlay) (FileResult, bool) {
+	text, ok := "", false
+	if overlay != nil {
+		text, ok = overlay(path)
+	}
+	if !ok {
+		info, err := os.Stat(path)
+		if err != nil || info.Size() > maxSearchFileSize {
+			return FileResult{}, false
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return FileResult{}, false
+		}
+		// decode like the editor does so columns line up with its buffers
+		enc := buffer.DetectEncoding(data)
+		if IsBinary(data) && !buffer.IsUTF16(enc) {
+			return FileResult{}, false
+		}
+		if text, err = buffer.DecodeText(data, enc); err != nil {
+			return FileResult{}, false
+		}
+	}
+
+	result := FileResult{Path: path}
+	for y, line := range strings.Split(text, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		for _, m := range buffer.MatchLine(re, line, y) {
+			result.Matches = append(result.Matches, LineMatch{Line: m.Line, Start: m.Start, End: m.End, Text: line})
+		}
+	}
+	return result, len(result.Matches) > 0
+}
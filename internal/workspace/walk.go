@@ -0,0 +1,55 @@
+package workspace
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Walk calls fn for every file under root that isn't excluded by a
+// .gitignore, in directory order. Symlinked directories are not followed.
+func Walk(ctx context.Context, root string, fn func(path string) error) error {
+	return walkDir(ctx, root, nil, fn)
+}
+
+func walkDir(ctx context.Context, dir string, ignores ignoreStack, fn func(path string) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		// unreadable folders are skipped like the tree view does
+		return nil
+	}
+	if ig := loadIgnore(dir); ig != nil {
+		ignores = append(ignores[:len(ignores):len(ignores)], ig)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		isDir := entry.IsDir()
+		if entry.Type()&fs.ModeSymlink != 0 {
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+		}
+		if isDir && entry.Name() == ".git" {
+			continue
+		}
+		if ignores.Ignored(path, isDir) {
+			continue
+		}
+
+		if isDir {
+			err = walkDir(ctx, path, ignores, fn)
+		} else if entry.Type().IsRegular() || entry.Type()&fs.ModeSymlink != 0 {
+			err = fn(path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}